@@ -0,0 +1,284 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	askVerbose  bool
+	askContinue bool
+	askLog      bool
+	askModel    string
+)
+
+var askCmd = &cobra.Command{
+	Use:   "ask [question]",
+	Short: "Ask a question about your workspace",
+	Long: `Ask a natural-language question answered by the local LLM using your
+Thymer workspace tools.
+
+Thymer Desktop runs the tool-calling loop (search, issues, people, calendar,
+journal...) and streams the answer back.
+
+Examples:
+  thymer ask "what did I promise Maria last week?"
+  thymer ask --verbose "which issues are open in synchub?"
+  thymer ask --continue "and which of those are assigned to me?"
+  thymer ask --log "summarize today's meetings"
+  echo "what's on my calendar?" | thymer ask -`,
+	Args: cobra.MinimumNArgs(1),
+	Run:  runAsk,
+}
+
+func init() {
+	askCmd.Flags().BoolVarP(&askVerbose, "verbose", "v", false, "Show tool calls on stderr (listed after the answer with --output or --format)")
+	askCmd.Flags().BoolVarP(&askContinue, "continue", "c", false, "Continue the previous conversation")
+	askCmd.Flags().BoolVar(&askLog, "log", false, "Log the question and answer to today's journal")
+	askCmd.Flags().StringVar(&askModel, "model", "", "Model to use (default: llmModel from config)")
+
+	rootCmd.AddCommand(askCmd)
+}
+
+// askMessage is a chat message kept between `thymer ask --continue` runs
+type askMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+func askSessionPath() string {
+	return filepath.Join(getConfigDir(), "ask_session.json")
+}
+
+func loadAskSession() []askMessage {
+	data, err := os.ReadFile(askSessionPath())
+	if err != nil {
+		return nil
+	}
+	var messages []askMessage
+	json.Unmarshal(data, &messages)
+	return messages
+}
+
+func saveAskSession(messages []askMessage) error {
+	if err := os.MkdirAll(getConfigDir(), 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(messages, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(askSessionPath(), data, 0600)
+}
+
+func runAsk(cmd *cobra.Command, args []string) {
+	question := strings.Join(args, " ")
+
+	// Handle stdin
+	if question == "-" {
+		stdin, err := io.ReadAll(cmd.InOrStdin())
+		if err != nil {
+			exitError("Failed to read stdin: %v", err)
+		}
+		question = strings.TrimSpace(string(stdin))
+	}
+
+	if question == "" {
+		exitError("Nothing to ask")
+	}
+
+	model := askModel
+	if model == "" {
+		if config, _ := loadConfigFile(); config != nil && config.LLMModel != "" {
+			model = config.LLMModel
+		} else {
+			model = "default"
+		}
+	}
+
+	var messages []askMessage
+	if askContinue {
+		messages = loadAskSession()
+	}
+	messages = append(messages, askMessage{Role: "user", Content: question})
+
+	// Structured output needs the whole response; otherwise stream the answer
+	stream := outputFormat == "" && outputTemplate == ""
+	payload := map[string]interface{}{
+		"model":    model,
+		"messages": messages,
+		"stream":   stream,
+	}
+	body, _ := json.Marshal(payload)
+
+	resp, err := http.Post(serverAddr+"/agent/v1/chat/completions", "application/json", bytes.NewReader(body))
+	if err != nil {
+		exitError("Failed to connect to Thymer Desktop: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		exitError("Ask failed: %s", strings.TrimSpace(string(respBody)))
+	}
+
+	var answer string
+	if !stream {
+		respBody, _ := io.ReadAll(resp.Body)
+		printOutput(respBody)
+
+		var completion struct {
+			Choices []struct {
+				Message askMessage `json:"message"`
+			} `json:"choices"`
+			ThymerToolCalls []json.RawMessage `json:"thymer_tool_calls"`
+		}
+		if err := json.Unmarshal(respBody, &completion); err == nil && len(completion.Choices) > 0 {
+			answer = completion.Choices[0].Message.Content
+		}
+		// The calls have all run by now; list them after the fact
+		if askVerbose {
+			for _, tool := range completion.ThymerToolCalls {
+				printAskTool(string(tool))
+			}
+		}
+	} else {
+		answer, err = streamAnswer(resp.Body)
+		if err != nil {
+			exitError("%v", err)
+		}
+	}
+
+	messages = append(messages, askMessage{Role: "assistant", Content: answer})
+	if err := saveAskSession(messages); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to save conversation: %v\n", err)
+	}
+
+	if askLog {
+		logAskToJournal(question, answer)
+	}
+}
+
+// streamAnswer prints the answer as it streams in, and tool calls to stderr
+// with --verbose as they run. It returns the full answer text.
+func streamAnswer(r io.Reader) (string, error) {
+	var answer strings.Builder
+	event := ""
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "event:") {
+			event = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+			continue
+		}
+		if !strings.HasPrefix(line, "data:") {
+			if line == "" {
+				event = ""
+			}
+			continue
+		}
+		data := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+		if data == "[DONE]" {
+			break
+		}
+
+		if event == "thymer_tool" {
+			if askVerbose {
+				printAskTool(data)
+			}
+			continue
+		}
+
+		var chunk struct {
+			Choices []struct {
+				Delta struct {
+					Content string `json:"content"`
+				} `json:"delta"`
+			} `json:"choices"`
+			Error *struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			continue
+		}
+		if chunk.Error != nil {
+			return answer.String(), fmt.Errorf("%s", chunk.Error.Message)
+		}
+		for _, choice := range chunk.Choices {
+			fmt.Print(choice.Delta.Content)
+			answer.WriteString(choice.Delta.Content)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return answer.String(), err
+	}
+
+	if answer.Len() > 0 && !strings.HasSuffix(answer.String(), "\n") {
+		fmt.Println()
+	}
+	return answer.String(), nil
+}
+
+func printAskTool(data string) {
+	var tool struct {
+		Name      string                 `json:"name"`
+		Arguments map[string]interface{} `json:"arguments"`
+		Result    json.RawMessage        `json:"result"`
+		Error     string                 `json:"error"`
+	}
+	if err := json.Unmarshal([]byte(data), &tool); err != nil {
+		return
+	}
+
+	args, _ := json.Marshal(tool.Arguments)
+	fmt.Fprintf(os.Stderr, "→ %s %s\n", tool.Name, args)
+	if tool.Error != "" {
+		fmt.Fprintf(os.Stderr, "  ✗ %s\n", tool.Error)
+		return
+	}
+
+	summary := string(tool.Result)
+	if len(summary) > 120 {
+		summary = summary[:120] + "…"
+	}
+	fmt.Fprintf(os.Stderr, "  ✓ %s\n", summary)
+}
+
+func logAskToJournal(question, answer string) {
+	content := fmt.Sprintf("**Q:** %s\n\n**A:** %s", question, strings.TrimSpace(answer))
+	body, _ := json.Marshal(map[string]interface{}{
+		"name": "log_to_journal",
+		"args": map[string]interface{}{"content": content},
+	})
+
+	resp, err := http.Post(serverAddr+"/api/mcp/call", "application/json", bytes.NewReader(body))
+	if err != nil {
+		exitError("Failed to log to journal: %v", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, _ := io.ReadAll(resp.Body)
+	var result struct {
+		Error string `json:"error"`
+	}
+	json.Unmarshal(respBody, &result)
+	if resp.StatusCode != http.StatusOK || result.Error != "" {
+		exitError("Failed to log to journal: %s", strings.TrimSpace(string(respBody)))
+	}
+
+	if outputFormat == "" {
+		fmt.Fprintln(os.Stderr, "Logged to journal")
+	}
+}
@@ -5,7 +5,10 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"mime/multipart"
 	"net/http"
+	"os"
+	"path/filepath"
 	"strings"
 
 	"github.com/spf13/cobra"
@@ -14,25 +17,49 @@ import (
 var (
 	captureSource string
 	captureTags   string
+	captureTo     string
+	captureFiles  []string
+	captureURL    string
 )
 
+var captureTargets = []string{"captures", "journal", "both"}
+
 var captureCmd = &cobra.Command{
 	Use:   "capture [text]",
 	Short: "Quick capture a note",
 	Long: `Capture a quick note to the Captures collection.
 
+Captures keep their source, tags and time. Capturing the same text again
+finds the first capture instead of adding another. With --to both, the
+capture is also linked from today's journal; --to journal only adds it to
+the journal. Without a Captures collection, captures go to the journal.
+
+--url fetches the page and keeps its title, description and readable
+text. --file captures a text file's contents; images and PDFs are kept
+by Thymer Desktop and linked from the capture note.
+
 Examples:
   thymer capture "Remember to check the logs"
   thymer capture "$(wl-paste)"
-  thymer capture --source=cli "Note with source"
+  thymer capture --source=Web --tags=reading,ml "Attention is all you need"
+  thymer capture --to both "Idea for the offsite"
+  thymer capture --file notes.md
+  thymer capture --url https://example.com/post "Read this weekend"
+  thymer capture --file whiteboard.png --file slides.pdf "Planning session"
   echo "piped content" | thymer capture -`,
-	Args: cobra.MinimumNArgs(1),
+	Args: cobra.ArbitraryArgs,
 	Run:  runCapture,
 }
 
 func init() {
 	captureCmd.Flags().StringVar(&captureSource, "source", "cli", "Source label for the capture")
 	captureCmd.Flags().StringVar(&captureTags, "tags", "", "Comma-separated tags")
+	captureCmd.Flags().StringVar(&captureTo, "to", "captures", "Where to capture: "+strings.Join(captureTargets, ", "))
+	captureCmd.Flags().StringArrayVar(&captureFiles, "file", nil, "File to capture: text, image or PDF (repeatable)")
+	captureCmd.Flags().StringVar(&captureURL, "url", "", "Web page to capture")
+	captureCmd.RegisterFlagCompletionFunc("to", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return captureTargets, cobra.ShellCompDirectiveNoFileComp
+	})
 
 	rootCmd.AddCommand(captureCmd)
 }
@@ -49,20 +76,30 @@ func runCapture(cmd *cobra.Command, args []string) {
 		text = strings.TrimSpace(string(stdin))
 	}
 
-	if text == "" {
+	if text == "" && captureURL == "" && len(captureFiles) == 0 {
 		exitError("Nothing to capture")
 	}
 
-	payload := map[string]interface{}{
-		"text":   text,
-		"source": captureSource,
-	}
-	if captureTags != "" {
-		payload["tags"] = strings.Split(captureTags, ",")
+	var body []byte
+	contentType := "application/json"
+	if len(captureFiles) > 0 {
+		body, contentType = captureForm(text)
+	} else {
+		payload := map[string]interface{}{
+			"text":   text,
+			"source": captureSource,
+			"to":     captureTo,
+		}
+		if captureURL != "" {
+			payload["url"] = captureURL
+		}
+		if captureTags != "" {
+			payload["tags"] = strings.Split(captureTags, ",")
+		}
+		body, _ = json.Marshal(payload)
 	}
 
-	body, _ := json.Marshal(payload)
-	resp, err := http.Post(serverAddr+"/api/capture", "application/json", bytes.NewReader(body))
+	resp, err := http.Post(serverAddr+"/api/capture", contentType, bytes.NewReader(body))
 	if err != nil {
 		exitError("Failed to connect to Thymer Desktop: %v", err)
 	}
@@ -71,13 +108,78 @@ func runCapture(cmd *cobra.Command, args []string) {
 	respBody, _ := io.ReadAll(resp.Body)
 
 	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		var e struct {
+			Error string `json:"error"`
+		}
+		if json.Unmarshal(respBody, &e) == nil && e.Error != "" {
+			exitError("Capture failed: %s", e.Error)
+		}
 		exitError("Capture failed: %s", string(respBody))
 	}
 
-	if jsonOutput {
-		fmt.Println(string(respBody))
+	if printOutput(respBody) {
 		return
 	}
 
-	fmt.Println("Captured!")
+	var result struct {
+		To          string   `json:"to"`
+		Duplicate   bool     `json:"duplicate"`
+		Fallback    string   `json:"fallback"`
+		Title       string   `json:"title"`
+		Attachments []string `json:"attachments"`
+		Warning     string   `json:"warning"`
+	}
+	json.Unmarshal(respBody, &result)
+	if result.Warning != "" {
+		fmt.Fprintln(os.Stderr, colorize("33", "Warning: "+result.Warning))
+	}
+	switch {
+	case result.Duplicate:
+		fmt.Println("Already captured")
+	case result.Fallback != "":
+		fmt.Println("Captured to journal " + dim("("+result.Fallback+")"))
+	case result.To == "both":
+		fmt.Println("Captured, and linked from the journal")
+	case result.To == "journal":
+		fmt.Println("Captured to journal")
+	default:
+		fmt.Println("Captured!")
+	}
+	if result.Title != "" && (captureURL != "" || len(captureFiles) > 0) {
+		fmt.Println(dim("  " + result.Title))
+	}
+	for _, a := range result.Attachments {
+		fmt.Println(dim("  Attached " + a))
+	}
+}
+
+// captureForm builds a multipart upload of the capture and its files
+func captureForm(text string) ([]byte, string) {
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	fields := map[string]string{
+		"text":   text,
+		"source": captureSource,
+		"to":     captureTo,
+		"tags":   captureTags,
+		"url":    captureURL,
+	}
+	for name, value := range fields {
+		if value != "" {
+			mw.WriteField(name, value)
+		}
+	}
+	for _, path := range captureFiles {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			exitError("Failed to read %s: %v", path, err)
+		}
+		part, err := mw.CreateFormFile("file", filepath.Base(path))
+		if err != nil {
+			exitError("Failed to attach %s: %v", path, err)
+		}
+		part.Write(data)
+	}
+	mw.Close()
+	return buf.Bytes(), mw.FormDataContentType()
 }
@@ -0,0 +1,236 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// Completion asks thymer-bar for live values but never waits long: answers
+// are cached for completionTTL, and a stale cache is used when the server is
+// slow or down.
+const (
+	completionTTL     = time.Minute
+	completionTimeout = 800 * time.Millisecond
+)
+
+var completionClient = &http.Client{Timeout: completionTimeout}
+
+type completionEntry struct {
+	Fetched time.Time `json:"fetched"`
+	Values  []string  `json:"values"`
+}
+
+func completionCachePath() string {
+	return filepath.Join(getConfigDir(), "completion_cache.json")
+}
+
+func loadCompletionCache() map[string]completionEntry {
+	cache := make(map[string]completionEntry)
+	if data, err := os.ReadFile(completionCachePath()); err == nil {
+		json.Unmarshal(data, &cache)
+	}
+	return cache
+}
+
+// cachedCompletions returns the values for key, calling fetch only when the
+// cached values are older than completionTTL
+func cachedCompletions(key string, fetch func() ([]string, error)) []string {
+	key = serverAddr + " " + key
+	cache := loadCompletionCache()
+	entry, cached := cache[key]
+	if cached && time.Since(entry.Fetched) < completionTTL {
+		return entry.Values
+	}
+
+	values, err := fetch()
+	if err != nil {
+		// Better stale than nothing
+		return entry.Values
+	}
+
+	cache[key] = completionEntry{Fetched: time.Now(), Values: values}
+	if data, err := json.Marshal(cache); err == nil {
+		os.MkdirAll(getConfigDir(), 0755)
+		os.WriteFile(completionCachePath(), data, 0600)
+	}
+	return values
+}
+
+func completionGet(path string, v interface{}) error {
+	resp, err := completionClient.Get(serverAddr + path)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("status %d", resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// completionCallTool is callTool with the completion timeout
+func completionCallTool(name string) (json.RawMessage, error) {
+	body, _ := json.Marshal(map[string]interface{}{
+		"name": name,
+		"args": map[string]interface{}{},
+	})
+	resp, err := completionClient.Post(serverAddr+"/api/mcp/call", "application/json", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("status %d", resp.StatusCode)
+	}
+	if msg := toolError(respBody); msg != "" {
+		return nil, fmt.Errorf("%s", msg)
+	}
+	return respBody, nil
+}
+
+func completePlugins(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	values := cachedCompletions("plugins", func() ([]string, error) {
+		var status struct {
+			Plugins []struct {
+				ID      string `json:"id"`
+				Name    string `json:"name"`
+				Enabled bool   `json:"enabled"`
+			} `json:"plugins"`
+		}
+		if err := completionGet("/api/status", &status); err != nil {
+			return nil, err
+		}
+		var names []string
+		for _, p := range status.Plugins {
+			// Older SyncHubs only send the id, as name
+			id, desc := p.ID, p.Name
+			if id == "" || id == desc {
+				id, desc = p.Name, ""
+			}
+			if !p.Enabled {
+				desc = strings.TrimSpace(desc + " (paused)")
+			}
+			if desc != "" {
+				id += "\t" + desc
+			}
+			names = append(names, id)
+		}
+		return names, nil
+	})
+	return values, cobra.ShellCompDirectiveNoFileComp
+}
+
+// collectionSchemas fetches list_collections as name -> field -> description
+func collectionSchemas() map[string]map[string]string {
+	values := cachedCompletions("collections", func() ([]string, error) {
+		result, err := completionCallTool("list_collections")
+		if err != nil {
+			return nil, err
+		}
+		// Cache the raw result; callers need more than the names
+		return []string{string(result)}, nil
+	})
+	if len(values) == 0 {
+		return nil
+	}
+
+	var list struct {
+		Collections map[string]struct {
+			Schema map[string]interface{} `json:"schema"`
+		} `json:"collections"`
+	}
+	if err := json.Unmarshal([]byte(values[0]), &list); err != nil {
+		return nil
+	}
+	schemas := make(map[string]map[string]string, len(list.Collections))
+	for name, col := range list.Collections {
+		fields := make(map[string]string, len(col.Schema))
+		for field, desc := range col.Schema {
+			fields[strings.ToLower(field)] = fmt.Sprint(desc)
+		}
+		schemas[name] = fields
+	}
+	return schemas
+}
+
+// completeCollectionArg completes a command's single collection argument
+func completeCollectionArg(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	return completeCollections(cmd, args, toComplete)
+}
+
+func completeCollections(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	var names []string
+	for name := range collectionSchemas() {
+		names = append(names, strings.ToLower(name))
+	}
+	sort.Strings(names)
+	return names, cobra.ShellCompDirectiveNoFileComp
+}
+
+func completeTools(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	values := cachedCompletions("tools", func() ([]string, error) {
+		var tools []toolInfo
+		if err := completionGet("/api/mcp/tools", &tools); err != nil {
+			return nil, err
+		}
+		names := make([]string, 0, len(tools))
+		for _, t := range tools {
+			desc := strings.SplitN(t.Description, "\n", 2)[0]
+			names = append(names, t.Name+"\t"+desc)
+		}
+		sort.Strings(names)
+		return names, nil
+	})
+	return values, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeQueryState offers the states the collection's schema lists, e.g.
+// "Open | Next | In Progress | Closed"
+func completeQueryState(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	collection := "issues"
+	if len(args) > 0 {
+		collection = args[0]
+	}
+
+	var states []string
+	for name, fields := range collectionSchemas() {
+		if !strings.EqualFold(name, collection) {
+			continue
+		}
+		if desc := fields["state"]; strings.Contains(desc, "|") {
+			for _, s := range strings.Split(desc, "|") {
+				if s = strings.TrimSpace(s); s != "" {
+					states = append(states, strings.ToLower(s))
+				}
+			}
+		}
+	}
+	if len(states) == 0 {
+		states = []string{"open", "closed"}
+	}
+	return states, cobra.ShellCompDirectiveNoFileComp
+}
@@ -6,16 +6,27 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"strconv"
 	"strings"
 
 	"github.com/spf13/cobra"
 )
 
 type Config struct {
-	Workspace   string `json:"workspace"`
-	ThymerURL   string `json:"thymerUrl"`
-	LLMModel    string `json:"llmModel,omitempty"`
-	AutoStartLLM bool  `json:"autoStartLLM,omitempty"`
+	Workspace    string     `json:"workspace"`
+	ThymerURL    string     `json:"thymerUrl"`
+	Token        string     `json:"token,omitempty"`
+	LLMModel     string     `json:"llmModel,omitempty"`
+	AutoStartLLM bool       `json:"autoStartLLM,omitempty"`
+	LLM          *LLMConfig `json:"llm,omitempty"`
+}
+
+// LLMConfig mirrors the local LLM settings thymer-bar reads from the same file
+type LLMConfig struct {
+	Command    string   `json:"command,omitempty"`
+	Args       []string `json:"args,omitempty"`
+	Endpoint   string   `json:"endpoint,omitempty"`
+	HealthPath string   `json:"healthPath,omitempty"`
 }
 
 var configCmd = &cobra.Command{
@@ -41,10 +52,16 @@ var configSetCmd = &cobra.Command{
 	Long: `Set a configuration value.
 
 Available keys:
-  workspace    Your Thymer workspace name (e.g., liberato)
+  workspace      Your Thymer workspace name (e.g., liberato)
+  model          Model name for the local LLM
+  autostart-llm  Start the local LLM with thymer-bar (true/false)
+  llm-command    Inference server command thymer-bar supervises
+  llm-endpoint   OpenAI-compatible base URL of the local LLM
 
 Examples:
-  thymer config set workspace liberato`,
+  thymer config set workspace liberato
+  thymer config set llm-command llama-server
+  thymer config set llm-endpoint http://127.0.0.1:8080`,
 	Args: cobra.ExactArgs(2),
 	Run:  runConfigSet,
 }
@@ -96,18 +113,56 @@ func loadConfigFile() (*Config, error) {
 	return &config, nil
 }
 
-func saveConfigFile(config *Config) error {
-	configDir := getConfigDir()
-	if err := os.MkdirAll(configDir, 0755); err != nil {
+// updateConfigFile sets keys in the config file and leaves the others as
+// they are, since thymer-bar keeps settings there the CLI doesn't know.
+// Object values are merged into the object already there.
+func updateConfigFile(values map[string]interface{}) error {
+	raw := make(map[string]json.RawMessage)
+	data, err := os.ReadFile(getConfigPath())
+	if err == nil {
+		if err := json.Unmarshal(data, &raw); err != nil {
+			return err
+		}
+	} else if !os.IsNotExist(err) {
 		return err
 	}
 
-	data, err := json.MarshalIndent(config, "", "  ")
+	for key, value := range values {
+		if fields, ok := value.(map[string]interface{}); ok {
+			merged := make(map[string]json.RawMessage)
+			if existing, ok := raw[key]; ok && string(existing) != "null" {
+				if err := json.Unmarshal(existing, &merged); err != nil {
+					return fmt.Errorf("%s: %w", key, err)
+				}
+			}
+			for k, v := range fields {
+				b, err := json.Marshal(v)
+				if err != nil {
+					return err
+				}
+				merged[k] = b
+			}
+			value = merged
+		}
+		b, err := json.Marshal(value)
+		if err != nil {
+			return err
+		}
+		raw[key] = b
+	}
+
+	if err := os.MkdirAll(getConfigDir(), 0755); err != nil {
+		return err
+	}
+	data, err = json.MarshalIndent(raw, "", "  ")
 	if err != nil {
 		return err
 	}
-
-	return os.WriteFile(getConfigPath(), data, 0644)
+	// The file holds the Thymer token; older versions wrote it readable
+	if err := os.WriteFile(getConfigPath(), data, 0600); err != nil {
+		return err
+	}
+	return os.Chmod(getConfigPath(), 0600)
 }
 
 func runConfigShow(cmd *cobra.Command, args []string) {
@@ -122,9 +177,7 @@ func runConfigShow(cmd *cobra.Command, args []string) {
 		return
 	}
 
-	if jsonOutput {
-		data, _ := json.MarshalIndent(config, "", "  ")
-		fmt.Println(string(data))
+	if printValueOutput(config) {
 		return
 	}
 
@@ -135,37 +188,57 @@ func runConfigShow(cmd *cobra.Command, args []string) {
 	if config.LLMModel != "" {
 		fmt.Printf("LLM Model:  %s\n", config.LLMModel)
 	}
+	if config.LLM != nil {
+		if config.LLM.Command != "" {
+			fmt.Printf("LLM Cmd:    %s %s\n", config.LLM.Command, strings.Join(config.LLM.Args, " "))
+		}
+		if config.LLM.Endpoint != "" {
+			fmt.Printf("LLM URL:    %s\n", config.LLM.Endpoint)
+		}
+	}
+	if config.AutoStartLLM {
+		fmt.Println("LLM Start:  automatic")
+	}
 }
 
 func runConfigSet(cmd *cobra.Command, args []string) {
 	key := strings.ToLower(args[0])
 	value := args[1]
 
-	config, err := loadConfigFile()
-	if err != nil {
-		exitError("Failed to load config: %v", err)
-	}
-	if config == nil {
-		config = &Config{}
-	}
-
+	var values map[string]interface{}
 	switch key {
 	case "workspace":
 		workspace := strings.ToLower(strings.TrimSpace(value))
-		config.Workspace = workspace
-		config.ThymerURL = fmt.Sprintf("https://%s.thymer.com", workspace)
+		thymerURL := fmt.Sprintf("https://%s.thymer.com", workspace)
+		values = map[string]interface{}{"workspace": workspace, "thymerUrl": thymerURL}
 		fmt.Printf("Workspace set to: %s\n", workspace)
-		fmt.Printf("Thymer URL: %s\n", config.ThymerURL)
+		fmt.Printf("Thymer URL: %s\n", thymerURL)
 
 	case "llmmodel", "llm-model", "model":
-		config.LLMModel = value
+		values = map[string]interface{}{"llmModel": value}
 		fmt.Printf("LLM model set to: %s\n", value)
 
+	case "autostartllm", "autostart-llm":
+		enabled, err := strconv.ParseBool(value)
+		if err != nil {
+			exitError("autostart-llm must be true or false")
+		}
+		values = map[string]interface{}{"autoStartLLM": enabled}
+		fmt.Printf("LLM auto-start set to: %v\n", enabled)
+
+	case "llm-command":
+		values = map[string]interface{}{"llm": map[string]interface{}{"command": value}}
+		fmt.Printf("LLM command set to: %s\n", value)
+
+	case "llm-endpoint":
+		values = map[string]interface{}{"llm": map[string]interface{}{"endpoint": value}}
+		fmt.Printf("LLM endpoint set to: %s\n", value)
+
 	default:
-		exitError("Unknown config key: %s\n\nAvailable keys: workspace, llmmodel", key)
+		exitError("Unknown config key: %s\n\nAvailable keys: workspace, model, autostart-llm, llm-command, llm-endpoint", key)
 	}
 
-	if err := saveConfigFile(config); err != nil {
+	if err := updateConfigFile(values); err != nil {
 		exitError("Failed to save config: %v", err)
 	}
 
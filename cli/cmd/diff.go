@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+)
+
+// diffContext is the number of unchanged lines shown around each change
+const diffContext = 2
+
+// unifiedDiff returns a line diff of a and b in unified style, colored when
+// the terminal supports it. It returns "" when they're equal.
+func unifiedDiff(aName, bName, a, b string) string {
+	if a == b {
+		return ""
+	}
+	aLines := strings.Split(a, "\n")
+	bLines := strings.Split(b, "\n")
+
+	// Longest common subsequence table, filled from the end
+	lcs := make([][]int, len(aLines)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(bLines)+1)
+	}
+	for i := len(aLines) - 1; i >= 0; i-- {
+		for j := len(bLines) - 1; j >= 0; j-- {
+			if aLines[i] == bLines[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	type op struct {
+		kind byte // ' ', '-', '+'
+		text string
+	}
+	var ops []op
+	i, j := 0, 0
+	for i < len(aLines) || j < len(bLines) {
+		switch {
+		case i < len(aLines) && j < len(bLines) && aLines[i] == bLines[j]:
+			ops = append(ops, op{' ', aLines[i]})
+			i++
+			j++
+		case i < len(aLines) && (j == len(bLines) || lcs[i+1][j] >= lcs[i][j+1]):
+			ops = append(ops, op{'-', aLines[i]})
+			i++
+		default:
+			ops = append(ops, op{'+', bLines[j]})
+			j++
+		}
+	}
+
+	var sb strings.Builder
+	sb.WriteString(colorize("1", "--- "+aName) + "\n")
+	sb.WriteString(colorize("1", "+++ "+bName) + "\n")
+
+	// Only print changed lines and their context
+	nearChange := func(k int) bool {
+		for d := -diffContext; d <= diffContext; d++ {
+			if n := k + d; n >= 0 && n < len(ops) && ops[n].kind != ' ' {
+				return true
+			}
+		}
+		return false
+	}
+	lastPrinted := -1
+	for k, o := range ops {
+		if o.kind == ' ' && !nearChange(k) {
+			continue
+		}
+		if lastPrinted >= 0 && k > lastPrinted+1 {
+			sb.WriteString(colorize("36", "@@") + "\n")
+		}
+		lastPrinted = k
+
+		line := string(o.kind) + o.text
+		switch o.kind {
+		case '-':
+			line = colorize("31", line)
+		case '+':
+			line = colorize("32", line)
+		}
+		sb.WriteString(line + "\n")
+	}
+	return sb.String()
+}
+
+// colorize wraps s in an ANSI color code when color is enabled
+func colorize(code, s string) string {
+	if !colorEnabled() {
+		return s
+	}
+	return fmt.Sprintf("\033[%sm%s\033[0m", code, s)
+}
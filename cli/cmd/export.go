@@ -0,0 +1,156 @@
+package cmd
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	exportFormat  string
+	exportOut     string
+	exportOffline bool
+)
+
+var exportFormats = []string{"csv", "json", "ndjson", "markdown", "ics"}
+
+var exportCmd = &cobra.Command{
+	Use:   "export <collection>",
+	Short: "Export a whole collection to a file",
+	Long: `Export every record of a collection, for backups or reports.
+
+Formats:
+  json      an array of records with their fields and text (default)
+  ndjson    one record per line
+  csv       a row per record; multi-value fields are joined with "; "
+  markdown  one file per record, with the fields as YAML front matter
+  ics       an event per record that has a date
+
+Choice fields are written as their labels and dates as RFC 3339. Without
+--out the export goes to stdout; markdown needs --out, a directory the
+record files are written to.
+
+Examples:
+  thymer export issues -o csv > issues.csv
+  thymer export issues -o csv --out backups/
+  thymer export people -o markdown --out notes/people
+  thymer export calendar -o ics --out ~/calendars
+  thymer export captures -o ndjson --offline`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeCollectionArg,
+	Run:               runExport,
+}
+
+func init() {
+	exportCmd.Flags().StringVarP(&exportFormat, "output", "o", "json", "Export format: "+strings.Join(exportFormats, ", "))
+	exportCmd.Flags().StringVar(&exportOut, "out", "", "Directory to write the export to (default: stdout)")
+	exportCmd.Flags().BoolVar(&exportOffline, "offline", false, "Export thymer-bar's local mirror without asking SyncHub")
+	exportCmd.RegisterFlagCompletionFunc("output", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return exportFormats, cobra.ShellCompDirectiveNoFileComp
+	})
+	exportCmd.MarkFlagDirname("out")
+
+	rootCmd.AddCommand(exportCmd)
+}
+
+func runExport(cmd *cobra.Command, args []string) {
+	if exportFormat == "markdown" && exportOut == "" {
+		exitError("markdown writes a file per record; set --out to a directory")
+	}
+
+	params := url.Values{}
+	params.Set("collection", args[0])
+	params.Set("format", exportFormat)
+	if exportOffline {
+		params.Set("offline", "1")
+	}
+
+	resp, err := http.Get(serverAddr + "/api/export?" + params.Encode())
+	if err != nil {
+		exitError("Failed to connect to Thymer Desktop: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		var e struct {
+			Error string `json:"error"`
+		}
+		if json.Unmarshal(body, &e) == nil && e.Error != "" {
+			exitError("Export failed: %s", e.Error)
+		}
+		exitError("Export failed: %s", strings.TrimSpace(string(body)))
+	}
+
+	// Stderr, so piped output stays clean
+	if resp.Header.Get("X-Stale") != "" {
+		snapshot := "an unknown time"
+		if at, err := time.Parse(time.RFC3339, resp.Header.Get("X-Snapshot-At")); err == nil {
+			snapshot = formatLastSync(&at)
+		}
+		fmt.Fprintln(os.Stderr, dim("Offline: exported from the local mirror, last synced "+snapshot))
+	}
+
+	if exportOut == "" {
+		os.Stdout.Write(body)
+		return
+	}
+
+	if err := os.MkdirAll(exportOut, 0755); err != nil {
+		exitError("%v", err)
+	}
+	count := resp.Header.Get("X-Total-Count")
+	if exportFormat == "markdown" {
+		files, err := extractExport(body, exportOut)
+		if err != nil {
+			exitError("Failed to write export: %v", err)
+		}
+		fmt.Fprintf(os.Stderr, "Exported %s record(s) to %d file(s) in %s\n", count, files, exportOut)
+		return
+	}
+
+	name := strings.ToLower(args[0]) + "." + exportFormat
+	if _, p, err := mime.ParseMediaType(resp.Header.Get("Content-Disposition")); err == nil && p["filename"] != "" {
+		name = filepath.Base(p["filename"])
+	}
+	path := filepath.Join(exportOut, name)
+	if err := os.WriteFile(path, body, 0644); err != nil {
+		exitError("Failed to write export: %v", err)
+	}
+	fmt.Fprintf(os.Stderr, "Exported %s record(s) to %s\n", count, path)
+}
+
+// extractExport writes the files of a markdown export zip into dir
+func extractExport(data []byte, dir string) (int, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return 0, err
+	}
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			return 0, err
+		}
+		content, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			return 0, err
+		}
+		// Names are flat; never write outside dir
+		if err := os.WriteFile(filepath.Join(dir, filepath.Base(f.Name)), content, 0644); err != nil {
+			return 0, err
+		}
+	}
+	return len(zr.File), nil
+}
@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var feedsCmd = &cobra.Command{
+	Use:   "feeds",
+	Short: "Show the feed URLs for captures, issues and the calendar",
+	Long: `Show the URLs of thymer-bar's Atom feeds, to add to a feed reader, and
+of its calendar feed, to subscribe to from a calendar app.
+
+Each URL carries the feed's token. Add filters as query parameters, e.g.
+&tag=reading for captures, &repo=owner/repo&state=Open for issues or
+&days=14 for the calendar. To
+revoke a URL, delete the feed's token from thymer-bar's config.json and
+restart it; a new one is generated.
+
+Examples:
+  thymer feeds
+  thymer feeds --json`,
+	Args: cobra.NoArgs,
+	Run:  runFeeds,
+}
+
+func init() {
+	rootCmd.AddCommand(feedsCmd)
+}
+
+func runFeeds(cmd *cobra.Command, args []string) {
+	resp, err := http.Get(serverAddr + "/api/feeds")
+	if err != nil {
+		exitError("Failed to connect to Thymer Desktop: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		exitError("Failed to list feeds: %s", strings.TrimSpace(string(body)))
+	}
+
+	if printOutput(body, "name", "url", "filters") {
+		return
+	}
+
+	var feeds []struct {
+		Name    string   `json:"name"`
+		Title   string   `json:"title"`
+		URL     string   `json:"url"`
+		Filters []string `json:"filters"`
+	}
+	if err := json.Unmarshal(body, &feeds); err != nil {
+		exitError("Invalid response: %s", string(body))
+	}
+
+	for i, f := range feeds {
+		if i > 0 {
+			fmt.Println()
+		}
+		fmt.Println(f.Title)
+		fmt.Println("  " + f.URL)
+		fmt.Println(dim("  Filters: " + strings.Join(f.Filters, ", ")))
+	}
+}
@@ -0,0 +1,183 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	importMap    []string
+	importKey    string
+	importDryRun bool
+	importFormat string
+)
+
+var importCmd = &cobra.Command{
+	Use:   "import <collection> <file>",
+	Short: "Create or update records from a CSV or JSON file",
+	Long: `Import records into a collection from a CSV file with a header row, a
+JSON array of objects, or one JSON object per line. Use - to read stdin.
+
+Columns named like a field (by id or label) are imported as that field;
+--map sets the others, as Column=field pairs. Columns can also go to
+title, body (the note text) and external_id. Values are checked against
+the collection's schema: choices must be one of the field's options and
+dates look like 2026-01-31 or 2026-01-31T09:00:00Z. Rows that don't fit
+are reported and skipped.
+
+Records are matched by external_id: existing ones are updated, the rest
+created. Map a column to external_id, or when the file has none, name a
+column whose values identify the rows with --key; the external_id is then
+derived from its value. Either way re-importing an edited file updates
+the same records.
+
+Examples:
+  thymer import issues trello.csv --map 'Card Name=title,List=state,Card ID=external_id'
+  thymer import issues tracker.csv --map 'Title=title,Repo=repo' --key Title --dry-run
+  thymer import people contacts.json --key Email
+  thymer export issues -o ndjson | thymer import issues -`,
+	Args:              cobra.ExactArgs(2),
+	ValidArgsFunction: completeImportArgs,
+	Run:               runImport,
+}
+
+func init() {
+	importCmd.Flags().StringArrayVar(&importMap, "map", nil, "Column=field pairs, comma-separated (repeatable)")
+	importCmd.Flags().StringVar(&importKey, "key", "", "Column that identifies rows, when the file has no external_id column")
+	importCmd.Flags().BoolVar(&importDryRun, "dry-run", false, "Check the file and report what would change without writing")
+	importCmd.Flags().StringVar(&importFormat, "input-format", "", "File format: csv or json (default: from the file name or contents)")
+	importCmd.RegisterFlagCompletionFunc("input-format", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return []string{"csv", "json"}, cobra.ShellCompDirectiveNoFileComp
+	})
+
+	rootCmd.AddCommand(importCmd)
+}
+
+// completeImportArgs completes the collection, then the file
+func completeImportArgs(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) == 0 {
+		return completeCollections(cmd, args, toComplete)
+	}
+	return nil, cobra.ShellCompDirectiveDefault
+}
+
+// importRow mirrors a row of thymer-bar's import result
+type importRow struct {
+	Row        int    `json:"row"`
+	ExternalID string `json:"external_id"`
+	Title      string `json:"title"`
+	Action     string `json:"action"`
+	GUID       string `json:"guid"`
+	Error      string `json:"error"`
+}
+
+func runImport(cmd *cobra.Command, args []string) {
+	collection, file := args[0], args[1]
+
+	var data []byte
+	var err error
+	if file == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(file)
+	}
+	if err != nil {
+		exitError("%v", err)
+	}
+
+	format := importFormat
+	if format == "" {
+		switch strings.ToLower(filepath.Ext(file)) {
+		case ".csv":
+			format = "csv"
+		case ".json", ".ndjson", ".jsonl":
+			format = "json"
+		}
+	}
+
+	params := url.Values{}
+	params.Set("collection", collection)
+	if len(importMap) > 0 {
+		params.Set("map", strings.Join(importMap, ","))
+	}
+	if importKey != "" {
+		params.Set("key", importKey)
+	}
+	if format != "" {
+		params.Set("format", format)
+	}
+	if importDryRun {
+		params.Set("dry_run", "1")
+	}
+
+	resp, err := http.Post(serverAddr+"/api/import?"+params.Encode(), "application/octet-stream", bytes.NewReader(data))
+	if err != nil {
+		exitError("Failed to connect to Thymer Desktop: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		var e struct {
+			Error string `json:"error"`
+		}
+		if json.Unmarshal(body, &e) == nil && e.Error != "" {
+			exitError("Import failed: %s", e.Error)
+		}
+		exitError("Import failed: %s", strings.TrimSpace(string(body)))
+	}
+
+	var result struct {
+		Collection     string      `json:"collection"`
+		DryRun         bool        `json:"dry_run"`
+		Created        int         `json:"created"`
+		Updated        int         `json:"updated"`
+		Failed         int         `json:"failed"`
+		IgnoredColumns []string    `json:"ignored_columns"`
+		Rows           []importRow `json:"rows"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		exitError("Invalid response: %s", string(body))
+	}
+
+	if !printOutput(body) {
+		printImportResult(result.Collection, result.DryRun, result.Created, result.Updated, result.Failed, result.IgnoredColumns, result.Rows)
+	}
+	if result.Failed > 0 {
+		os.Exit(1)
+	}
+}
+
+func printImportResult(collection string, dryRun bool, created, updated, failed int, ignored []string, rows []importRow) {
+	if dryRun {
+		fmt.Printf("Dry run for %s: %d to create, %d to update, %d failed\n", collection, created, updated, failed)
+	} else {
+		fmt.Printf("%s: %d created, %d updated, %d failed\n", collection, created, updated, failed)
+	}
+	if len(ignored) > 0 {
+		fmt.Println(dim("Ignored columns: " + strings.Join(ignored, ", ") + " (use --map to import them)"))
+	}
+	if failed == 0 {
+		return
+	}
+
+	fmt.Println()
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ROW\tTITLE\tERROR")
+	for _, r := range rows {
+		if r.Action == "failed" {
+			fmt.Fprintf(tw, "%d\t%s\t%s\n", r.Row, r.Title, colorize("31", r.Error))
+		}
+	}
+	tw.Flush()
+}
@@ -0,0 +1,191 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	journalDate    string
+	journalSection string
+)
+
+var journalCmd = &cobra.Command{
+	Use:   "journal",
+	Short: "Read and add to daily notes",
+	Long: `Print today's daily note, or a past day's with --date.
+
+Examples:
+  thymer journal
+  thymer journal --date 2026-10-14
+  thymer journal add "Shipped the release"
+  thymer journal add --section "## Morning" "Standup: API review moved to Thursday"
+  thymer journal tasks`,
+	Args: cobra.NoArgs,
+	Run:  runJournal,
+}
+
+var journalAddCmd = &cobra.Command{
+	Use:   "add [text]",
+	Short: "Add an entry to a daily note",
+	Long: `Add markdown to the end of a daily note. Use - to read from stdin.
+
+With --section the entry goes at the end of that heading's section; the
+heading is added if the note doesn't have it yet.
+
+Examples:
+  thymer journal add "Call with Acme went well"
+  thymer journal add --section Morning "- [ ] Review PR #42"
+  thymer journal add --date 2026-10-14 "Forgot to note: invoice sent"
+  git log --oneline --since=today | thymer journal add --section "## Commits" -`,
+	Args: cobra.MinimumNArgs(1),
+	Run:  runJournalAdd,
+}
+
+var journalTasksCmd = &cobra.Command{
+	Use:   "tasks",
+	Short: "List unchecked tasks in a daily note",
+	Args:  cobra.NoArgs,
+	Run:   runJournalTasks,
+}
+
+func init() {
+	journalCmd.PersistentFlags().StringVar(&journalDate, "date", "", "Day to use: YYYY-MM-DD, today or yesterday (default: today)")
+	journalAddCmd.Flags().StringVar(&journalSection, "section", "", `Heading to add under, e.g. "## Morning"`)
+
+	journalCmd.AddCommand(journalAddCmd)
+	journalCmd.AddCommand(journalTasksCmd)
+
+	rootCmd.AddCommand(journalCmd)
+}
+
+// journalDateArg returns the --date value as YYYY-MM-DD, or "" for today.
+// Days are local, matching how SyncHub picks today's note.
+func journalDateArg() string {
+	switch strings.ToLower(journalDate) {
+	case "", "today":
+		return ""
+	case "yesterday":
+		return time.Now().AddDate(0, 0, -1).Format("2006-01-02")
+	}
+	if _, err := time.Parse("2006-01-02", journalDate); err != nil {
+		exitError("Invalid --date %q (use YYYY-MM-DD)", journalDate)
+	}
+	return journalDate
+}
+
+// callJournalTool calls a journal tool with --date added when set
+func callJournalTool(name string, args map[string]interface{}) json.RawMessage {
+	if date := journalDateArg(); date != "" {
+		args["date"] = date
+	}
+	result, err := callTool(name, args)
+	if err != nil {
+		exitError("%v", err)
+	}
+	if msg := toolError(result); msg != "" {
+		exitError("%s", msg)
+	}
+	return result
+}
+
+func runJournal(cmd *cobra.Command, args []string) {
+	tool := "get_todays_journal"
+	if journalDateArg() != "" {
+		tool = "get_journal"
+	}
+	result := callJournalTool(tool, map[string]interface{}{})
+
+	if printOutput(result) {
+		return
+	}
+
+	var journal struct {
+		Title string `json:"title"`
+		Body  string `json:"body"`
+	}
+	if err := json.Unmarshal(result, &journal); err != nil {
+		exitError("Invalid response: %s", string(result))
+	}
+
+	fmt.Printf("# %s\n\n", journal.Title)
+	if journal.Body != "(empty)" {
+		fmt.Println(journal.Body)
+	}
+}
+
+func runJournalAdd(cmd *cobra.Command, args []string) {
+	text := strings.Join(args, " ")
+
+	// Handle stdin
+	if text == "-" {
+		stdin, err := io.ReadAll(cmd.InOrStdin())
+		if err != nil {
+			exitError("Failed to read stdin: %v", err)
+		}
+		text = strings.TrimRight(string(stdin), "\n")
+	}
+
+	if strings.TrimSpace(text) == "" {
+		exitError("Nothing to add")
+	}
+
+	toolArgs := map[string]interface{}{"content": text}
+	if journalSection != "" {
+		toolArgs["section"] = journalSection
+	}
+	result := callJournalTool("log_to_journal", toolArgs)
+
+	if printOutput(result) {
+		return
+	}
+	if journalSection != "" {
+		fmt.Printf("Added to %s\n", journalSection)
+	} else {
+		fmt.Println("Added to journal")
+	}
+}
+
+func runJournalTasks(cmd *cobra.Command, args []string) {
+	result := callJournalTool("get_journal_tasks", map[string]interface{}{})
+
+	var journal struct {
+		Tasks []struct {
+			GUID    string `json:"guid"`
+			Text    string `json:"text"`
+			Status  string `json:"status"`
+			Section string `json:"section"`
+		} `json:"tasks"`
+	}
+	if err := json.Unmarshal(result, &journal); err != nil {
+		exitError("Invalid response: %s", string(result))
+	}
+
+	if printValueOutput(journal.Tasks, "text", "status", "section", "guid") {
+		return
+	}
+
+	if len(journal.Tasks) == 0 {
+		fmt.Println("No open tasks")
+		return
+	}
+
+	section := ""
+	for _, t := range journal.Tasks {
+		if t.Section != section {
+			section = t.Section
+			fmt.Printf("\n%s\n", section)
+		}
+		box := "[ ]"
+		if t.Status == "in_progress" {
+			box = "[-]"
+		}
+		fmt.Printf("  %s %s\n", box, t.Text)
+	}
+	fmt.Printf("\n%d open task(s)\n", len(journal.Tasks))
+}
@@ -0,0 +1,169 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/spf13/cobra"
+)
+
+var llmLogLines int
+
+var llmCmd = &cobra.Command{
+	Use:   "llm",
+	Short: "Manage the local LLM",
+	Long: `Manage the local LLM supervised by Thymer Desktop.
+
+Thymer Desktop exposes it as an OpenAI-compatible endpoint at
+<server>/v1/chat/completions.
+
+Examples:
+  thymer llm status
+  thymer llm start
+  thymer llm logs --lines=50`,
+}
+
+var llmStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show local LLM status",
+	Run:   runLLMStatus,
+}
+
+var llmStartCmd = &cobra.Command{
+	Use:   "start",
+	Short: "Start the local LLM",
+	Run:   runLLMControl,
+}
+
+var llmStopCmd = &cobra.Command{
+	Use:   "stop",
+	Short: "Stop the local LLM",
+	Run:   runLLMControl,
+}
+
+var llmRestartCmd = &cobra.Command{
+	Use:   "restart",
+	Short: "Restart the local LLM",
+	Run:   runLLMControl,
+}
+
+var llmLogsCmd = &cobra.Command{
+	Use:   "logs",
+	Short: "Show recent local LLM output",
+	Run:   runLLMLogs,
+}
+
+func init() {
+	llmLogsCmd.Flags().IntVar(&llmLogLines, "lines", 100, "Number of lines to show")
+
+	llmCmd.AddCommand(llmStatusCmd)
+	llmCmd.AddCommand(llmStartCmd)
+	llmCmd.AddCommand(llmStopCmd)
+	llmCmd.AddCommand(llmRestartCmd)
+	llmCmd.AddCommand(llmLogsCmd)
+
+	rootCmd.AddCommand(llmCmd)
+}
+
+func runLLMStatus(cmd *cobra.Command, args []string) {
+	resp, err := http.Get(serverAddr + "/api/llm/status")
+	if err != nil {
+		exitError("Thymer Desktop not running at %s", serverAddr)
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+	printLLMStatus(body)
+}
+
+func runLLMControl(cmd *cobra.Command, args []string) {
+	resp, err := http.Post(serverAddr+"/api/llm/"+cmd.Name(), "application/json", nil)
+	if err != nil {
+		exitError("Failed to connect to Thymer Desktop: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+
+	if resp.StatusCode != http.StatusOK {
+		exitError("LLM %s failed: %s", cmd.Name(), string(body))
+	}
+
+	printLLMStatus(body)
+}
+
+func printLLMStatus(body []byte) {
+	if printOutput(body) {
+		return
+	}
+
+	var status struct {
+		Running   bool   `json:"running"`
+		Managed   bool   `json:"managed"`
+		Process   bool   `json:"process"`
+		PID       int    `json:"pid"`
+		Model     string `json:"model"`
+		Endpoint  string `json:"endpoint"`
+		Restarts  int    `json:"restarts"`
+		LastError string `json:"last_error"`
+	}
+	if err := json.Unmarshal(body, &status); err != nil {
+		fmt.Println(string(body))
+		return
+	}
+
+	if status.Running {
+		fmt.Printf("Local LLM:  ● %s\n", status.Model)
+	} else {
+		fmt.Println("Local LLM:  ○ Not running")
+	}
+	fmt.Printf("Endpoint:   %s\n", status.Endpoint)
+	if status.Managed {
+		if status.Process {
+			fmt.Printf("Process:    pid %d\n", status.PID)
+		} else {
+			fmt.Println("Process:    stopped")
+		}
+	} else {
+		fmt.Println("Process:    not managed (set llm-command to supervise)")
+	}
+	if status.Restarts > 0 {
+		fmt.Printf("Restarts:   %d\n", status.Restarts)
+	}
+	if status.LastError != "" {
+		fmt.Printf("Last error: %s\n", status.LastError)
+	}
+}
+
+func runLLMLogs(cmd *cobra.Command, args []string) {
+	resp, err := http.Get(fmt.Sprintf("%s/api/llm/logs?lines=%d", serverAddr, llmLogLines))
+	if err != nil {
+		exitError("Thymer Desktop not running at %s", serverAddr)
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+
+	if jsonOutput {
+		fmt.Println(string(body))
+		return
+	}
+
+	var result struct {
+		Lines []string `json:"lines"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		fmt.Println(string(body))
+		return
+	}
+
+	if printValueOutput(result.Lines) {
+		return
+	}
+
+	for _, line := range result.Lines {
+		fmt.Println(line)
+	}
+}
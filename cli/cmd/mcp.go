@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -8,6 +9,7 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"strings"
 
 	"github.com/spf13/cobra"
 )
@@ -112,8 +114,7 @@ func runMcpStatus(cmd *cobra.Command, args []string) {
 
 	body, _ := io.ReadAll(resp.Body)
 
-	if jsonOutput {
-		fmt.Println(string(body))
+	if printOutput(body) {
 		return
 	}
 
@@ -139,8 +140,7 @@ func runMcpTools(cmd *cobra.Command, args []string) {
 
 	body, _ := io.ReadAll(resp.Body)
 
-	if jsonOutput {
-		fmt.Println(string(body))
+	if printOutput(body, "name", "description") {
 		return
 	}
 
@@ -160,3 +160,32 @@ func runMcpTools(cmd *cobra.Command, args []string) {
 		}
 	}
 }
+
+// callTool executes a tool through Thymer Desktop and returns its raw result.
+// Errors the tool itself reports are left in the result for the caller.
+func callTool(name string, args map[string]interface{}) (json.RawMessage, error) {
+	body, _ := json.Marshal(map[string]interface{}{
+		"name": name,
+		"args": args,
+	})
+	resp, err := http.Post(serverAddr+"/api/mcp/call", "application/json", bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to Thymer Desktop: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s", strings.TrimSpace(string(respBody)))
+	}
+	return respBody, nil
+}
+
+// toolError extracts the "error" a tool returned, if any
+func toolError(result json.RawMessage) string {
+	var r struct {
+		Error string `json:"error"`
+	}
+	json.Unmarshal(result, &r)
+	return r.Error
+}
@@ -0,0 +1,239 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var noteForce bool
+
+var noteCmd = &cobra.Command{
+	Use:   "note",
+	Short: "Read and edit notes",
+	Long: `Read, append to and edit individual notes by GUID.
+
+GUIDs come from 'thymer search' and 'thymer query'.
+
+Examples:
+  thymer note get 01HXYZ...
+  echo "- follow up with Maria" | thymer note append 01HXYZ... -
+  thymer note edit 01HXYZ...`,
+}
+
+var noteGetCmd = &cobra.Command{
+	Use:   "get <guid>",
+	Short: "Print a note as markdown",
+	Args:  cobra.ExactArgs(1),
+	Run:   runNoteGet,
+}
+
+var noteAppendCmd = &cobra.Command{
+	Use:   "append <guid> [text]",
+	Short: "Append markdown to a note",
+	Long: `Append markdown to the end of a note. Use - to read from stdin.
+
+Examples:
+  thymer note append 01HXYZ... "Decided to ship on Friday"
+  git log --oneline -5 | thymer note append 01HXYZ... -`,
+	Args: cobra.MinimumNArgs(2),
+	Run:  runNoteAppend,
+}
+
+var noteEditCmd = &cobra.Command{
+	Use:   "edit <guid>",
+	Short: "Edit a note's body in $EDITOR",
+	Long: `Open a note's body in $EDITOR and save it back when the editor exits.
+
+If the note changed in Thymer while you were editing, nothing is saved: the
+remote changes are shown as a diff and your edit is kept in a file so you can
+merge by hand. --force overwrites the remote changes instead.
+
+The body is markdown: headings, tasks, lists, quotes and code blocks, with
+nested items indented two spaces. Lines you leave alone keep their items,
+so task states and links to them survive. Items can't change type in
+Thymer, so changing a line's kind replaces its item, and removed lines
+leave empty items behind.`,
+	Args: cobra.ExactArgs(1),
+	Run:  runNoteEdit,
+}
+
+func init() {
+	noteEditCmd.Flags().BoolVar(&noteForce, "force", false, "Save even if the note changed remotely")
+
+	noteCmd.AddCommand(noteGetCmd)
+	noteCmd.AddCommand(noteAppendCmd)
+	noteCmd.AddCommand(noteEditCmd)
+
+	rootCmd.AddCommand(noteCmd)
+}
+
+// note is the get_note result
+type note struct {
+	GUID        string                 `json:"guid"`
+	Title       string                 `json:"title"`
+	Fields      map[string]interface{} `json:"fields"`
+	Body        string                 `json:"body"`
+	ContentHash string                 `json:"content_hash"`
+}
+
+func fetchNote(guid string) (*note, json.RawMessage) {
+	result, err := callTool("get_note", map[string]interface{}{"guid": guid})
+	if err != nil {
+		exitError("%v", err)
+	}
+	if msg := toolError(result); msg != "" {
+		exitError("%s", msg)
+	}
+
+	var n note
+	if err := json.Unmarshal(result, &n); err != nil {
+		exitError("Invalid response: %s", string(result))
+	}
+	// get_note shows empty bodies as a placeholder
+	if n.Body == "(empty)" {
+		n.Body = ""
+	}
+	return &n, result
+}
+
+func runNoteGet(cmd *cobra.Command, args []string) {
+	n, raw := fetchNote(args[0])
+
+	if printOutput(raw) {
+		return
+	}
+
+	fmt.Printf("# %s\n\n", n.Title)
+	if n.Body != "" {
+		fmt.Println(n.Body)
+	}
+}
+
+func runNoteAppend(cmd *cobra.Command, args []string) {
+	guid := args[0]
+	text := strings.Join(args[1:], " ")
+
+	// Handle stdin
+	if text == "-" {
+		stdin, err := io.ReadAll(cmd.InOrStdin())
+		if err != nil {
+			exitError("Failed to read stdin: %v", err)
+		}
+		text = strings.TrimRight(string(stdin), "\n")
+	}
+
+	if strings.TrimSpace(text) == "" {
+		exitError("Nothing to append")
+	}
+
+	result, err := callTool("append_to_note", map[string]interface{}{
+		"guid":    guid,
+		"content": text,
+	})
+	if err != nil {
+		exitError("%v", err)
+	}
+	if msg := toolError(result); msg != "" {
+		exitError("Append failed: %s", msg)
+	}
+
+	if printOutput(result) {
+		return
+	}
+	fmt.Println("Appended!")
+}
+
+func runNoteEdit(cmd *cobra.Command, args []string) {
+	guid := args[0]
+	n, _ := fetchNote(guid)
+
+	tmp, err := os.CreateTemp("", "thymer-note-*.md")
+	if err != nil {
+		exitError("Failed to create temp file: %v", err)
+	}
+	path := tmp.Name()
+	tmp.WriteString(n.Body)
+	tmp.Close()
+
+	if err := runEditor(path); err != nil {
+		os.Remove(path)
+		exitError("Editor failed: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		exitError("Failed to read edited note: %v", err)
+	}
+	edited := strings.TrimRight(string(data), "\n")
+
+	if edited == strings.TrimRight(n.Body, "\n") {
+		os.Remove(path)
+		fmt.Println("No changes")
+		return
+	}
+
+	saveArgs := map[string]interface{}{
+		"guid":    guid,
+		"content": edited,
+	}
+	if !noteForce {
+		saveArgs["base_hash"] = n.ContentHash
+	}
+
+	result, err := callTool("save_note", saveArgs)
+	if err != nil {
+		exitError("%v (your edit is in %s)", err, path)
+	}
+
+	var saved struct {
+		Conflict bool   `json:"conflict"`
+		Body     string `json:"body"`
+		Error    string `json:"error"`
+	}
+	json.Unmarshal(result, &saved)
+
+	if saved.Conflict {
+		fmt.Fprintf(os.Stderr, "%q changed in Thymer while you were editing:\n\n", n.Title)
+		fmt.Fprint(os.Stderr, unifiedDiff("before", "now in Thymer", n.Body, saved.Body))
+		exitError("Not saved. Your edit is in %s; merge it and rerun, or use --force", path)
+	}
+	if saved.Error != "" {
+		exitError("Save failed: %s (your edit is in %s)", saved.Error, path)
+	}
+
+	os.Remove(path)
+	if printOutput(result) {
+		return
+	}
+	fmt.Printf("Saved %q\n", n.Title)
+}
+
+// runEditor opens path in $VISUAL or $EDITOR and waits for it to exit
+func runEditor(path string) error {
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		if runtime.GOOS == "windows" {
+			editor = "notepad"
+		} else {
+			editor = "vi"
+		}
+	}
+
+	// EDITOR may carry arguments, e.g. "code --wait"
+	parts := strings.Fields(editor)
+	cmd := exec.Command(parts[0], append(parts[1:], path)...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
@@ -0,0 +1,439 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"text/template"
+)
+
+var (
+	outputFormat   string
+	outputTemplate string
+	outputColumns  string
+)
+
+// Output formats accepted by --output
+var outputFormats = []string{"table", "csv", "tsv", "yaml", "ndjson", "json"}
+
+const maxTableCell = 60
+
+func init() {
+	rootCmd.PersistentFlags().StringVarP(&outputFormat, "output", "o", "", "Output format: "+strings.Join(outputFormats, "|"))
+	rootCmd.PersistentFlags().StringVar(&outputTemplate, "format", "", "Go template applied to each result, e.g. '{{.title}} ({{.state}})'")
+	rootCmd.PersistentFlags().StringVar(&outputColumns, "columns", "", "Columns to output, comma-separated")
+}
+
+// setupOutput reconciles --json with --output before a command runs
+func setupOutput() error {
+	if jsonOutput && outputFormat == "" {
+		outputFormat = "json"
+	}
+	if outputFormat == "" {
+		return nil
+	}
+	for _, f := range outputFormats {
+		if outputFormat == f {
+			jsonOutput = outputFormat == "json"
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown output format %q (use %s)", outputFormat, strings.Join(outputFormats, ", "))
+}
+
+// printOutput renders a JSON response body in the format picked with
+// --output or --format. It returns false when neither was given, so the
+// command prints its own human-readable view instead. defaultColumns are
+// used for table/csv/tsv when --columns isn't set.
+func printOutput(body []byte, defaultColumns ...string) bool {
+	if outputFormat == "" && outputTemplate == "" {
+		return false
+	}
+
+	// Plain --json keeps the server's response as-is
+	if outputFormat == "json" && outputTemplate == "" && outputColumns == "" {
+		fmt.Println(strings.TrimSpace(string(body)))
+		return true
+	}
+
+	var value interface{}
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	if err := decoder.Decode(&value); err != nil {
+		fmt.Println(string(body))
+		return true
+	}
+
+	if err := renderValue(os.Stdout, value, defaultColumns); err != nil {
+		exitError("%v", err)
+	}
+	return true
+}
+
+// printValueOutput is printOutput for values built by the CLI itself
+func printValueOutput(v interface{}, defaultColumns ...string) bool {
+	body, err := json.Marshal(v)
+	if err != nil {
+		exitError("%v", err)
+	}
+	return printOutput(body, defaultColumns...)
+}
+
+func renderValue(w io.Writer, value interface{}, defaultColumns []string) error {
+	records, isList := toRecords(value)
+
+	columns := defaultColumns
+	if outputColumns != "" {
+		columns = splitColumns(outputColumns)
+		records = projectRecords(records, columns)
+		if isList {
+			value = recordsToValue(records)
+		} else if len(records) == 1 {
+			value = records[0]
+		}
+	}
+	if len(columns) == 0 {
+		columns = recordColumns(records)
+	}
+
+	if outputTemplate != "" {
+		return renderTemplate(w, records)
+	}
+
+	switch outputFormat {
+	case "json":
+		data, err := json.MarshalIndent(value, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(w, string(data))
+	case "ndjson":
+		items := []interface{}{value}
+		if list, ok := value.([]interface{}); ok {
+			items = list
+		}
+		for _, item := range items {
+			data, err := json.Marshal(item)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintln(w, string(data))
+		}
+	case "yaml":
+		writeYAML(w, value, 0)
+	case "csv", "tsv":
+		return renderCSV(w, records, columns, outputFormat == "tsv")
+	default:
+		renderTable(w, records, columns)
+	}
+	return nil
+}
+
+// toRecords turns a decoded response into rows. Lists of scalars become
+// rows with a single "value" column; a single object becomes one row.
+func toRecords(value interface{}) ([]map[string]interface{}, bool) {
+	switch v := value.(type) {
+	case []interface{}:
+		records := make([]map[string]interface{}, 0, len(v))
+		for _, item := range v {
+			if m, ok := item.(map[string]interface{}); ok {
+				records = append(records, m)
+			} else {
+				records = append(records, map[string]interface{}{"value": item})
+			}
+		}
+		return records, true
+	case map[string]interface{}:
+		return []map[string]interface{}{v}, false
+	default:
+		return []map[string]interface{}{{"value": v}}, false
+	}
+}
+
+func recordsToValue(records []map[string]interface{}) []interface{} {
+	list := make([]interface{}, len(records))
+	for i, r := range records {
+		list[i] = r
+	}
+	return list
+}
+
+func splitColumns(s string) []string {
+	var columns []string
+	for _, c := range strings.Split(s, ",") {
+		if c = strings.TrimSpace(c); c != "" {
+			columns = append(columns, c)
+		}
+	}
+	return columns
+}
+
+func projectRecords(records []map[string]interface{}, columns []string) []map[string]interface{} {
+	projected := make([]map[string]interface{}, len(records))
+	for i, r := range records {
+		p := make(map[string]interface{}, len(columns))
+		for _, c := range columns {
+			if v, ok := r[c]; ok {
+				p[c] = v
+			}
+		}
+		projected[i] = p
+	}
+	return projected
+}
+
+// recordColumns lists every key seen, guid and title first, the rest sorted
+func recordColumns(records []map[string]interface{}) []string {
+	seen := make(map[string]bool)
+	var rest []string
+	for _, r := range records {
+		for k := range r {
+			if !seen[k] {
+				seen[k] = true
+				if k != "guid" && k != "title" {
+					rest = append(rest, k)
+				}
+			}
+		}
+	}
+	sort.Strings(rest)
+
+	var columns []string
+	for _, k := range []string{"guid", "title"} {
+		if seen[k] {
+			columns = append(columns, k)
+		}
+	}
+	return append(columns, rest...)
+}
+
+// cellString formats a value for a table or CSV cell
+func cellString(v interface{}) string {
+	switch val := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return val
+	case json.Number:
+		return val.String()
+	case bool:
+		return strconv.FormatBool(val)
+	case float64:
+		return strconv.FormatFloat(val, 'f', -1, 64)
+	default:
+		data, err := json.Marshal(val)
+		if err != nil {
+			return fmt.Sprint(val)
+		}
+		return string(data)
+	}
+}
+
+func renderTable(w io.Writer, records []map[string]interface{}, columns []string) {
+	if len(records) == 0 {
+		fmt.Fprintln(w, "No results")
+		return
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	headers := make([]string, len(columns))
+	for i, c := range columns {
+		headers[i] = strings.ToUpper(c)
+	}
+	fmt.Fprintln(tw, strings.Join(headers, "\t"))
+
+	for _, r := range records {
+		cells := make([]string, len(columns))
+		for i, c := range columns {
+			cell := strings.Join(strings.Fields(cellString(r[c])), " ")
+			if len([]rune(cell)) > maxTableCell {
+				cell = string([]rune(cell)[:maxTableCell-1]) + "…"
+			}
+			cells[i] = cell
+		}
+		fmt.Fprintln(tw, strings.Join(cells, "\t"))
+	}
+	tw.Flush()
+}
+
+func renderCSV(w io.Writer, records []map[string]interface{}, columns []string, tabs bool) error {
+	cw := csv.NewWriter(w)
+	if tabs {
+		cw.Comma = '\t'
+	}
+	if err := cw.Write(columns); err != nil {
+		return err
+	}
+	for _, r := range records {
+		row := make([]string, len(columns))
+		for i, c := range columns {
+			row[i] = cellString(r[c])
+		}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+var templateFuncs = template.FuncMap{
+	"json": func(v interface{}) string {
+		data, _ := json.Marshal(v)
+		return string(data)
+	},
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+	"join": func(sep string, v interface{}) string {
+		list, ok := v.([]interface{})
+		if !ok {
+			return cellString(v)
+		}
+		parts := make([]string, len(list))
+		for i, item := range list {
+			parts[i] = cellString(item)
+		}
+		return strings.Join(parts, sep)
+	},
+	"truncate": func(n int, s string) string {
+		if len([]rune(s)) <= n {
+			return s
+		}
+		return string([]rune(s)[:n]) + "…"
+	},
+}
+
+func renderTemplate(w io.Writer, records []map[string]interface{}) error {
+	tmpl, err := template.New("template").Funcs(templateFuncs).Parse(outputTemplate)
+	if err != nil {
+		return fmt.Errorf("invalid --format: %w", err)
+	}
+
+	for _, r := range records {
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, r); err != nil {
+			return fmt.Errorf("--format: %w", err)
+		}
+		// Missing keys in a map render as "<no value>"; show them as empty
+		line := strings.ReplaceAll(buf.String(), "<no value>", "")
+		if !strings.HasSuffix(line, "\n") {
+			line += "\n"
+		}
+		fmt.Fprint(w, line)
+	}
+	return nil
+}
+
+// writeYAML writes JSON-decoded data as block-style YAML
+func writeYAML(w io.Writer, v interface{}, indent int) {
+	pad := strings.Repeat("  ", indent)
+	switch val := v.(type) {
+	case map[string]interface{}:
+		if len(val) == 0 {
+			fmt.Fprintf(w, "%s{}\n", pad)
+			return
+		}
+		keys := make([]string, 0, len(val))
+		for k := range val {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			child := val[k]
+			if isYAMLBlock(child) {
+				fmt.Fprintf(w, "%s%s:\n", pad, yamlScalar(k))
+				writeYAML(w, child, indent+1)
+			} else {
+				fmt.Fprintf(w, "%s%s: %s\n", pad, yamlScalar(k), yamlScalar(child))
+			}
+		}
+	case []interface{}:
+		if len(val) == 0 {
+			fmt.Fprintf(w, "%s[]\n", pad)
+			return
+		}
+		for _, item := range val {
+			if !isYAMLBlock(item) {
+				fmt.Fprintf(w, "%s- %s\n", pad, yamlScalar(item))
+				continue
+			}
+			// Render the item one level deeper, then put the dash in place
+			// of the first line's indentation
+			var buf bytes.Buffer
+			writeYAML(&buf, item, indent+1)
+			block := buf.String()
+			fmt.Fprintf(w, "%s- %s", pad, strings.TrimPrefix(block, pad+"  "))
+		}
+	default:
+		fmt.Fprintf(w, "%s%s\n", pad, yamlScalar(val))
+	}
+}
+
+func isYAMLBlock(v interface{}) bool {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		return len(val) > 0
+	case []interface{}:
+		return len(val) > 0
+	}
+	return false
+}
+
+func yamlScalar(v interface{}) string {
+	switch val := v.(type) {
+	case nil:
+		return "null"
+	case map[string]interface{}:
+		return "{}"
+	case []interface{}:
+		return "[]"
+	case string:
+		if yamlNeedsQuotes(val) {
+			return strconv.Quote(val)
+		}
+		return val
+	default:
+		return cellString(val)
+	}
+}
+
+func yamlNeedsQuotes(s string) bool {
+	if s == "" || strings.TrimSpace(s) != s {
+		return true
+	}
+	switch strings.ToLower(s) {
+	case "true", "false", "yes", "no", "on", "off", "null", "~":
+		return true
+	}
+	if _, err := strconv.ParseFloat(s, 64); err == nil {
+		return true
+	}
+	if strings.ContainsAny(s[:1], "-?:,[]{}#&*!|>'\"%@`") {
+		return true
+	}
+	return strings.Contains(s, ": ") || strings.Contains(s, " #") || strings.ContainsAny(s, "\n\t")
+}
+
+// colorEnabled reports whether stdout is a terminal that wants color
+func colorEnabled() bool {
+	if os.Getenv("NO_COLOR") != "" || os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	info, err := os.Stdout.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// dim renders s in a faint color when color is enabled
+func dim(s string) string {
+	if !colorEnabled() {
+		return s
+	}
+	return "\033[2m" + s + "\033[0m"
+}
@@ -0,0 +1,293 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var pluginsUnset []string
+
+var pluginsCmd = &cobra.Command{
+	Use:   "plugins",
+	Short: "List and configure sync plugins",
+	Long: `List SyncHub's sync plugins, pause or resume them, and change their
+settings without opening Thymer.
+
+Examples:
+  thymer plugins list
+  thymer plugins disable telegram-sync
+  thymer plugins config github-sync
+  thymer plugins config github-sync interval=15m journal=verbose`,
+}
+
+var pluginsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List sync plugins with their status",
+	Args:  cobra.NoArgs,
+	Run:   runPluginsList,
+}
+
+var pluginsEnableCmd = &cobra.Command{
+	Use:               "enable <plugin>",
+	Short:             "Resume a plugin's scheduled syncs",
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completePlugins,
+	Run:               runPluginsToggle,
+}
+
+var pluginsDisableCmd = &cobra.Command{
+	Use:               "disable <plugin>",
+	Short:             "Pause a plugin's scheduled syncs",
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completePlugins,
+	Run:               runPluginsToggle,
+}
+
+var pluginsConfigCmd = &cobra.Command{
+	Use:   "config <plugin> [key=value...]",
+	Short: "Show or change a plugin's settings",
+	Long: `Show a plugin's settings, or change them with key=value pairs.
+
+Keys are SyncHub's settings (interval, journal, toast, log_level) or keys of
+the plugin's JSON config. Values are parsed as JSON when they can be, so
+repos='["owner/repo"]' sets a list; anything else is a string. Tokens and
+other secrets are hidden and can only be set in Thymer.
+
+Examples:
+  thymer plugins config github-sync
+  thymer plugins config github-sync interval=15m
+  thymer plugins config github-sync query="is:open assignee:@me"
+  thymer plugins config github-sync --unset query`,
+	Args:              cobra.MinimumNArgs(1),
+	ValidArgsFunction: completePlugins,
+	Run:               runPluginsConfig,
+}
+
+// pluginInfo is a plugin as /api/plugins reports it
+type pluginInfo struct {
+	ID          string                 `json:"id"`
+	Name        string                 `json:"name"`
+	Enabled     bool                   `json:"enabled"`
+	Loaded      bool                   `json:"loaded"`
+	Version     string                 `json:"version"`
+	Collection  string                 `json:"collection"`
+	Interval    string                 `json:"interval"`
+	Status      string                 `json:"status"`
+	LastSync    *time.Time             `json:"last_sync"`
+	LastError   string                 `json:"last_error"`
+	HasToken    bool                   `json:"has_token"`
+	Settings    map[string]string      `json:"settings"`
+	Config      map[string]interface{} `json:"config"`
+	ConfigError string                 `json:"config_error"`
+}
+
+func init() {
+	pluginsConfigCmd.Flags().StringArrayVar(&pluginsUnset, "unset", nil, "Config key to remove (repeatable)")
+
+	pluginsCmd.AddCommand(pluginsListCmd)
+	pluginsCmd.AddCommand(pluginsEnableCmd)
+	pluginsCmd.AddCommand(pluginsDisableCmd)
+	pluginsCmd.AddCommand(pluginsConfigCmd)
+
+	rootCmd.AddCommand(pluginsCmd)
+}
+
+// pluginsRequest calls a /api/plugins endpoint and exits on failure
+func pluginsRequest(method, path string, payload interface{}) []byte {
+	var reqBody io.Reader
+	if payload != nil {
+		data, _ := json.Marshal(payload)
+		reqBody = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, serverAddr+"/api/plugins"+path, reqBody)
+	if err != nil {
+		exitError("%v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		exitError("Failed to connect to Thymer Desktop: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		var e struct {
+			Error string `json:"error"`
+		}
+		if json.Unmarshal(body, &e) == nil && e.Error != "" {
+			exitError("%s", e.Error)
+		}
+		exitError("Request failed: %s", strings.TrimSpace(string(body)))
+	}
+	return body
+}
+
+func runPluginsList(cmd *cobra.Command, args []string) {
+	body := pluginsRequest("GET", "", nil)
+
+	if printOutput(body, "id", "name", "enabled", "status", "interval", "last_sync", "collection", "version") {
+		return
+	}
+
+	var plugins []pluginInfo
+	if err := json.Unmarshal(body, &plugins); err != nil {
+		exitError("Invalid response: %s", string(body))
+	}
+	if len(plugins) == 0 {
+		fmt.Println("No sync plugins")
+		return
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "  ID\tNAME\tSTATUS\tINTERVAL\tLAST SYNC\tCOLLECTION\tVERSION")
+	for _, p := range plugins {
+		mark := "●"
+		status := p.Status
+		if !p.Enabled {
+			mark, status = "○", "paused"
+		} else if !p.Loaded {
+			status = "not loaded"
+		}
+		fmt.Fprintf(tw, "%s %s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			mark, p.ID, p.Name, status, p.Interval, formatLastSync(p.LastSync), p.Collection, p.Version)
+	}
+	tw.Flush()
+
+	for _, p := range plugins {
+		if p.LastError != "" {
+			fmt.Printf("\n%s: %s\n", p.ID, colorize("31", p.LastError))
+		}
+	}
+}
+
+func runPluginsToggle(cmd *cobra.Command, args []string) {
+	body := pluginsRequest("POST", "/"+url.PathEscape(args[0])+"/"+cmd.Name(), nil)
+
+	if printOutput(body) {
+		return
+	}
+	if cmd.Name() == "enable" {
+		fmt.Printf("Enabled %s\n", args[0])
+	} else {
+		fmt.Printf("Paused %s\n", args[0])
+	}
+}
+
+func runPluginsConfig(cmd *cobra.Command, args []string) {
+	path := "/" + url.PathEscape(args[0])
+
+	changes := make(map[string]interface{})
+	for _, pair := range args[1:] {
+		key, value, ok := strings.Cut(pair, "=")
+		if !ok || key == "" {
+			exitError("Invalid setting %q (use key=value)", pair)
+		}
+		changes[key] = parseSettingValue(value)
+	}
+	for _, key := range pluginsUnset {
+		changes[key] = nil
+	}
+
+	var body []byte
+	if len(changes) == 0 {
+		body = pluginsRequest("GET", path, nil)
+	} else {
+		body = pluginsRequest("POST", path+"/config", changes)
+	}
+
+	if printOutput(body) {
+		return
+	}
+
+	var p pluginInfo
+	if err := json.Unmarshal(body, &p); err != nil {
+		exitError("Invalid response: %s", string(body))
+	}
+	printPluginConfig(p)
+}
+
+// parseSettingValue reads a value as JSON (numbers, booleans, lists,
+// objects), falling back to the plain string
+func parseSettingValue(s string) interface{} {
+	var v interface{}
+	if err := json.Unmarshal([]byte(s), &v); err == nil {
+		return v
+	}
+	return s
+}
+
+func printPluginConfig(p pluginInfo) {
+	enabled := "yes"
+	if !p.Enabled {
+		enabled = "no (paused)"
+	}
+	fmt.Printf("%s (%s)\n\n", p.Name, p.ID)
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "enabled\t%s\n", enabled)
+	fmt.Fprintf(tw, "interval\t%s\n", p.Interval)
+	keys := make([]string, 0, len(p.Settings))
+	for k := range p.Settings {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Fprintf(tw, "%s\t%s\n", k, p.Settings[k])
+	}
+	token := "not set"
+	if p.HasToken {
+		token = "set"
+	}
+	fmt.Fprintf(tw, "token\t%s\n", dim(token))
+	tw.Flush()
+
+	if p.ConfigError != "" {
+		fmt.Printf("\nconfig: %s\n", colorize("31", p.ConfigError))
+		return
+	}
+	if len(p.Config) == 0 {
+		return
+	}
+
+	fmt.Println("\nconfig:")
+	keys = keys[:0]
+	for k := range p.Config {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	tw = tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, k := range keys {
+		fmt.Fprintf(tw, "  %s\t%s\n", k, cellString(p.Config[k]))
+	}
+	tw.Flush()
+}
+
+// formatLastSync shows how long ago a plugin last synced
+func formatLastSync(t *time.Time) string {
+	if t == nil {
+		return "never"
+	}
+	ago := time.Since(*t)
+	switch {
+	case ago < time.Minute:
+		return "just now"
+	case ago < time.Hour:
+		return fmt.Sprintf("%dm ago", int(ago.Minutes()))
+	case ago < 24*time.Hour:
+		return fmt.Sprintf("%dh ago", int(ago.Hours()))
+	}
+	return t.Local().Format("Jan 2 15:04")
+}
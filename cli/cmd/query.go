@@ -6,7 +6,8 @@ import (
 	"io"
 	"net/http"
 	"net/url"
-	"strings"
+	"os"
+	"time"
 
 	"github.com/spf13/cobra"
 )
@@ -16,6 +17,12 @@ var (
 	queryRepo     string
 	queryAssignee string
 	queryLimit    int
+	queryOffset   int
+	queryCursor   string
+	queryWhere    []string
+	querySort     string
+	queryFields   string
+	queryOffline  bool
 )
 
 var queryCmd = &cobra.Command{
@@ -23,20 +30,39 @@ var queryCmd = &cobra.Command{
 	Short: "Query a collection",
 	Long: `Query records from a Thymer collection.
 
+Filters are checked against the collection's schema. Operators:
+  =  !=  >  >=  <  <=   compare (numbers and dates compare by value)
+  ~                     contains (case-insensitive)
+
 Examples:
   thymer query issues --state=open
   thymer query issues --repo=anthropics/claude-code --limit=10
-  thymer query captures --limit=5
-  thymer query calendar --json`,
-	Args: cobra.ExactArgs(1),
-	Run:  runQuery,
+  thymer query issues --where state=Open --where updated_at>2026-01-01 --sort=-updated_at
+  thymer query people --where organization~acme --fields=title,email
+  thymer query captures --limit=5 --offset=5
+  thymer query calendar --json
+  thymer query issues --offline --state=open
+
+When SyncHub isn't connected, thymer-bar answers from its local mirror of
+collection records and the results are marked stale. --offline always uses
+the mirror.`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeCollectionArg,
+	Run:               runQuery,
 }
 
 func init() {
 	queryCmd.Flags().StringVar(&queryState, "state", "", "Filter by state (open, closed, etc.)")
 	queryCmd.Flags().StringVar(&queryRepo, "repo", "", "Filter by repository")
 	queryCmd.Flags().StringVar(&queryAssignee, "assignee", "", "Filter by assignee")
-	queryCmd.Flags().IntVar(&queryLimit, "limit", 20, "Maximum results to return")
+	queryCmd.Flags().IntVar(&queryLimit, "limit", 20, "Maximum results to return (0 for all)")
+	queryCmd.Flags().IntVar(&queryOffset, "offset", 0, "Skip this many results")
+	queryCmd.Flags().StringVar(&queryCursor, "cursor", "", "Continue from a previous page's cursor")
+	queryCmd.Flags().StringArrayVar(&queryWhere, "where", nil, "Field predicate, e.g. status=Open (repeatable)")
+	queryCmd.Flags().StringVar(&querySort, "sort", "", "Sort fields, comma-separated; prefix with - for descending")
+	queryCmd.Flags().StringVar(&queryFields, "fields", "", "Fields to return, comma-separated")
+	queryCmd.Flags().BoolVar(&queryOffline, "offline", false, "Answer from thymer-bar's local mirror without asking SyncHub")
+	queryCmd.RegisterFlagCompletionFunc("state", completeQueryState)
 
 	rootCmd.AddCommand(queryCmd)
 }
@@ -48,15 +74,32 @@ func runQuery(cmd *cobra.Command, args []string) {
 	params := url.Values{}
 	params.Set("collection", collection)
 	if queryState != "" {
-		params.Set("state", queryState)
+		params.Add("where", "state="+queryState)
 	}
 	if queryRepo != "" {
-		params.Set("repo", queryRepo)
+		params.Add("where", "repo~"+queryRepo)
 	}
 	if queryAssignee != "" {
-		params.Set("assignee", queryAssignee)
+		params.Add("where", "assignee~"+queryAssignee)
+	}
+	for _, where := range queryWhere {
+		params.Add("where", where)
+	}
+	if querySort != "" {
+		params.Set("sort", querySort)
+	}
+	if queryFields != "" {
+		params.Set("fields", queryFields)
 	}
 	params.Set("limit", fmt.Sprintf("%d", queryLimit))
+	if queryCursor != "" {
+		params.Set("cursor", queryCursor)
+	} else if queryOffset > 0 {
+		params.Set("offset", fmt.Sprintf("%d", queryOffset))
+	}
+	if queryOffline {
+		params.Set("offline", "1")
+	}
 
 	// Call desktop API
 	resp, err := http.Get(serverAddr + "/api/query?" + params.Encode())
@@ -71,8 +114,20 @@ func runQuery(cmd *cobra.Command, args []string) {
 		exitError("Query failed: %s", string(body))
 	}
 
-	if jsonOutput {
-		fmt.Println(string(body))
+	// Stderr, so piped output stays clean
+	if resp.Header.Get("X-Stale") != "" {
+		snapshot := "an unknown time"
+		if at, err := time.Parse(time.RFC3339, resp.Header.Get("X-Snapshot-At")); err == nil {
+			snapshot = formatLastSync(&at)
+		}
+		fmt.Fprintln(os.Stderr, dim("Offline: results from the local mirror, last synced "+snapshot))
+	}
+
+	var columns []string
+	if queryFields != "" {
+		columns = splitColumns(queryFields)
+	}
+	if printOutput(body, columns...) {
 		return
 	}
 
@@ -88,41 +143,29 @@ func runQuery(cmd *cobra.Command, args []string) {
 		return
 	}
 
-	for _, r := range results {
-		title := r["title"]
-		state := r["state"]
-		guid := r["guid"]
+	if len(columns) > 0 {
+		renderTable(os.Stdout, results, columns)
+	} else {
+		for _, r := range results {
+			title := r["title"]
+			state := r["state"]
 
-		stateStr := ""
-		if state != nil {
-			stateStr = fmt.Sprintf(" [%s]", state)
-		}
+			stateStr := ""
+			if state != nil {
+				stateStr = fmt.Sprintf(" [%s]", state)
+			}
 
-		fmt.Printf("• %s%s\n", title, stateStr)
-		if jsonOutput {
-			fmt.Printf("  guid: %s\n", guid)
+			fmt.Printf("• %s%s\n", title, stateStr)
 		}
 	}
 
-	fmt.Printf("\n%d result(s)\n", len(results))
-}
-
-// formatTable formats results as a simple table
-func formatTable(results []map[string]interface{}, columns []string) string {
-	if len(results) == 0 {
-		return "No results"
+	total := resp.Header.Get("X-Total-Count")
+	if total == "" || total == fmt.Sprintf("%d", len(results)) {
+		fmt.Printf("\n%d result(s)\n", len(results))
+	} else {
+		fmt.Printf("\n%d of %s result(s)\n", len(results), total)
 	}
-
-	var sb strings.Builder
-	for _, r := range results {
-		var parts []string
-		for _, col := range columns {
-			if v, ok := r[col]; ok && v != nil {
-				parts = append(parts, fmt.Sprintf("%v", v))
-			}
-		}
-		sb.WriteString(strings.Join(parts, " | "))
-		sb.WriteString("\n")
+	if next := resp.Header.Get("X-Next-Cursor"); next != "" {
+		fmt.Printf("Next page: --cursor=%s\n", next)
 	}
-	return sb.String()
 }
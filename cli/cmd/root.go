@@ -24,7 +24,13 @@ Examples:
   thymer query issues --state=open
   thymer sync github
   thymer capture "Quick note from terminal"
+  thymer ask "what did I promise Maria last week?"
   thymer mcp status`,
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		if err := setupOutput(); err != nil {
+			exitError("%v", err)
+		}
+	},
 }
 
 func Execute() error {
@@ -0,0 +1,269 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	searchCollection string
+	searchLimit      int
+	searchPick       bool
+	searchLocal      bool
+	searchSemantic   bool
+	searchSimilarTo  string
+)
+
+var searchCmd = &cobra.Command{
+	Use:   "search [query]",
+	Short: "Search the workspace",
+	Long: `Search across all notes and collections.
+
+Matches are highlighted in the terminal. Each result shows its GUID for use
+with other commands; --pick lets you choose one to open in the browser.
+
+--local searches Thymer Desktop's own index of the workspace instead, which
+is instant, ranked, and works while Thymer is closed. It understands
+"exact phrases", field:value and -word.
+
+--semantic finds records by meaning using embeddings computed by Thymer
+Desktop (configure embeddings.model first); keyword matches still rank
+high. --similar-to lists records related to a given one.
+
+Examples:
+  thymer search "quarterly planning"
+  thymer search --collection=people acme
+  thymer search --limit=20 oauth
+  thymer search --pick "release notes"
+  thymer search --local 'repo:thymer state:open login'
+  thymer search --local '"release notes" -draft'
+  thymer search --semantic "ideas for the team offsite"
+  thymer search --similar-to 01HXYZ... --collection=captures`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(args) == 0 && searchSimilarTo == "" {
+			return fmt.Errorf("requires a query")
+		}
+		return nil
+	},
+	Run: runSearch,
+}
+
+func init() {
+	searchCmd.Flags().StringVar(&searchCollection, "collection", "", "Only search this collection")
+	searchCmd.Flags().IntVar(&searchLimit, "limit", 10, "Maximum results to return")
+	searchCmd.Flags().BoolVar(&searchPick, "pick", false, "Choose a result to open in the browser")
+	searchCmd.Flags().BoolVar(&searchLocal, "local", false, "Search Thymer Desktop's local index (works offline)")
+	searchCmd.Flags().BoolVar(&searchSemantic, "semantic", false, "Search by meaning with local embeddings")
+	searchCmd.Flags().StringVar(&searchSimilarTo, "similar-to", "", "Find records related to this GUID")
+	searchCmd.RegisterFlagCompletionFunc("collection", completeCollections)
+
+	rootCmd.AddCommand(searchCmd)
+}
+
+type searchResult struct {
+	GUID       string  `json:"guid"`
+	Title      string  `json:"title"`
+	Collection string  `json:"collection,omitempty"` // Local search only
+	Snippet    string  `json:"snippet"`
+	Similarity float64 `json:"similarity,omitempty"` // Semantic search only
+}
+
+func runSearch(cmd *cobra.Command, args []string) {
+	query := strings.Join(args, " ")
+
+	if searchSemantic || searchSimilarTo != "" {
+		searchLocal = true
+	}
+
+	var respBody []byte
+	if searchLocal {
+		respBody = searchLocalIndex(query)
+	} else {
+		respBody = searchWorkspace(query)
+	}
+
+	var result struct {
+		Results []searchResult `json:"results"`
+		Error   string         `json:"error"`
+	}
+	if err := json.Unmarshal(respBody, &result); err != nil {
+		exitError("Invalid response: %s", string(respBody))
+	}
+	if result.Error != "" {
+		exitError("Search failed: %s", result.Error)
+	}
+
+	columns := []string{"guid", "title", "snippet"}
+	switch {
+	case searchSemantic || searchSimilarTo != "":
+		columns = []string{"guid", "title", "collection", "similarity", "snippet"}
+	case searchLocal:
+		columns = []string{"guid", "title", "collection", "snippet"}
+	}
+	if !searchPick && printValueOutput(result.Results, columns...) {
+		return
+	}
+
+	if len(result.Results) == 0 {
+		fmt.Println("No results found")
+		return
+	}
+
+	highlight := highlighter(query)
+	for i, r := range result.Results {
+		where := r.GUID
+		if r.Collection != "" {
+			where = r.Collection + " " + r.GUID
+		}
+		if r.Similarity != 0 {
+			where += fmt.Sprintf(" %.2f", r.Similarity)
+		}
+		fmt.Printf("%2d. %s  %s\n", i+1, highlight(r.Title), dim(where))
+		if snippet := strings.Join(strings.Fields(r.Snippet), " "); snippet != "" {
+			fmt.Printf("    %s\n", highlight(snippet))
+		}
+	}
+
+	if searchPick {
+		pickSearchResult(result.Results)
+		return
+	}
+
+	fmt.Printf("\n%d result(s)\n", len(result.Results))
+}
+
+// searchWorkspace runs Thymer's own search through SyncHub
+func searchWorkspace(query string) []byte {
+	toolArgs := map[string]interface{}{
+		"query": query,
+		"limit": searchLimit,
+	}
+	if searchCollection != "" {
+		toolArgs["collection"] = searchCollection
+	}
+
+	body, _ := json.Marshal(map[string]interface{}{
+		"name": "search_workspace",
+		"args": toolArgs,
+	})
+	resp, err := http.Post(serverAddr+"/api/mcp/call", "application/json", bytes.NewReader(body))
+	if err != nil {
+		exitError("Failed to connect to Thymer Desktop: %v", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, _ := io.ReadAll(resp.Body)
+
+	if resp.StatusCode != http.StatusOK {
+		exitError("Search failed: %s", string(respBody))
+	}
+	return respBody
+}
+
+// searchLocalIndex runs the query against thymer-bar's local index
+func searchLocalIndex(query string) []byte {
+	params := url.Values{}
+	if query != "" {
+		params.Set("q", query)
+	}
+	params.Set("limit", strconv.Itoa(searchLimit))
+	if searchSemantic {
+		params.Set("semantic", "1")
+	}
+	if searchSimilarTo != "" {
+		params.Set("similar_to", searchSimilarTo)
+	}
+	if searchCollection != "" {
+		params.Set("collection", searchCollection)
+	}
+
+	resp, err := http.Get(serverAddr + "/api/search?" + params.Encode())
+	if err != nil {
+		exitError("Failed to connect to Thymer Desktop: %v", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, _ := io.ReadAll(resp.Body)
+
+	if resp.StatusCode != http.StatusOK {
+		var e struct {
+			Error string `json:"error"`
+		}
+		if json.Unmarshal(respBody, &e) == nil && e.Error != "" {
+			exitError("Search failed: %s", e.Error)
+		}
+		exitError("Search failed: %s", string(respBody))
+	}
+	return respBody
+}
+
+func pickSearchResult(results []searchResult) {
+	fmt.Printf("\nOpen which? [1-%d, Enter to cancel]: ", len(results))
+	line, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return
+	}
+
+	n, err := strconv.Atoi(line)
+	if err != nil || n < 1 || n > len(results) {
+		exitError("Invalid choice: %s", line)
+	}
+	chosen := results[n-1]
+
+	body, _ := json.Marshal(map[string]string{"guid": chosen.GUID})
+	resp, err := http.Post(serverAddr+"/api/open", "application/json", bytes.NewReader(body))
+	if err != nil {
+		exitError("Failed to connect to Thymer Desktop: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var opened struct {
+		URL string `json:"url"`
+	}
+	json.NewDecoder(resp.Body).Decode(&opened)
+	fmt.Printf("Opened %s\n", opened.URL)
+}
+
+// highlighter returns a function that marks the query's words in text
+func highlighter(query string) func(string) string {
+	if !colorEnabled() {
+		return func(s string) string { return s }
+	}
+
+	// Local search syntax: -word, field:value, "phrase"
+	var words []string
+	for _, w := range strings.Fields(query) {
+		if strings.HasPrefix(w, "-") {
+			continue
+		}
+		if _, value, ok := strings.Cut(w, ":"); ok {
+			w = value
+		}
+		w = strings.Trim(w, `"`)
+		if len(w) > 1 {
+			words = append(words, regexp.QuoteMeta(w))
+		}
+	}
+	if len(words) == 0 {
+		return func(s string) string { return s }
+	}
+
+	re := regexp.MustCompile("(?i)" + strings.Join(words, "|"))
+	return func(s string) string {
+		return re.ReplaceAllStringFunc(s, func(m string) string {
+			return "\033[1;33m" + m + "\033[0m"
+		})
+	}
+}
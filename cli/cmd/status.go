@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 )
@@ -16,7 +18,9 @@ var statusCmd = &cobra.Command{
 - Connection to Thymer
 - Registered plugins
 - Local LLM status
-- MCP server status`,
+- MCP server status
+- Scheduled syncs and when they next run
+- The offline mirror of collection records`,
 	Run: runStatus,
 }
 
@@ -33,8 +37,7 @@ func runStatus(cmd *cobra.Command, args []string) {
 
 	body, _ := io.ReadAll(resp.Body)
 
-	if jsonOutput {
-		fmt.Println(string(body))
+	if printOutput(body) {
 		return
 	}
 
@@ -48,7 +51,7 @@ func runStatus(cmd *cobra.Command, args []string) {
 	fmt.Println("Thymer Desktop")
 	fmt.Println("==============")
 
-	if connected, ok := status["thymer_connected"].(bool); ok {
+	if connected, ok := status["connected"].(bool); ok {
 		if connected {
 			fmt.Println("Thymer:     ● Connected")
 		} else {
@@ -74,11 +77,47 @@ func runStatus(cmd *cobra.Command, args []string) {
 		}
 	}
 
+	var mirror struct {
+		Mirror *struct {
+			Collections  int        `json:"collections"`
+			Records      int        `json:"records"`
+			LastSnapshot *time.Time `json:"last_snapshot"`
+		} `json:"mirror"`
+		Embeddings *struct {
+			Model     string `json:"model"`
+			Embedded  int    `json:"embedded"`
+			Pending   int    `json:"pending"`
+			LastError string `json:"last_error"`
+		} `json:"embeddings"`
+	}
+	json.Unmarshal(body, &mirror)
+	if m := mirror.Mirror; m != nil {
+		if m.LastSnapshot != nil {
+			fmt.Printf("Mirror:     ● %d records in %d collections, synced %s\n", m.Records, m.Collections, formatLastSync(m.LastSnapshot))
+		} else {
+			fmt.Println("Mirror:     ○ Empty")
+		}
+	}
+	if e := mirror.Embeddings; e != nil {
+		line := fmt.Sprintf("%s, %d embedded", e.Model, e.Embedded)
+		if e.Pending > 0 {
+			line += fmt.Sprintf(", %d pending", e.Pending)
+		}
+		if e.LastError != "" {
+			fmt.Printf("Embeddings: ○ %s (%s)\n", line, colorize("31", e.LastError))
+		} else {
+			fmt.Printf("Embeddings: ● %s\n", line)
+		}
+	}
+
 	if plugins, ok := status["plugins"].([]interface{}); ok {
 		fmt.Printf("\nPlugins (%d):\n", len(plugins))
 		for _, p := range plugins {
 			if pm, ok := p.(map[string]interface{}); ok {
-				name := pm["name"]
+				name := fmt.Sprint(pm["name"])
+				if id, _ := pm["id"].(string); id != "" && id != name {
+					name += " (" + id + ")"
+				}
 				enabled := pm["enabled"]
 				if enabled == true {
 					fmt.Printf("  ● %s\n", name)
@@ -88,4 +127,58 @@ func runStatus(cmd *cobra.Command, args []string) {
 			}
 		}
 	}
+
+	var parsed struct {
+		Schedule *struct {
+			QuietHours string `json:"quiet_hours"`
+			Quiet      bool   `json:"quiet"`
+			Entries    []struct {
+				Plugin  string     `json:"plugin"`
+				Cron    string     `json:"cron"`
+				NextRun *time.Time `json:"next_run"`
+				Pending string     `json:"pending"`
+				Error   string     `json:"error"`
+			} `json:"entries"`
+		} `json:"schedule"`
+	}
+	json.Unmarshal(body, &parsed)
+	if s := parsed.Schedule; s != nil && len(s.Entries) > 0 {
+		fmt.Printf("\nSchedule (%d):\n", len(s.Entries))
+		if s.QuietHours != "" {
+			quiet := ""
+			if s.Quiet {
+				quiet = " (now)"
+			}
+			fmt.Printf("  Quiet hours %s%s\n", s.QuietHours, quiet)
+		}
+		for _, e := range s.Entries {
+			var when string
+			switch {
+			case e.Error != "":
+				when = colorize("31", "invalid: "+e.Error)
+			case e.Pending != "":
+				when = "queued, " + e.Pending
+			case e.NextRun != nil:
+				when = "next " + formatNextRun(*e.NextRun)
+			default:
+				when = "never"
+			}
+			fmt.Printf("  %-12s %-16s %s\n", e.Plugin, e.Cron, when)
+		}
+	}
+}
+
+// formatNextRun shows a time as "14:30 (in 12m)", with the date when it
+// isn't today
+func formatNextRun(t time.Time) string {
+	t = t.Local()
+	layout := "15:04"
+	if y, m, d := time.Now().Date(); t.Year() != y || t.Month() != m || t.Day() != d {
+		layout = "Mon Jan 2 15:04"
+	}
+	in := time.Until(t).Round(time.Minute)
+	if in < time.Minute {
+		return t.Format(layout) + " (now)"
+	}
+	return fmt.Sprintf("%s (in %s)", t.Format(layout), strings.TrimSuffix(in.String(), "0s"))
 }
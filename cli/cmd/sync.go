@@ -1,45 +1,115 @@
 package cmd
 
 import (
+	"bufio"
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 )
 
-var syncAll bool
+var (
+	syncAll     bool
+	syncWait    bool
+	syncTimeout time.Duration
+
+	historyPlugin string
+	historyFailed bool
+	historyLimit  int
+)
+
+// Exit codes for sync --wait
+const (
+	syncExitFailed  = 1
+	syncExitTimeout = 2
+)
 
 var syncCmd = &cobra.Command{
 	Use:   "sync [plugin]",
 	Short: "Trigger a sync",
 	Long: `Trigger a sync for a specific plugin or all plugins.
 
+With --wait, progress is shown as each plugin syncs and the command exits
+non-zero if any plugin failed (1) or the sync didn't finish within
+--timeout (2), so it can be used from cron and scripts.
+
 Examples:
   thymer sync github
   thymer sync readwise
-  thymer sync --all`,
-	Run: runSync,
+  thymer sync --all
+  thymer sync --all --wait --timeout=10m`,
+	ValidArgsFunction: completePlugins,
+	Run:               runSync,
+}
+
+var syncHistoryCmd = &cobra.Command{
+	Use:   "history",
+	Short: "Show past syncs",
+	Long: `Show recent sync runs, one line per plugin, newest first.
+
+Examples:
+  thymer sync history
+  thymer sync history --plugin=github --limit=5
+  thymer sync history --failed`,
+	Args: cobra.NoArgs,
+	Run:  runSyncHistory,
 }
 
 func init() {
 	syncCmd.Flags().BoolVar(&syncAll, "all", false, "Sync all enabled plugins")
+	syncCmd.Flags().BoolVar(&syncWait, "wait", false, "Wait for the sync to finish and show progress")
+	syncCmd.Flags().DurationVar(&syncTimeout, "timeout", 10*time.Minute, "With --wait, give up after this long (0 for no limit)")
+
+	syncHistoryCmd.Flags().StringVar(&historyPlugin, "plugin", "", "Only show this plugin")
+	syncHistoryCmd.Flags().BoolVar(&historyFailed, "failed", false, "Only show failed syncs")
+	syncHistoryCmd.Flags().IntVar(&historyLimit, "limit", 20, "Maximum sync jobs to show (0 for all)")
+	syncHistoryCmd.RegisterFlagCompletionFunc("plugin", completePlugins)
 
+	syncCmd.AddCommand(syncHistoryCmd)
 	rootCmd.AddCommand(syncCmd)
 }
 
+// syncResult is one plugin's outcome in a sync job
+type syncResult struct {
+	Plugin     string `json:"plugin"`
+	Status     string `json:"status"`
+	Created    int    `json:"created"`
+	Updated    int    `json:"updated"`
+	Skipped    int    `json:"skipped"`
+	Summary    string `json:"summary"`
+	Error      string `json:"error"`
+	DurationMs int64  `json:"duration_ms"`
+}
+
+type syncJob struct {
+	ID        string       `json:"id"`
+	Plugin    string       `json:"plugin"`
+	Trigger   string       `json:"trigger"`
+	Status    string       `json:"status"`
+	StartedAt time.Time    `json:"started_at"`
+	Results   []syncResult `json:"results"`
+	Error     string       `json:"error"`
+}
+
 func runSync(cmd *cobra.Command, args []string) {
 	if !syncAll && len(args) == 0 {
 		exitError("Specify a plugin name or use --all")
 	}
 
-	var payload map[string]interface{}
+	payload := map[string]interface{}{"trigger": "cli"}
 	if syncAll {
-		payload = map[string]interface{}{"all": true}
+		payload["all"] = true
 	} else {
-		payload = map[string]interface{}{"plugin": args[0]}
+		payload["plugin"] = args[0]
 	}
 
 	body, _ := json.Marshal(payload)
@@ -55,20 +125,259 @@ func runSync(cmd *cobra.Command, args []string) {
 		exitError("Sync failed: %s", string(respBody))
 	}
 
-	if jsonOutput {
-		fmt.Println(string(respBody))
+	var result struct {
+		Message string `json:"message"`
+		JobID   string `json:"job_id"`
+	}
+	json.Unmarshal(respBody, &result)
+
+	if syncWait && result.JobID != "" {
+		job, raw := waitForSync(result.JobID)
+		if !printOutput(raw) {
+			printSyncSummary(job)
+		}
+		if job.Status != "success" {
+			os.Exit(syncExitFailed)
+		}
 		return
 	}
 
-	var result map[string]interface{}
-	if err := json.Unmarshal(respBody, &result); err != nil {
-		fmt.Println("Sync triggered")
+	if printOutput(respBody) {
 		return
 	}
 
-	if msg, ok := result["message"]; ok {
-		fmt.Println(msg)
-	} else {
+	switch {
+	case result.Message != "":
+		fmt.Println(result.Message)
+	case result.JobID != "":
+		fmt.Printf("Sync triggered (job %s)\n", result.JobID)
+	default:
 		fmt.Println("Sync triggered")
 	}
 }
+
+// waitForSync follows a job's progress events until it's done, and returns
+// the final job decoded and as sent
+func waitForSync(id string) (*syncJob, json.RawMessage) {
+	ctx := context.Background()
+	if syncTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, syncTimeout)
+		defer cancel()
+	}
+
+	req, _ := http.NewRequestWithContext(ctx, "GET", serverAddr+"/api/syncs/"+id+"/events", nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		exitError("Failed to follow sync: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		exitError("Failed to follow sync: %s", strings.TrimSpace(string(respBody)))
+	}
+
+	// Progress is for people; structured output only gets the final job
+	showProgress := outputFormat == "" && outputTemplate == ""
+	printed := make(map[string]bool)
+	event := ""
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "event:") {
+			event = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+			continue
+		}
+		if !strings.HasPrefix(line, "data:") {
+			continue
+		}
+		data := []byte(strings.TrimSpace(strings.TrimPrefix(line, "data:")))
+
+		switch event {
+		case "job":
+			var job syncJob
+			json.Unmarshal(data, &job)
+			for _, r := range job.Results {
+				if showProgress {
+					printSyncProgress(r, printed)
+				}
+			}
+		case "started", "finished":
+			var progress struct {
+				Result syncResult `json:"result"`
+			}
+			json.Unmarshal(data, &progress)
+			if showProgress {
+				printSyncProgress(progress.Result, printed)
+			}
+		case "done":
+			var done struct {
+				Job json.RawMessage `json:"job"`
+			}
+			json.Unmarshal(data, &done)
+			var job syncJob
+			json.Unmarshal(done.Job, &job)
+			if showProgress {
+				for _, r := range job.Results {
+					printSyncProgress(r, printed)
+				}
+			}
+			return &job, done.Job
+		}
+	}
+
+	if ctx.Err() == context.DeadlineExceeded {
+		fmt.Fprintf(os.Stderr, "Error: Sync still running after %s (job %s)\n", syncTimeout, id)
+		os.Exit(syncExitTimeout)
+	}
+	if err := scanner.Err(); err != nil {
+		exitError("Lost connection while following sync: %v", err)
+	}
+	exitError("Lost connection while following sync")
+	return nil, nil
+}
+
+// printSyncProgress prints a plugin's state once per state
+func printSyncProgress(r syncResult, printed map[string]bool) {
+	key := r.Plugin + "/" + r.Status
+	if printed[key] {
+		return
+	}
+	printed[key] = true
+
+	switch r.Status {
+	case "running":
+		fmt.Printf("  … %s syncing\n", r.Plugin)
+	case "error":
+		fmt.Printf("  ✗ %s: %s\n", r.Plugin, r.Error)
+	case "skipped":
+		fmt.Printf("  - %s skipped: %s\n", r.Plugin, r.Summary)
+	default:
+		fmt.Printf("  ✓ %s: %d created, %d updated, %d skipped %s\n",
+			r.Plugin, r.Created, r.Updated, r.Skipped, dim(formatSyncDuration(r.DurationMs)))
+	}
+}
+
+func printSyncSummary(job *syncJob) {
+	failed := 0
+	for _, r := range job.Results {
+		if r.Status == "error" {
+			failed++
+		}
+	}
+
+	switch {
+	case job.Error != "":
+		fmt.Printf("Sync failed: %s\n", job.Error)
+	case failed > 0:
+		fmt.Printf("Sync finished with %d failed plugin(s)\n", failed)
+	default:
+		fmt.Println("Sync complete")
+	}
+}
+
+func formatSyncDuration(ms int64) string {
+	if ms <= 0 {
+		return ""
+	}
+	return "(" + (time.Duration(ms) * time.Millisecond).Round(100*time.Millisecond).String() + ")"
+}
+
+func runSyncHistory(cmd *cobra.Command, args []string) {
+	params := url.Values{}
+	params.Set("limit", strconv.Itoa(historyLimit))
+	if historyPlugin != "" {
+		params.Set("plugin", historyPlugin)
+	}
+	if historyFailed {
+		params.Set("status", "error")
+	}
+
+	resp, err := http.Get(serverAddr + "/api/syncs?" + params.Encode())
+	if err != nil {
+		exitError("Failed to connect to Thymer Desktop: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		exitError("Failed to get sync history: %s", strings.TrimSpace(string(body)))
+	}
+
+	var jobs []syncJob
+	if err := json.Unmarshal(body, &jobs); err != nil {
+		exitError("Invalid response: %s", string(body))
+	}
+
+	// One row per plugin run
+	type historyRow struct {
+		Started  string `json:"started"`
+		Plugin   string `json:"plugin"`
+		Trigger  string `json:"trigger"`
+		Status   string `json:"status"`
+		Created  int    `json:"created"`
+		Updated  int    `json:"updated"`
+		Skipped  int    `json:"skipped"`
+		Duration string `json:"duration"`
+		Error    string `json:"error"`
+		Job      string `json:"job"`
+	}
+	rows := []historyRow{}
+	for _, job := range jobs {
+		if len(job.Results) == 0 {
+			// Failed before any plugin ran, or an older SyncHub without results
+			plugin := job.Plugin
+			if plugin == "" {
+				plugin = "all"
+			}
+			rows = append(rows, historyRow{
+				Started: job.StartedAt.Local().Format("2006-01-02 15:04"),
+				Plugin:  plugin,
+				Trigger: job.Trigger,
+				Status:  job.Status,
+				Error:   job.Error,
+				Job:     job.ID,
+			})
+			continue
+		}
+		for _, r := range job.Results {
+			if historyPlugin != "" && r.Plugin != historyPlugin {
+				continue
+			}
+			if historyFailed && r.Status != "error" {
+				continue
+			}
+			rows = append(rows, historyRow{
+				Started:  job.StartedAt.Local().Format("2006-01-02 15:04"),
+				Plugin:   r.Plugin,
+				Trigger:  job.Trigger,
+				Status:   r.Status,
+				Created:  r.Created,
+				Updated:  r.Updated,
+				Skipped:  r.Skipped,
+				Duration: strings.Trim(formatSyncDuration(r.DurationMs), "()"),
+				Error:    r.Error,
+				Job:      job.ID,
+			})
+		}
+	}
+
+	columns := []string{"started", "plugin", "trigger", "status", "created", "updated", "skipped", "duration", "error"}
+	if printValueOutput(rows, columns...) {
+		return
+	}
+
+	if len(rows) == 0 {
+		fmt.Println("No syncs yet")
+		return
+	}
+	table := make([]map[string]interface{}, len(rows))
+	for i, r := range rows {
+		data, _ := json.Marshal(r)
+		json.Unmarshal(data, &table[i])
+	}
+	renderTable(os.Stdout, table, columns)
+}
@@ -0,0 +1,510 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	toolArgs     []string
+	toolArgsFile string
+)
+
+var toolCmd = &cobra.Command{
+	Use:   "tool",
+	Short: "Call any Thymer tool",
+	Long: `List, describe and call the tools SyncHub and installed collections
+expose, with arguments checked against each tool's schema.
+
+Examples:
+  thymer tool list
+  thymer tool describe issues_find
+  thymer tool call issues_find --arg state=open --arg limit=5
+  thymer tool call save_note --args-file note.json`,
+}
+
+var toolListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List available tools",
+	Args:  cobra.NoArgs,
+	Run:   runToolList,
+}
+
+var toolDescribeCmd = &cobra.Command{
+	Use:               "describe <name>",
+	Short:             "Show a tool's arguments",
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeTools,
+	Run:               runToolDescribe,
+}
+
+var toolCallCmd = &cobra.Command{
+	Use:   "call <name>",
+	Short: "Call a tool",
+	Long: `Call a tool with arguments given as --arg key=value.
+
+Values are converted to the type the tool's schema declares: numbers,
+booleans, arrays (comma-separated, JSON, or --arg repeated) and objects
+(JSON). --args-file reads a JSON object of arguments from a file, or stdin
+with -; --arg values override it.
+
+Examples:
+  thymer tool call search_workspace --arg query=oauth --arg limit=5
+  thymer tool call log_to_journal --arg content="Shipped it" --arg section=Evening
+  echo '{"guid":"01HXYZ..."}' | thymer tool call get_note --args-file -`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeTools,
+	Run:               runToolCall,
+}
+
+func init() {
+	toolCallCmd.Flags().StringArrayVar(&toolArgs, "arg", nil, "Argument as key=value (repeatable)")
+	toolCallCmd.Flags().StringVar(&toolArgsFile, "args-file", "", "JSON file with arguments (- for stdin)")
+
+	toolCmd.AddCommand(toolListCmd)
+	toolCmd.AddCommand(toolDescribeCmd)
+	toolCmd.AddCommand(toolCallCmd)
+
+	rootCmd.AddCommand(toolCmd)
+}
+
+// toolInfo is a tool as listed by /api/mcp/tools
+type toolInfo struct {
+	Name        string      `json:"name"`
+	Description string      `json:"description"`
+	InputSchema *toolSchema `json:"inputSchema"`
+}
+
+// toolSchema is the subset of JSON Schema tools use for their arguments
+type toolSchema struct {
+	Type        interface{}            `json:"type"`
+	Description string                 `json:"description"`
+	Properties  map[string]*toolSchema `json:"properties"`
+	Required    []string               `json:"required"`
+	Items       *toolSchema            `json:"items"`
+	Enum        []interface{}          `json:"enum"`
+	Default     interface{}            `json:"default"`
+}
+
+// typeName returns the schema's type, ignoring "null" in type unions
+func (s *toolSchema) typeName() string {
+	if s == nil {
+		return ""
+	}
+	switch t := s.Type.(type) {
+	case string:
+		return t
+	case []interface{}:
+		for _, v := range t {
+			if name, ok := v.(string); ok && name != "null" {
+				return name
+			}
+		}
+	}
+	return ""
+}
+
+func (s *toolSchema) isRequired(key string) bool {
+	for _, r := range s.Required {
+		if r == key {
+			return true
+		}
+	}
+	return false
+}
+
+// argNames returns the schema's properties, required ones first
+func (s *toolSchema) argNames() []string {
+	names := make([]string, 0, len(s.Properties))
+	for name := range s.Properties {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		ri, rj := s.isRequired(names[i]), s.isRequired(names[j])
+		if ri != rj {
+			return ri
+		}
+		return names[i] < names[j]
+	})
+	return names
+}
+
+func fetchTools() ([]toolInfo, []byte) {
+	resp, err := http.Get(serverAddr + "/api/mcp/tools")
+	if err != nil {
+		exitError("Failed to connect to Thymer Desktop: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		exitError("Failed to list tools: %s", strings.TrimSpace(string(body)))
+	}
+
+	var tools []toolInfo
+	if err := json.Unmarshal(body, &tools); err != nil {
+		exitError("Invalid response: %s", string(body))
+	}
+	return tools, body
+}
+
+func findTool(name string) *toolInfo {
+	tools, _ := fetchTools()
+	for i := range tools {
+		if tools[i].Name == name {
+			if tools[i].InputSchema == nil {
+				tools[i].InputSchema = &toolSchema{Type: "object"}
+			}
+			return &tools[i]
+		}
+	}
+	if len(tools) == 0 {
+		exitError("Unknown tool %q (no tools available; is SyncHub connected?)", name)
+	}
+	exitError("Unknown tool %q (see 'thymer tool list')", name)
+	return nil
+}
+
+func runToolList(cmd *cobra.Command, args []string) {
+	tools, body := fetchTools()
+
+	if printOutput(body, "name", "description") {
+		return
+	}
+
+	if len(tools) == 0 {
+		fmt.Println("No tools available (is SyncHub connected?)")
+		return
+	}
+
+	sort.Slice(tools, func(i, j int) bool { return tools[i].Name < tools[j].Name })
+	rows := make([]map[string]interface{}, len(tools))
+	for i, t := range tools {
+		// First sentence is enough for the overview
+		desc := strings.SplitN(t.Description, "\n", 2)[0]
+		if i := strings.Index(desc, ". "); i > 0 {
+			desc = desc[:i+1]
+		}
+		rows[i] = map[string]interface{}{"name": t.Name, "description": desc}
+	}
+	renderTable(os.Stdout, rows, []string{"name", "description"})
+	fmt.Printf("\n%d tool(s). Run 'thymer tool describe <name>' for arguments.\n", len(tools))
+}
+
+func runToolDescribe(cmd *cobra.Command, args []string) {
+	tool := findTool(args[0])
+
+	if printValueOutput(tool) {
+		return
+	}
+
+	fmt.Println(tool.Name)
+	if tool.Description != "" {
+		fmt.Printf("\n%s\n", tool.Description)
+	}
+
+	schema := tool.InputSchema
+	if len(schema.Properties) == 0 {
+		fmt.Printf("\nNo arguments.\n\nUsage:\n  thymer tool call %s\n", tool.Name)
+		return
+	}
+
+	fmt.Println("\nArguments:")
+	var usage []string
+	for _, name := range schema.argNames() {
+		prop := schema.Properties[name]
+		label := fmt.Sprintf("%s=<%s>", name, argTypeLabel(prop))
+		if schema.isRequired(name) {
+			usage = append(usage, "--arg "+label)
+			label += "  (required)"
+		}
+		fmt.Printf("  %s\n", label)
+		if prop.Description != "" {
+			fmt.Printf("      %s\n", prop.Description)
+		}
+		if len(prop.Enum) > 0 {
+			fmt.Printf("      One of: %s\n", joinValues(prop.Enum))
+		}
+		if prop.Default != nil {
+			fmt.Printf("      Default: %s\n", cellString(prop.Default))
+		}
+	}
+
+	fmt.Printf("\nUsage:\n  thymer tool call %s", tool.Name)
+	for _, u := range usage {
+		fmt.Printf(" %s", u)
+	}
+	fmt.Println()
+}
+
+// argTypeLabel describes a property's type for help text
+func argTypeLabel(prop *toolSchema) string {
+	switch t := prop.typeName(); t {
+	case "":
+		return "value"
+	case "array":
+		if item := prop.Items.typeName(); item != "" {
+			return item + ",..."
+		}
+		return "json array"
+	case "object":
+		return "json"
+	default:
+		return t
+	}
+}
+
+func joinValues(values []interface{}) string {
+	parts := make([]string, len(values))
+	for i, v := range values {
+		parts[i] = cellString(v)
+	}
+	return strings.Join(parts, ", ")
+}
+
+func runToolCall(cmd *cobra.Command, args []string) {
+	tool := findTool(args[0])
+	schema := tool.InputSchema
+
+	callArgs := make(map[string]interface{})
+	if toolArgsFile != "" {
+		callArgs = readArgsFile(cmd, toolArgsFile)
+		source := toolArgsFile
+		if source == "-" {
+			source = "stdin"
+		}
+		for key, value := range callArgs {
+			if err := checkArgValue(schema, key, value); err != nil {
+				exitError("%s: %v", source, err)
+			}
+		}
+	}
+
+	// Repeated --arg for an array appends rather than replaces
+	repeated := make(map[string]bool)
+	for _, a := range toolArgs {
+		key, raw, ok := strings.Cut(a, "=")
+		if !ok || key == "" {
+			exitError("Invalid --arg %q (use key=value)", a)
+		}
+		value, err := parseArgValue(schema, key, raw)
+		if err != nil {
+			exitError("%v", err)
+		}
+		if list, isList := value.([]interface{}); isList && repeated[key] {
+			if existing, ok := callArgs[key].([]interface{}); ok {
+				value = append(existing, list...)
+			}
+		}
+		callArgs[key] = value
+		repeated[key] = true
+	}
+
+	var missing []string
+	for _, name := range schema.Required {
+		if _, ok := callArgs[name]; !ok {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		exitError("Missing required argument(s): %s (see 'thymer tool describe %s')", strings.Join(missing, ", "), tool.Name)
+	}
+
+	result, err := callTool(tool.Name, callArgs)
+	if err != nil {
+		exitError("%v", err)
+	}
+
+	if !printOutput(result) {
+		var pretty interface{}
+		if err := json.Unmarshal(result, &pretty); err == nil {
+			data, _ := json.MarshalIndent(pretty, "", "  ")
+			fmt.Println(string(data))
+		} else {
+			fmt.Println(strings.TrimSpace(string(result)))
+		}
+	}
+
+	if msg := toolError(result); msg != "" {
+		os.Exit(1)
+	}
+}
+
+func readArgsFile(cmd *cobra.Command, path string) map[string]interface{} {
+	var data []byte
+	var err error
+	if path == "-" {
+		data, err = io.ReadAll(cmd.InOrStdin())
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		exitError("Failed to read arguments: %v", err)
+	}
+
+	var args map[string]interface{}
+	if err := json.Unmarshal(data, &args); err != nil {
+		exitError("Arguments must be a JSON object: %v", err)
+	}
+	if args == nil {
+		args = make(map[string]interface{})
+	}
+	return args
+}
+
+// argSchema looks up a property, failing for arguments the tool doesn't take
+func argSchema(schema *toolSchema, key string) (*toolSchema, error) {
+	prop, ok := schema.Properties[key]
+	if !ok {
+		if len(schema.Properties) == 0 {
+			return nil, fmt.Errorf("unknown argument %q (tool takes no arguments)", key)
+		}
+		return nil, fmt.Errorf("unknown argument %q (valid: %s)", key, strings.Join(schema.argNames(), ", "))
+	}
+	if prop == nil {
+		prop = &toolSchema{}
+	}
+	return prop, nil
+}
+
+// parseArgValue converts a --arg string to the property's schema type
+func parseArgValue(schema *toolSchema, key, raw string) (interface{}, error) {
+	prop, err := argSchema(schema, key)
+	if err != nil {
+		return nil, err
+	}
+
+	value, err := convertArg(prop, raw)
+	if err != nil {
+		return nil, fmt.Errorf("argument %q: %v", key, err)
+	}
+	if err := checkEnum(prop, value); err != nil {
+		return nil, fmt.Errorf("argument %q: %v", key, err)
+	}
+	return value, nil
+}
+
+func convertArg(prop *toolSchema, raw string) (interface{}, error) {
+	switch prop.typeName() {
+	case "integer":
+		n, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not an integer", raw)
+		}
+		return n, nil
+	case "number":
+		f, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not a number", raw)
+		}
+		return f, nil
+	case "boolean":
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not true or false", raw)
+		}
+		return b, nil
+	case "array":
+		if strings.HasPrefix(strings.TrimSpace(raw), "[") {
+			var list []interface{}
+			if err := json.Unmarshal([]byte(raw), &list); err != nil {
+				return nil, fmt.Errorf("invalid JSON array: %v", err)
+			}
+			for _, item := range list {
+				if err := checkType(prop.Items, item); err != nil {
+					return nil, err
+				}
+			}
+			return list, nil
+		}
+		var list []interface{}
+		for _, part := range strings.Split(raw, ",") {
+			item := interface{}(strings.TrimSpace(part))
+			if prop.Items != nil {
+				converted, err := convertArg(prop.Items, strings.TrimSpace(part))
+				if err != nil {
+					return nil, err
+				}
+				item = converted
+			}
+			list = append(list, item)
+		}
+		return list, nil
+	case "object":
+		var obj map[string]interface{}
+		if err := json.Unmarshal([]byte(raw), &obj); err != nil {
+			return nil, fmt.Errorf("expected a JSON object: %v", err)
+		}
+		return obj, nil
+	default:
+		return raw, nil
+	}
+}
+
+// checkArgValue validates a value from --args-file against the schema
+func checkArgValue(schema *toolSchema, key string, value interface{}) error {
+	prop, err := argSchema(schema, key)
+	if err != nil {
+		return err
+	}
+	if err := checkType(prop, value); err != nil {
+		return fmt.Errorf("argument %q: %v", key, err)
+	}
+	if err := checkEnum(prop, value); err != nil {
+		return fmt.Errorf("argument %q: %v", key, err)
+	}
+	return nil
+}
+
+// checkType reports whether a decoded JSON value matches the schema type
+func checkType(prop *toolSchema, value interface{}) error {
+	want := prop.typeName()
+	if want == "" || value == nil {
+		return nil
+	}
+
+	ok := false
+	switch v := value.(type) {
+	case string:
+		ok = want == "string"
+	case bool:
+		ok = want == "boolean"
+	case float64:
+		ok = want == "number" || (want == "integer" && v == float64(int64(v)))
+	case []interface{}:
+		ok = want == "array"
+		if ok {
+			for _, item := range v {
+				if err := checkType(prop.Items, item); err != nil {
+					return err
+				}
+			}
+		}
+	case map[string]interface{}:
+		ok = want == "object"
+	}
+	if !ok {
+		return fmt.Errorf("expected %s, got %s", want, cellString(value))
+	}
+	return nil
+}
+
+func checkEnum(prop *toolSchema, value interface{}) error {
+	if len(prop.Enum) == 0 {
+		return nil
+	}
+	for _, e := range prop.Enum {
+		if cellString(e) == cellString(value) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%s is not one of: %s", cellString(value), joinValues(prop.Enum))
+}
@@ -161,10 +161,18 @@ class Plugin extends CollectionPlugin {
             }
         }
 
-        // Add local date from JS Date for convenience
+        // Add local date from JS Date for convenience, and exact instants
+        // for calendar feeds. The range end shares the start's UTC offset.
         const jsDate = dt.toDate();
         if (jsDate) {
             result.local = jsDate.toLocaleString();
+            result.start = jsDate.toISOString();
+            if (val.r?.d) {
+                const wall = (d, t) => Date.UTC(+d.slice(0, 4), +d.slice(4, 6) - 1, +d.slice(6, 8),
+                    t ? +t.slice(0, 2) : 0, t ? +t.slice(2, 4) : 0);
+                const offset = wall(val.d, val.t?.t) - jsDate.getTime();
+                result.end = new Date(wall(val.r.d, val.r.t?.t || val.t?.t) - offset).toISOString();
+            }
         }
 
         return result;
@@ -610,7 +618,8 @@ class Plugin extends CollectionPlugin {
                         calendar: { type: 'string', enum: ['Primary', 'Work', 'Personal', 'Family'], optional: true },
                         status: { type: 'string', enum: ['Confirmed', 'Tentative', 'Cancelled'], optional: true },
                         timing: { type: 'string', enum: ['Upcoming', 'Past'], optional: true },
-                        limit: { type: 'number', optional: true }
+                        limit: { type: 'number', optional: true },
+                        offset: { type: 'number', description: 'Results to skip, for paging', optional: true }
                     },
                     handler: async (args, data) => this.toolFind(args, data)
                 },
@@ -689,16 +698,19 @@ class Plugin extends CollectionPlugin {
         });
 
         const limit = args.limit || 20;
-        results = results.slice(0, limit);
+        const offset = args.offset || 0;
+        results = results.slice(offset, offset + limit);
 
         return results.map(r => ({
             guid: r.guid,
+            external_id: r.text('external_id'),
             title: r.getName(),
             when: this.formatDateTime(r),
             calendar: this.idToLabel(r.prop('calendar')?.choice(), 'calendar'),
             status: this.idToLabel(r.prop('status')?.choice(), 'status'),
             timing: this.idToLabel(r.prop('timing')?.choice(), 'timing'),
-            location: r.text('location')
+            location: r.text('location'),
+            meet_link: r.text('meet_link')
         }));
     }
 
@@ -791,10 +803,13 @@ class Plugin extends CollectionPlugin {
             count: results.length,
             events: results.map(r => ({
                 guid: r.guid,
+                external_id: r.text('external_id'),
                 title: r.getName(),
                 when: this.formatDateTime(r),
                 calendar: this.idToLabel(r.prop('calendar')?.choice(), 'calendar'),
+                status: this.idToLabel(r.prop('status')?.choice(), 'status'),
                 location: r.text('location'),
+                meet_link: r.text('meet_link'),
                 prep: r.prop('prep')?.choice() === 'yes'
             }))
         };
@@ -820,9 +835,11 @@ class Plugin extends CollectionPlugin {
 
         return results.map(r => ({
             guid: r.guid,
+            external_id: r.text('external_id'),
             title: r.getName(),
             when: this.formatDateTime(r),
             calendar: this.idToLabel(r.prop('calendar')?.choice(), 'calendar'),
+            location: r.text('location'),
             outcome: this.idToLabel(r.prop('outcome')?.choice(), 'outcome')
         }));
     }
@@ -69,7 +69,8 @@ class Plugin extends CollectionPlugin {
                         source: { type: 'string', enum: ['Readwise', 'Kindle', 'Web', 'Manual'], optional: true },
                         author: { type: 'string', description: 'Author name', optional: true },
                         source_title: { type: 'string', description: 'Book or article title', optional: true },
-                        limit: { type: 'number', optional: true }
+                        limit: { type: 'number', optional: true },
+                        offset: { type: 'number', description: 'Results to skip, for paging', optional: true }
                     },
                     handler: async (args, data) => this.toolFind(args, data)
                 },
@@ -87,7 +88,8 @@ class Plugin extends CollectionPlugin {
                     description: 'Get recent captures. Returns GUIDs - use [[GUID]] to link.',
                     parameters: {
                         limit: { type: 'number', optional: true },
-                        source: { type: 'string', enum: ['Readwise', 'Kindle', 'Web', 'Manual'], optional: true }
+                        source: { type: 'string', enum: ['Readwise', 'Kindle', 'Web', 'Manual'], optional: true },
+                        tag: { type: 'string', description: 'Only captures with this tag', optional: true }
                     },
                     handler: async (args, data) => this.toolRecent(args, data)
                 },
@@ -134,7 +136,8 @@ class Plugin extends CollectionPlugin {
         }
 
         const limit = args.limit || 20;
-        results = results.slice(0, limit);
+        const offset = args.offset || 0;
+        results = results.slice(offset, offset + limit);
 
         return results.map(r => ({
             guid: r.guid,
@@ -183,6 +186,10 @@ class Plugin extends CollectionPlugin {
         if (args.source) {
             records = records.filter(r => this.sourceMatches(r, args.source));
         }
+        if (args.tag) {
+            const tag = args.tag.replace(/^#/, '').toLowerCase();
+            records = records.filter(r => this.tagList(r).includes(tag));
+        }
 
         // Sort by captured_at descending
         records.sort((a, b) => {
@@ -200,10 +207,21 @@ class Plugin extends CollectionPlugin {
             content: r.text('content')?.substring(0, 200),
             source: this.idToLabel(r.prop('source')?.choice()),
             source_title: r.text('source_title'),
+            source_author: r.text('source_author'),
+            source_url: r.text('source_url'),
+            tags: this.tagList(r),
             captured_at: r.prop('captured_at')?.date()?.toISOString()
         }));
     }
 
+    // Tags are free text: "#reading, ideas" -> ['reading', 'ideas']
+    tagList(record) {
+        return (record.text('tags') || '')
+            .split(/[,\s]+/)
+            .map(t => t.replace(/^#/, '').toLowerCase())
+            .filter(Boolean);
+    }
+
     async toolByBook(args, data) {
         if (!args.title) return { error: 'Title required' };
 
@@ -95,7 +95,9 @@ class Plugin extends CollectionPlugin {
                         type: { type: 'string', enum: ['Issue', 'PR', 'Task', 'Bug', 'Feature'], optional: true },
                         repo: { type: 'string', description: 'Repository name (e.g. owner/repo)', optional: true },
                         assignee: { type: 'string', optional: true },
-                        limit: { type: 'number', optional: true }
+                        sort: { type: 'string', enum: ['updated'], description: 'Most recently updated first', optional: true },
+                        limit: { type: 'number', optional: true },
+                        offset: { type: 'number', description: 'Results to skip, for paging', optional: true }
                     },
                     handler: async (args, data) => this.toolFind(args, data)
                 },
@@ -165,8 +167,14 @@ class Plugin extends CollectionPlugin {
             results = results.filter(r => r.text('assignee')?.toLowerCase().includes(assigneeLower));
         }
 
+        if (args.sort === 'updated') {
+            const updated = r => r.prop('updated_at')?.date() || new Date(0);
+            results = [...results].sort((a, b) => updated(b) - updated(a));
+        }
+
         const limit = args.limit || 20;
-        results = results.slice(0, limit);
+        const offset = args.offset || 0;
+        results = results.slice(offset, offset + limit);
 
         return results.map(r => ({
             guid: r.guid,
@@ -175,7 +183,9 @@ class Plugin extends CollectionPlugin {
             type: this.idToLabel(r.prop('type')?.choice(), 'type'),
             repo: r.text('repo'),
             number: r.prop('number')?.number(),
-            assignee: r.text('assignee')
+            assignee: r.text('assignee'),
+            url: r.text('url'),
+            updated_at: r.prop('updated_at')?.date()?.toISOString()
         }));
     }
 
@@ -76,7 +76,8 @@ class Plugin extends CollectionPlugin {
                     parameters: {
                         organization: { type: 'string', description: 'Company name', optional: true },
                         keep_in_touch: { type: 'string', enum: ['Weekly', 'Monthly', 'Quarterly', 'Yearly', 'Never'], optional: true },
-                        limit: { type: 'number', optional: true }
+                        limit: { type: 'number', optional: true },
+                        offset: { type: 'number', description: 'Results to skip, for paging', optional: true }
                     },
                     handler: async (args, data) => this.toolFind(args, data)
                 },
@@ -148,7 +149,8 @@ class Plugin extends CollectionPlugin {
         results.sort((a, b) => (a.getName() || '').localeCompare(b.getName() || ''));
 
         const limit = args.limit || 20;
-        results = results.slice(0, limit);
+        const offset = args.offset || 0;
+        results = results.slice(offset, offset + limit);
 
         return results.map(r => ({
             guid: r.guid,
@@ -0,0 +1,552 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	agentMaxSteps      = 8
+	agentMaxToolResult = 16000 // characters of tool output fed back to the model
+
+	agentSystemPrompt = `You are an assistant with access to the user's Thymer workspace through tools. ` +
+		`Use them to look up notes, issues, people, calendar events and captures before answering. ` +
+		`Answer concisely and cite note titles when you use them.`
+)
+
+// AgentToolEvent describes a tool the agent ran. It is streamed as a
+// "thymer_tool" SSE event and listed in non-streaming responses.
+type AgentToolEvent struct {
+	Name      string                 `json:"name"`
+	Arguments map[string]interface{} `json:"arguments"`
+	Result    json.RawMessage        `json:"result,omitempty"`
+	Error     string                 `json:"error,omitempty"`
+}
+
+// Agent runs the tool-calling loop against the local LLM, executing Thymer
+// tools through the bridge, so plain OpenAI clients get answers grounded in
+// the workspace without implementing tool calling themselves.
+type Agent struct {
+	bridge *Bridge
+	llm    *LLMManager
+	client *http.Client
+}
+
+func NewAgent(bridge *Bridge, llm *LLMManager) *Agent {
+	return &Agent{
+		bridge: bridge,
+		llm:    llm,
+		client: &http.Client{Timeout: 5 * time.Minute},
+	}
+}
+
+// agentOutput receives the agent's progress. Text arrives as it streams
+// from the model; the final turn is passed to Done.
+type agentOutput interface {
+	Text(delta string)
+	Tool(event AgentToolEvent)
+	Done(turn *agentTurn)
+}
+
+// agentTurn is one upstream completion with its tool calls reassembled
+type agentTurn struct {
+	ID           string
+	Model        string
+	Content      string
+	ToolCalls    []agentToolCall
+	FinishReason string
+	Usage        map[string]interface{}
+}
+
+type agentToolCall struct {
+	ID       string `json:"id"`
+	Type     string `json:"type"`
+	Function struct {
+		Name      string `json:"name"`
+		Arguments string `json:"arguments"`
+	} `json:"function"`
+}
+
+// Run drives the conversation in req until the model answers without
+// calling a Thymer tool. Calls to tools the client supplied itself end the
+// loop and are returned to the client, as a plain proxy would; Thymer calls
+// made in the same turn are run first, since the client can't run them.
+func (ag *Agent) Run(ctx context.Context, req map[string]interface{}, out agentOutput) error {
+	messages, _ := req["messages"].([]interface{})
+	if len(messages) == 0 {
+		return fmt.Errorf("messages required")
+	}
+	if !hasSystemMessage(messages) {
+		messages = append([]interface{}{
+			map[string]interface{}{"role": "system", "content": agentSystemPrompt},
+		}, messages...)
+	}
+
+	thymerTools := make(map[string]bool)
+	var tools []interface{}
+	for _, tool := range ag.bridge.GetTools() {
+		params := tool.Parameters
+		if params == nil {
+			params = map[string]interface{}{"type": "object", "properties": map[string]interface{}{}}
+		}
+		tools = append(tools, map[string]interface{}{
+			"type": "function",
+			"function": map[string]interface{}{
+				"name":        tool.Name,
+				"description": tool.Description,
+				"parameters":  params,
+			},
+		})
+		thymerTools[tool.Name] = true
+	}
+	if clientTools, ok := req["tools"].([]interface{}); ok {
+		tools = append(tools, clientTools...)
+	}
+
+	for step := 0; step < agentMaxSteps; step++ {
+		body := make(map[string]interface{}, len(req)+2)
+		for k, v := range req {
+			body[k] = v
+		}
+		body["messages"] = messages
+		body["stream"] = true
+		delete(body, "stream_options")
+		if len(tools) > 0 {
+			body["tools"] = tools
+		}
+
+		turn, err := ag.complete(ctx, body, out.Text)
+		if err != nil {
+			return err
+		}
+
+		if len(turn.ToolCalls) == 0 {
+			out.Done(turn)
+			return nil
+		}
+		var thymerCalls, clientCalls []agentToolCall
+		for _, call := range turn.ToolCalls {
+			if thymerTools[call.Function.Name] {
+				thymerCalls = append(thymerCalls, call)
+			} else {
+				clientCalls = append(clientCalls, call)
+			}
+		}
+
+		results := ag.runTools(thymerCalls, out)
+		if len(clientCalls) > 0 {
+			// The client only sees its own calls; the Thymer results went
+			// out as tool events
+			turn.ToolCalls = clientCalls
+			out.Done(turn)
+			return nil
+		}
+
+		messages = append(messages, map[string]interface{}{
+			"role":       "assistant",
+			"content":    turn.Content,
+			"tool_calls": turn.ToolCalls,
+		})
+		messages = append(messages, results...)
+	}
+
+	return fmt.Errorf("no answer after %d tool-calling steps", agentMaxSteps)
+}
+
+// runTools runs Thymer tool calls and returns their results as tool
+// messages
+func (ag *Agent) runTools(calls []agentToolCall, out agentOutput) []interface{} {
+	var messages []interface{}
+	for _, call := range calls {
+		event := ag.executeTool(call)
+		out.Tool(event)
+
+		content := string(event.Result)
+		if event.Error != "" {
+			errJSON, _ := json.Marshal(map[string]string{"error": event.Error})
+			content = string(errJSON)
+		}
+		if len(content) > agentMaxToolResult {
+			content = content[:agentMaxToolResult] + "... (truncated)"
+		}
+		messages = append(messages, map[string]interface{}{
+			"role":         "tool",
+			"tool_call_id": call.ID,
+			"content":      content,
+		})
+	}
+	return messages
+}
+
+func (ag *Agent) executeTool(call agentToolCall) AgentToolEvent {
+	event := AgentToolEvent{Name: call.Function.Name, Arguments: map[string]interface{}{}}
+
+	if args := strings.TrimSpace(call.Function.Arguments); args != "" {
+		if err := json.Unmarshal([]byte(args), &event.Arguments); err != nil {
+			event.Error = "invalid arguments: " + err.Error()
+			return event
+		}
+	}
+
+	log.Printf("[Agent] Calling %s", event.Name)
+	result, err := ag.bridge.ExecuteTool(event.Name, event.Arguments)
+	if err != nil {
+		event.Error = err.Error()
+		return event
+	}
+	event.Result = result
+	return event
+}
+
+// complete sends one streaming request upstream, passing content deltas to
+// onText and reassembling tool calls from their fragments
+func (ag *Agent) complete(ctx context.Context, body map[string]interface{}, onText func(string)) (*agentTurn, error) {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", ag.llm.Endpoint()+"/v1/chat/completions", bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Accept", "text/event-stream")
+
+	resp, err := ag.client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("LLM endpoint unavailable: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return nil, fmt.Errorf("LLM returned %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+
+	// Some servers ignore stream=true when tools are present
+	if !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream") {
+		return readCompletion(resp.Body, onText)
+	}
+	return readCompletionStream(resp.Body, onText)
+}
+
+func readCompletion(r io.Reader, onText func(string)) (*agentTurn, error) {
+	var completion struct {
+		ID      string `json:"id"`
+		Model   string `json:"model"`
+		Choices []struct {
+			Message struct {
+				Content   string          `json:"content"`
+				ToolCalls []agentToolCall `json:"tool_calls"`
+			} `json:"message"`
+			FinishReason string `json:"finish_reason"`
+		} `json:"choices"`
+		Usage map[string]interface{} `json:"usage"`
+	}
+	if err := json.NewDecoder(r).Decode(&completion); err != nil {
+		return nil, fmt.Errorf("invalid LLM response: %w", err)
+	}
+	if len(completion.Choices) == 0 {
+		return nil, fmt.Errorf("LLM returned no choices")
+	}
+
+	choice := completion.Choices[0]
+	if choice.Message.Content != "" {
+		onText(choice.Message.Content)
+	}
+	return &agentTurn{
+		ID:           completion.ID,
+		Model:        completion.Model,
+		Content:      choice.Message.Content,
+		ToolCalls:    choice.Message.ToolCalls,
+		FinishReason: choice.FinishReason,
+		Usage:        completion.Usage,
+	}, nil
+}
+
+func readCompletionStream(r io.Reader, onText func(string)) (*agentTurn, error) {
+	turn := &agentTurn{}
+	var content strings.Builder
+	calls := make(map[int]*agentToolCall)
+	var order []int
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "data:") {
+			continue
+		}
+		payload := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+		if payload == "[DONE]" {
+			break
+		}
+
+		var chunk struct {
+			ID      string `json:"id"`
+			Model   string `json:"model"`
+			Choices []struct {
+				Delta struct {
+					Content   string `json:"content"`
+					ToolCalls []struct {
+						Index    int    `json:"index"`
+						ID       string `json:"id"`
+						Type     string `json:"type"`
+						Function struct {
+							Name      string `json:"name"`
+							Arguments string `json:"arguments"`
+						} `json:"function"`
+					} `json:"tool_calls"`
+				} `json:"delta"`
+				FinishReason string `json:"finish_reason"`
+			} `json:"choices"`
+			Usage map[string]interface{} `json:"usage"`
+			Error map[string]interface{} `json:"error"`
+		}
+		if err := json.Unmarshal([]byte(payload), &chunk); err != nil {
+			continue
+		}
+		if chunk.Error != nil {
+			return nil, fmt.Errorf("LLM error: %v", chunk.Error["message"])
+		}
+
+		if chunk.ID != "" {
+			turn.ID = chunk.ID
+		}
+		if chunk.Model != "" {
+			turn.Model = chunk.Model
+		}
+		if chunk.Usage != nil {
+			turn.Usage = chunk.Usage
+		}
+		if len(chunk.Choices) == 0 {
+			continue
+		}
+
+		choice := chunk.Choices[0]
+		if choice.Delta.Content != "" {
+			content.WriteString(choice.Delta.Content)
+			onText(choice.Delta.Content)
+		}
+		for _, fragment := range choice.Delta.ToolCalls {
+			call, ok := calls[fragment.Index]
+			if !ok {
+				call = &agentToolCall{Type: "function"}
+				calls[fragment.Index] = call
+				order = append(order, fragment.Index)
+			}
+			if fragment.ID != "" {
+				call.ID = fragment.ID
+			}
+			call.Function.Name += fragment.Function.Name
+			call.Function.Arguments += fragment.Function.Arguments
+		}
+		if choice.FinishReason != "" {
+			turn.FinishReason = choice.FinishReason
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	turn.Content = content.String()
+	for i, index := range order {
+		call := calls[index]
+		if call.ID == "" {
+			call.ID = fmt.Sprintf("call_%d", i)
+		}
+		turn.ToolCalls = append(turn.ToolCalls, *call)
+	}
+	return turn, nil
+}
+
+func hasSystemMessage(messages []interface{}) bool {
+	for _, m := range messages {
+		if msg, ok := m.(map[string]interface{}); ok && msg["role"] == "system" {
+			return true
+		}
+	}
+	return false
+}
+
+// agentJSON collects the agent's answer into a chat.completion response
+type agentJSON struct {
+	model string
+	tools []AgentToolEvent
+	turn  *agentTurn
+}
+
+func (o *agentJSON) Text(string)               {}
+func (o *agentJSON) Tool(event AgentToolEvent) { o.tools = append(o.tools, event) }
+func (o *agentJSON) Done(turn *agentTurn)      { o.turn = turn }
+
+func (o *agentJSON) response() map[string]interface{} {
+	message := map[string]interface{}{
+		"role":    "assistant",
+		"content": o.turn.Content,
+	}
+	if len(o.turn.ToolCalls) > 0 {
+		message["tool_calls"] = o.turn.ToolCalls
+	}
+
+	id, model := o.turn.ID, o.turn.Model
+	if id == "" {
+		id = fmt.Sprintf("chatcmpl-thymer-%d", time.Now().UnixNano())
+	}
+	if model == "" {
+		model = o.model
+	}
+	finish := o.turn.FinishReason
+	if finish == "" {
+		finish = "stop"
+	}
+	tools := o.tools
+	if tools == nil {
+		tools = []AgentToolEvent{}
+	}
+
+	resp := map[string]interface{}{
+		"id":      id,
+		"object":  "chat.completion",
+		"created": time.Now().Unix(),
+		"model":   model,
+		"choices": []interface{}{
+			map[string]interface{}{
+				"index":         0,
+				"message":       message,
+				"finish_reason": finish,
+			},
+		},
+		"thymer_tool_calls": tools,
+	}
+	if o.turn.Usage != nil {
+		resp["usage"] = o.turn.Usage
+	}
+	return resp
+}
+
+// agentStream writes the agent's answer as chat.completion.chunk events.
+// Tool activity goes out as named "thymer_tool" events, which OpenAI client
+// libraries skip.
+type agentStream struct {
+	w       http.ResponseWriter
+	flusher http.Flusher
+	id      string
+	model   string
+	created int64
+	started bool
+}
+
+func newAgentStream(w http.ResponseWriter, model string) *agentStream {
+	flusher, _ := w.(http.Flusher)
+	return &agentStream{
+		w:       w,
+		flusher: flusher,
+		id:      fmt.Sprintf("chatcmpl-thymer-%d", time.Now().UnixNano()),
+		model:   model,
+		created: time.Now().Unix(),
+	}
+}
+
+func (s *agentStream) start() {
+	if s.started {
+		return
+	}
+	s.started = true
+	s.w.Header().Set("Content-Type", "text/event-stream")
+	s.w.Header().Set("Cache-Control", "no-cache")
+	s.w.WriteHeader(http.StatusOK)
+}
+
+func (s *agentStream) event(name string, data interface{}) {
+	s.start()
+	payload, _ := json.Marshal(data)
+	if name != "" {
+		fmt.Fprintf(s.w, "event: %s\n", name)
+	}
+	fmt.Fprintf(s.w, "data: %s\n\n", payload)
+	if s.flusher != nil {
+		s.flusher.Flush()
+	}
+}
+
+func (s *agentStream) chunk(delta map[string]interface{}, finishReason interface{}) {
+	s.event("", map[string]interface{}{
+		"id":      s.id,
+		"object":  "chat.completion.chunk",
+		"created": s.created,
+		"model":   s.model,
+		"choices": []interface{}{
+			map[string]interface{}{
+				"index":         0,
+				"delta":         delta,
+				"finish_reason": finishReason,
+			},
+		},
+	})
+}
+
+func (s *agentStream) Text(delta string) {
+	s.chunk(map[string]interface{}{"role": "assistant", "content": delta}, nil)
+}
+
+func (s *agentStream) Tool(event AgentToolEvent) {
+	s.event("thymer_tool", event)
+}
+
+func (s *agentStream) Done(turn *agentTurn) {
+	if turn.Model != "" {
+		s.model = turn.Model
+	}
+	finish := turn.FinishReason
+	if finish == "" {
+		finish = "stop"
+	}
+	if len(turn.ToolCalls) > 0 {
+		calls := make([]interface{}, len(turn.ToolCalls))
+		for i, call := range turn.ToolCalls {
+			calls[i] = map[string]interface{}{
+				"index":    i,
+				"id":       call.ID,
+				"type":     call.Type,
+				"function": call.Function,
+			}
+		}
+		s.chunk(map[string]interface{}{"role": "assistant", "tool_calls": calls}, nil)
+		finish = "tool_calls"
+	}
+	s.chunk(map[string]interface{}{}, finish)
+	s.finish()
+}
+
+// fail reports an error, in-band if the stream has already started
+func (s *agentStream) fail(err error) {
+	if !s.started {
+		s.w.Header().Set("Content-Type", "application/json")
+		s.w.WriteHeader(http.StatusBadGateway)
+		json.NewEncoder(s.w).Encode(map[string]interface{}{
+			"error": map[string]string{"message": err.Error()},
+		})
+		return
+	}
+	s.event("", map[string]interface{}{
+		"error": map[string]string{"message": err.Error()},
+	})
+	s.finish()
+}
+
+func (s *agentStream) finish() {
+	fmt.Fprint(s.w, "data: [DONE]\n\n")
+	if s.flusher != nil {
+		s.flusher.Flush()
+	}
+}
@@ -0,0 +1,389 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// stubModel answers chat completions like an OpenAI-compatible server. The
+// user's message picks the tool calls of the first turn; once tool results
+// are in the conversation it answers with text. With sse set it streams the
+// answer as chunks, splitting text and tool call arguments across them.
+type stubModel struct {
+	mu       sync.Mutex
+	requests []map[string]interface{}
+	sse      bool
+}
+
+func (m *stubModel) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req map[string]interface{}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	m.mu.Lock()
+	m.requests = append(m.requests, req)
+	m.mu.Unlock()
+
+	messages := req["messages"].([]interface{})
+	last := messages[len(messages)-1].(map[string]interface{})
+	message := map[string]interface{}{"role": "assistant"}
+	finish := "tool_calls"
+	call := func(id, name string) map[string]interface{} {
+		return map[string]interface{}{
+			"id":   id,
+			"type": "function",
+			"function": map[string]interface{}{
+				"name":      name,
+				"arguments": `{"query":"lizard"}`,
+			},
+		}
+	}
+	switch {
+	case last["role"] == "tool":
+		message["content"] = "Found it: " + last["content"].(string)
+		finish = "stop"
+	case last["content"] == "thymer":
+		message["tool_calls"] = []interface{}{call("call_1", "search_workspace")}
+	case last["content"] == "client":
+		message["tool_calls"] = []interface{}{call("call_1", "get_weather")}
+	case last["content"] == "mixed":
+		message["tool_calls"] = []interface{}{call("call_1", "search_workspace"), call("call_2", "get_weather")}
+	default:
+		message["content"] = "Hello"
+		finish = "stop"
+	}
+
+	if m.sse {
+		m.stream(w, message, finish)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"id":      fmt.Sprintf("chatcmpl-%d", len(m.requests)),
+		"model":   "stub",
+		"choices": []interface{}{map[string]interface{}{"message": message, "finish_reason": finish}},
+	})
+}
+
+func (m *stubModel) stream(w http.ResponseWriter, message map[string]interface{}, finish string) {
+	w.Header().Set("Content-Type", "text/event-stream")
+	send := func(delta map[string]interface{}, finish interface{}) {
+		chunk, _ := json.Marshal(map[string]interface{}{
+			"id":      "chatcmpl-stream",
+			"model":   "stub",
+			"choices": []interface{}{map[string]interface{}{"index": 0, "delta": delta, "finish_reason": finish}},
+		})
+		fmt.Fprintf(w, "data: %s\n\n", chunk)
+	}
+
+	if content, ok := message["content"].(string); ok {
+		half := len(content) / 2
+		send(map[string]interface{}{"role": "assistant", "content": content[:half]}, nil)
+		send(map[string]interface{}{"content": content[half:]}, nil)
+	}
+	calls, _ := message["tool_calls"].([]interface{})
+	for i, c := range calls {
+		call := c.(map[string]interface{})
+		function := call["function"].(map[string]interface{})
+		args := function["arguments"].(string)
+		// The id and name come first, the arguments in two pieces after
+		send(map[string]interface{}{"tool_calls": []interface{}{map[string]interface{}{
+			"index": i, "id": call["id"], "type": "function",
+			"function": map[string]interface{}{"name": function["name"], "arguments": ""},
+		}}}, nil)
+		send(map[string]interface{}{"tool_calls": []interface{}{map[string]interface{}{
+			"index": i, "function": map[string]interface{}{"arguments": args[:5]},
+		}}}, nil)
+		send(map[string]interface{}{"tool_calls": []interface{}{map[string]interface{}{
+			"index": i, "function": map[string]interface{}{"arguments": args[5:]},
+		}}}, nil)
+	}
+	send(map[string]interface{}{}, finish)
+	fmt.Fprint(w, "data: [DONE]\n\n")
+}
+
+func newTestAgent(t *testing.T) (*Agent, *stubModel, *[]string) {
+	model := &stubModel{}
+	srv := httptest.NewServer(model)
+	t.Cleanup(srv.Close)
+
+	var ran []string
+	bridge := NewBridge(0)
+	bridge.AddLocalTool(Tool{Name: "search_workspace", Description: "Search"}, func(args map[string]interface{}) (interface{}, error) {
+		ran = append(ran, args["query"].(string))
+		return map[string]interface{}{"results": []string{"Lizard notes"}}, nil
+	})
+	llm := NewLLMManager(&Config{LLM: &LLMConfig{Endpoint: srv.URL}})
+	return NewAgent(bridge, llm), model, &ran
+}
+
+func runAgent(t *testing.T, ag *Agent, content string) *agentJSON {
+	t.Helper()
+	out := &agentJSON{}
+	req := map[string]interface{}{
+		"model":    "stub",
+		"messages": []interface{}{map[string]interface{}{"role": "user", "content": content}},
+		"tools": []interface{}{map[string]interface{}{
+			"type":     "function",
+			"function": map[string]interface{}{"name": "get_weather", "parameters": map[string]interface{}{"type": "object"}},
+		}},
+	}
+	if err := ag.Run(context.Background(), req, out); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	return out
+}
+
+func TestAgentThymerTurn(t *testing.T) {
+	ag, model, ran := newTestAgent(t)
+	out := runAgent(t, ag, "thymer")
+
+	if len(*ran) != 1 || (*ran)[0] != "lizard" {
+		t.Errorf("search_workspace ran with %v", *ran)
+	}
+	if len(model.requests) != 2 {
+		t.Fatalf("%d model requests, want 2", len(model.requests))
+	}
+	if !strings.Contains(out.turn.Content, "Lizard notes") || len(out.turn.ToolCalls) != 0 {
+		t.Errorf("answer = %+v", out.turn)
+	}
+	if len(out.tools) != 1 || out.tools[0].Name != "search_workspace" {
+		t.Errorf("tool events = %+v", out.tools)
+	}
+
+	// The model gets both Thymer's tools and the client's
+	tools := model.requests[0]["tools"].([]interface{})
+	if len(tools) != 2 {
+		t.Errorf("model offered %d tools, want 2", len(tools))
+	}
+}
+
+func TestAgentClientTurn(t *testing.T) {
+	ag, model, ran := newTestAgent(t)
+	out := runAgent(t, ag, "client")
+
+	if len(*ran) != 0 {
+		t.Errorf("Thymer tools ran: %v", *ran)
+	}
+	if len(model.requests) != 1 {
+		t.Errorf("%d model requests, want 1", len(model.requests))
+	}
+	if len(out.turn.ToolCalls) != 1 || out.turn.ToolCalls[0].Function.Name != "get_weather" {
+		t.Errorf("returned calls = %+v", out.turn.ToolCalls)
+	}
+	if finish := out.response()["choices"].([]interface{})[0].(map[string]interface{})["finish_reason"]; finish != "tool_calls" {
+		t.Errorf("finish_reason = %v", finish)
+	}
+}
+
+func TestAgentMixedTurn(t *testing.T) {
+	ag, model, ran := newTestAgent(t)
+	out := runAgent(t, ag, "mixed")
+
+	// Thymer's call runs here, and only the client's goes back
+	if len(*ran) != 1 {
+		t.Errorf("search_workspace ran %d times, want 1", len(*ran))
+	}
+	if len(out.tools) != 1 || out.tools[0].Name != "search_workspace" || len(out.tools[0].Result) == 0 {
+		t.Errorf("tool events = %+v", out.tools)
+	}
+	if len(out.turn.ToolCalls) != 1 || out.turn.ToolCalls[0].ID != "call_2" {
+		t.Errorf("returned calls = %+v", out.turn.ToolCalls)
+	}
+	if len(model.requests) != 1 {
+		t.Errorf("%d model requests, want 1", len(model.requests))
+	}
+}
+
+// sseEvent is one event read back from the agent's stream
+type sseEvent struct {
+	name string
+	data string
+}
+
+func readSSE(t *testing.T, body string) []sseEvent {
+	t.Helper()
+	var events []sseEvent
+	var name string
+	for _, line := range strings.Split(body, "\n") {
+		switch {
+		case strings.HasPrefix(line, "event: "):
+			name = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			events = append(events, sseEvent{name: name, data: strings.TrimPrefix(line, "data: ")})
+		case line == "":
+			name = ""
+		}
+	}
+	if len(events) == 0 || events[len(events)-1].data != "[DONE]" {
+		t.Fatalf("stream doesn't end with [DONE]:\n%s", body)
+	}
+	return events[:len(events)-1]
+}
+
+// streamAgent runs a streaming request the way handleAgentChat does and
+// returns the events written to the client
+func streamAgent(t *testing.T, ag *Agent, content string) []sseEvent {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	stream := newAgentStream(rec, "stub")
+	req := map[string]interface{}{
+		"model":    "stub",
+		"stream":   true,
+		"messages": []interface{}{map[string]interface{}{"role": "user", "content": content}},
+		"tools": []interface{}{map[string]interface{}{
+			"type":     "function",
+			"function": map[string]interface{}{"name": "get_weather", "parameters": map[string]interface{}{"type": "object"}},
+		}},
+	}
+	if err := ag.Run(context.Background(), req, stream); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	return readSSE(t, rec.Body.String())
+}
+
+// streamedChunk is the part of a chat.completion.chunk the tests look at
+type streamedChunk struct {
+	Choices []struct {
+		Delta struct {
+			Content   string `json:"content"`
+			ToolCalls []struct {
+				ID       string `json:"id"`
+				Function struct {
+					Name      string `json:"name"`
+					Arguments string `json:"arguments"`
+				} `json:"function"`
+			} `json:"tool_calls"`
+		} `json:"delta"`
+		FinishReason *string `json:"finish_reason"`
+	} `json:"choices"`
+}
+
+func TestReadCompletionStream(t *testing.T) {
+	model := &stubModel{sse: true}
+	srv := httptest.NewServer(model)
+	defer srv.Close()
+
+	resp, err := http.Post(srv.URL, "application/json", strings.NewReader(`{"messages":[{"role":"user","content":"mixed"}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	turn, err := readCompletionStream(resp.Body, func(string) {})
+	if err != nil {
+		t.Fatalf("readCompletionStream: %v", err)
+	}
+	if turn.ID != "chatcmpl-stream" || turn.Model != "stub" || turn.FinishReason != "tool_calls" {
+		t.Errorf("turn = %+v", turn)
+	}
+	if len(turn.ToolCalls) != 2 {
+		t.Fatalf("tool calls = %+v", turn.ToolCalls)
+	}
+	for i, want := range []struct{ id, name string }{{"call_1", "search_workspace"}, {"call_2", "get_weather"}} {
+		call := turn.ToolCalls[i]
+		if call.ID != want.id || call.Function.Name != want.name || call.Function.Arguments != `{"query":"lizard"}` {
+			t.Errorf("call %d = %+v", i, call)
+		}
+	}
+}
+
+func TestAgentStreamThymerTurn(t *testing.T) {
+	ag, model, ran := newTestAgent(t)
+	model.sse = true
+	events := streamAgent(t, ag, "thymer")
+
+	if len(*ran) != 1 || (*ran)[0] != "lizard" {
+		t.Errorf("search_workspace ran with %v", *ran)
+	}
+
+	var text strings.Builder
+	var tools []AgentToolEvent
+	var finish string
+	for _, e := range events {
+		if e.name == "thymer_tool" {
+			var tool AgentToolEvent
+			if err := json.Unmarshal([]byte(e.data), &tool); err != nil {
+				t.Fatalf("thymer_tool event: %v", err)
+			}
+			tools = append(tools, tool)
+			continue
+		}
+		var chunk streamedChunk
+		if err := json.Unmarshal([]byte(e.data), &chunk); err != nil {
+			t.Fatalf("chunk %s: %v", e.data, err)
+		}
+		for _, c := range chunk.Choices {
+			text.WriteString(c.Delta.Content)
+			if len(c.Delta.ToolCalls) > 0 {
+				t.Errorf("Thymer tool call passed to the client: %s", e.data)
+			}
+			if c.FinishReason != nil {
+				finish = *c.FinishReason
+			}
+		}
+	}
+
+	if len(tools) != 1 || tools[0].Name != "search_workspace" || tools[0].Arguments["query"] != "lizard" {
+		t.Errorf("tool events = %+v", tools)
+	}
+	if !strings.HasPrefix(text.String(), "Found it: ") || !strings.Contains(text.String(), "Lizard notes") {
+		t.Errorf("streamed text = %q", text.String())
+	}
+	if finish != "stop" {
+		t.Errorf("finish_reason = %q", finish)
+	}
+}
+
+func TestAgentStreamMixedTurn(t *testing.T) {
+	ag, model, ran := newTestAgent(t)
+	model.sse = true
+	events := streamAgent(t, ag, "mixed")
+
+	if len(*ran) != 1 {
+		t.Errorf("search_workspace ran %d times, want 1", len(*ran))
+	}
+
+	var toolEvents int
+	var calls []string
+	var finish string
+	for _, e := range events {
+		if e.name == "thymer_tool" {
+			toolEvents++
+			continue
+		}
+		var chunk streamedChunk
+		if err := json.Unmarshal([]byte(e.data), &chunk); err != nil {
+			t.Fatalf("chunk %s: %v", e.data, err)
+		}
+		for _, c := range chunk.Choices {
+			for _, call := range c.Delta.ToolCalls {
+				calls = append(calls, call.ID+" "+call.Function.Name+" "+call.Function.Arguments)
+			}
+			if c.FinishReason != nil {
+				finish = *c.FinishReason
+			}
+		}
+	}
+
+	if toolEvents != 1 {
+		t.Errorf("%d thymer_tool events, want 1", toolEvents)
+	}
+	// The client gets its own call whole, with the arguments reassembled
+	if len(calls) != 1 || calls[0] != `call_2 get_weather {"query":"lizard"}` {
+		t.Errorf("client tool calls = %q", calls)
+	}
+	if finish != "tool_calls" {
+		t.Errorf("finish_reason = %q", finish)
+	}
+}
@@ -1,9 +1,18 @@
 package main
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
+	"log"
+	"mime"
 	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 )
 
 // handleStatus returns connection status
@@ -13,134 +22,786 @@ func (a *App) handleStatus(w http.ResponseWriter, r *http.Request) {
 		"tools":      a.ToolCount(),
 		"workspace":  a.config.Workspace,
 		"thymer_url": a.config.ThymerURL(),
+		"sampling":   a.SamplingAvailable(),
 	}
 
 	if a.bridge != nil {
 		status["plugins"] = a.bridge.GetPlugins()
 	}
 
+	if a.llm != nil {
+		status["llm"] = a.llm.Status()
+	}
+
+	if a.scheduler != nil {
+		status["schedule"] = a.scheduler.Status()
+	}
+
+	if a.mirror != nil {
+		status["mirror"] = a.mirror.Status()
+	}
+
+	if a.embedder != nil {
+		status["embeddings"] = a.embedder.Status()
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(status)
 }
 
-// handleQuery proxies collection queries to SyncHub
+// handleQuery proxies collection queries to SyncHub, or answers them from
+// the mirror when offline
 func (a *App) handleQuery(w http.ResponseWriter, r *http.Request) {
-	if !a.IsConnected() {
-		http.Error(w, `{"error":"SyncHub not connected"}`, http.StatusServiceUnavailable)
+	params := r.URL.Query()
+
+	// Disconnected, or asked to, answer from the mirror
+	offline := params.Get("offline") == "1" || params.Get("offline") == "true" || !a.IsConnected()
+	if offline && a.mirror == nil {
+		writeJSONError(w, "SyncHub not connected", http.StatusServiceUnavailable)
 		return
 	}
 
-	collection := r.URL.Query().Get("collection")
+	q := &Query{Limit: defaultQueryLimit}
+
+	q.Collection = params.Get("collection")
+	if q.Collection == "" {
+		writeJSONError(w, "collection parameter required", http.StatusBadRequest)
+		return
+	}
+
+	var plain []string
+	for key, values := range params {
+		for _, value := range values {
+			switch key {
+			case "collection", "offline":
+			case "where":
+				filter, err := ParseFilter(value)
+				if err != nil {
+					writeJSONError(w, err.Error(), http.StatusBadRequest)
+					return
+				}
+				q.Where = append(q.Where, filter)
+			case "sort":
+				for _, field := range strings.Split(value, ",") {
+					q.Sort = append(q.Sort, ParseSort(field))
+				}
+			case "fields":
+				q.Fields = append(q.Fields, strings.Split(value, ",")...)
+			case "limit", "offset":
+				n, err := strconv.Atoi(value)
+				if err != nil || n < 0 {
+					writeJSONError(w, key+" must be a non-negative number", http.StatusBadRequest)
+					return
+				}
+				if key == "limit" {
+					q.Limit = n
+				} else {
+					q.Offset = n
+				}
+			case "cursor":
+				offset, err := DecodeCursor(value)
+				if err != nil {
+					writeJSONError(w, err.Error(), http.StatusBadRequest)
+					return
+				}
+				q.Offset = offset
+			default:
+				// Plain field=value params, e.g. ?state=open
+				q.Where = append(q.Where, QueryFilter{Field: key, Op: "=", Value: value})
+				plain = append(plain, key)
+			}
+		}
+	}
+
+	var collections json.RawMessage
+	var err error
+	if offline {
+		collections, err = a.mirror.Collections()
+	} else {
+		collections, err = a.bridge.ExecuteTool("list_collections", map[string]interface{}{})
+	}
+	if err != nil {
+		writeJSONError(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	if !offline && a.mirror != nil {
+		logMirrorError("store schemas", a.mirror.PutSchemas(collections))
+	}
+	name, schema, err := collectionSchema(collections, q.Collection)
+	if err != nil {
+		writeJSONError(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	q.Collection = name
+	check := q.fieldCheck(schema)
+	for _, key := range plain {
+		if check(key) != nil {
+			writeJSONError(w, fmt.Sprintf("unknown query parameter %q: not a field of %s, nor one of collection, where, sort, fields, limit, offset, cursor, offline", key, name), http.StatusBadRequest)
+			return
+		}
+	}
+	if err := q.Validate(schema); err != nil {
+		writeJSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var page *QueryPage
+	if !offline {
+		page, err = a.findQuery(q)
+		if err != nil {
+			writeJSONError(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+	}
+
+	var records json.RawMessage
+	switch {
+	case page != nil:
+		// Answered by _find
+	case offline:
+		var snapshotAt time.Time
+		records, snapshotAt, err = a.mirror.Records(name)
+		if err != nil {
+			msg := err.Error()
+			if !a.IsConnected() {
+				msg = "SyncHub not connected and " + msg
+			}
+			writeJSONError(w, msg, http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("X-Stale", "true")
+		if !snapshotAt.IsZero() {
+			w.Header().Set("X-Snapshot-At", snapshotAt.Format(time.RFC3339))
+		}
+	default:
+		// The whole collection, for what the collection's _find tool can't
+		// answer: where and sort on any field, and the total count. It also
+		// keeps the mirror current.
+		records, err = a.bridge.ExecuteTool("get_collection_records", map[string]interface{}{
+			"collection": name,
+		})
+		if err != nil {
+			writeJSONError(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		if a.mirror != nil {
+			logMirrorError("store "+name, a.mirror.PutQueryResult(name, records, time.Now()))
+		}
+	}
+
+	if page == nil {
+		page, err = q.Apply(records)
+		if err != nil {
+			writeJSONError(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+	}
+
+	// The body stays a plain array; pagination and staleness ride in headers
+	w.Header().Set("Content-Type", "application/json")
+	if page.Total >= 0 {
+		w.Header().Set("X-Total-Count", strconv.Itoa(page.Total))
+	}
+	if page.NextCursor != "" {
+		w.Header().Set("X-Next-Cursor", page.NextCursor)
+	}
+	json.NewEncoder(w).Encode(page.Records)
+}
+
+// findQuery answers a query with the collection's _find tool, fetching
+// only the page's records. It returns nil when the collection has no _find
+// tool or the tool can't answer the query, to fall back to the whole
+// collection.
+func (a *App) findQuery(q *Query) (*QueryPage, error) {
+	tool, ok := findTool(a.bridge, q.Collection)
+	if !ok {
+		return nil, nil
+	}
+	args, ok := q.findArgs(tool)
+	if !ok {
+		return nil, nil
+	}
+	found, err := a.bridge.ExecuteTool(tool.Name, args)
+	if err != nil {
+		return nil, err
+	}
+	guids, total, next, ok := q.findPage(found, args["limit"].(int))
+	if !ok {
+		return nil, nil
+	}
+
+	records, err := a.bridge.ExecuteTool("get_collection_records", map[string]interface{}{
+		"collection": q.Collection,
+		"guids":      guids,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if a.mirror != nil {
+		if result, err := decodeRecords(records); err == nil {
+			logMirrorError("store "+q.Collection, a.mirror.PutRecords(q.Collection, result.Records, nil, false, time.Now()))
+		}
+	}
+
+	// The records are the page, filtered already and in _find's order
+	pageQuery := *q
+	pageQuery.Where, pageQuery.Offset, pageQuery.Limit = nil, 0, 0
+	page, err := pageQuery.Apply(records)
+	if err != nil {
+		return nil, err
+	}
+	page.Total, page.NextCursor = total, next
+	return page, nil
+}
+
+// handleSearch searches the local index, by keywords or, with semantic=1
+// or similar_to, by meaning. It works while disconnected.
+func (a *App) handleSearch(w http.ResponseWriter, r *http.Request) {
+	if a.mirror == nil {
+		writeJSONError(w, "local search needs the mirror, which is disabled", http.StatusServiceUnavailable)
+		return
+	}
+
+	params := r.URL.Query()
+	query := params.Get("q")
+	similarTo := params.Get("similar_to")
+	semantic := params.Get("semantic") == "1" || params.Get("semantic") == "true" || similarTo != ""
+	if query == "" && similarTo == "" {
+		writeJSONError(w, "q parameter required", http.StatusBadRequest)
+		return
+	}
+	if semantic && a.embedder == nil {
+		writeJSONError(w, "semantic search needs an embeddings model (set embeddings.model in the config)", http.StatusServiceUnavailable)
+		return
+	}
+	limit := defaultSearchLimit
+	if v := params.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			writeJSONError(w, "limit must be a positive number", http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+
+	var results []SearchResult
+	var err error
+	if semantic {
+		results, err = a.embedder.SemanticSearch(r.Context(), query, similarTo, params.Get("collection"), limit)
+		if errors.Is(err, errNoEmbedding) {
+			writeJSONError(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			writeJSONError(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+	} else {
+		results, err = a.mirror.Search(query, params.Get("collection"), limit)
+		if err != nil {
+			writeJSONError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	response := map[string]interface{}{"results": results}
+	if query != "" {
+		response["query"] = query
+	}
+	if similarTo != "" {
+		response["similar_to"] = similarTo
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// handleExport writes a whole collection as csv, json, ndjson, ics or
+// markdown (a zip of one file per record). Like /api/query, it answers from
+// the mirror while disconnected.
+func (a *App) handleExport(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+	collection := params.Get("collection")
 	if collection == "" {
-		http.Error(w, `{"error":"collection parameter required"}`, http.StatusBadRequest)
+		writeJSONError(w, "collection parameter required", http.StatusBadRequest)
+		return
+	}
+	format := params.Get("format")
+	if format == "" {
+		format = ExportJSON
+	}
+	if !slices.Contains(exportFormats, format) {
+		writeJSONError(w, fmt.Sprintf("unknown format %q (use %s)", format, strings.Join(exportFormats, ", ")), http.StatusBadRequest)
+		return
+	}
+
+	offline := params.Get("offline") == "1" || params.Get("offline") == "true" || !a.IsConnected()
+	if offline && a.mirror == nil {
+		writeJSONError(w, "SyncHub not connected", http.StatusServiceUnavailable)
+		return
+	}
+
+	var collections json.RawMessage
+	var err error
+	if offline {
+		collections, err = a.mirror.Collections()
+	} else {
+		collections, err = a.bridge.ExecuteTool("list_collections", map[string]interface{}{})
+	}
+	if err != nil {
+		writeJSONError(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	if !offline && a.mirror != nil {
+		logMirrorError("store schemas", a.mirror.PutSchemas(collections))
+	}
+	name, _, err := collectionSchema(collections, collection)
+	if err != nil {
+		writeJSONError(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	fields, err := collectionFields(collections, name)
+	if err != nil {
+		writeJSONError(w, err.Error(), http.StatusBadGateway)
 		return
 	}
 
-	// Build tool name based on collection
-	toolName := collection + "_find"
+	var records []mirrorRecord
+	if offline {
+		var result json.RawMessage
+		var snapshotAt time.Time
+		result, snapshotAt, err = a.mirror.Records(name)
+		var page *recordsPage
+		if err == nil {
+			page, err = decodeRecords(result)
+		}
+		if err == nil {
+			records = page.Records
+		}
+		if err != nil {
+			writeJSONError(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("X-Stale", "true")
+		if !snapshotAt.IsZero() {
+			w.Header().Set("X-Snapshot-At", snapshotAt.Format(time.RFC3339))
+		}
+	} else {
+		records, err = ExportRecords(a.bridge, name)
+		if err != nil {
+			writeJSONError(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		if a.mirror != nil {
+			guids := make([]string, len(records))
+			for i, rec := range records {
+				guids[i] = rec.GUID
+			}
+			logMirrorError("store "+name, a.mirror.PutRecords(name, records, guids, true, time.Now()))
+		}
+	}
+
+	// Buffered, so a failure can still be reported as an error
+	var buf bytes.Buffer
+	if err := NewExport(name, fields, records).Write(&buf, format); err != nil {
+		code := http.StatusInternalServerError
+		if errors.Is(err, errNoEventDate) {
+			code = http.StatusBadRequest
+		}
+		writeJSONError(w, err.Error(), code)
+		return
+	}
+
+	contentType, ext := exportContentType(format)
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.%s"`, strings.ToLower(name), ext))
+	w.Header().Set("X-Total-Count", strconv.Itoa(len(records)))
+	w.Write(buf.Bytes())
+}
+
+// maxImportSize bounds an /api/import upload
+const maxImportSize = 32 << 20
+
+// handleImport creates or updates records from a CSV or JSON upload,
+// matched by an external_id column or the key column. map renames columns
+// to fields and dry_run reports what would happen without writing.
+func (a *App) handleImport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		writeJSONError(w, "POST only", http.StatusMethodNotAllowed)
+		return
+	}
+	if !a.IsConnected() {
+		writeJSONError(w, "SyncHub not connected", http.StatusServiceUnavailable)
+		return
+	}
 
-	// Build args from query params
-	args := make(map[string]interface{})
-	for k, v := range r.URL.Query() {
-		if k != "collection" && len(v) > 0 {
-			args[k] = v[0]
+	params := r.URL.Query()
+	collection := params.Get("collection")
+	if collection == "" {
+		writeJSONError(w, "collection parameter required", http.StatusBadRequest)
+		return
+	}
+	mapping, err := ParseImportMap(params.Get("map"))
+	if err != nil {
+		writeJSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	dryRun := params.Get("dry_run") == "1" || params.Get("dry_run") == "true"
+
+	format := params.Get("format")
+	if format == "" {
+		switch mediaType, _, _ := strings.Cut(r.Header.Get("Content-Type"), ";"); strings.TrimSpace(mediaType) {
+		case "text/csv":
+			format = "csv"
+		case "application/json", "application/x-ndjson":
+			format = "json"
 		}
 	}
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxImportSize))
+	if err != nil {
+		writeJSONError(w, "file too large or unreadable: "+err.Error(), http.StatusRequestEntityTooLarge)
+		return
+	}
+	rows, columns, err := ReadImportRows(data, format)
+	if err != nil {
+		writeJSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	collections, err := a.bridge.ExecuteTool("list_collections", map[string]interface{}{})
+	if err != nil {
+		writeJSONError(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	name, _, err := collectionSchema(collections, collection)
+	if err != nil {
+		writeJSONError(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	fields, err := collectionFields(collections, name)
+	if err != nil {
+		writeJSONError(w, err.Error(), http.StatusBadGateway)
+		return
+	}
 
-	result, err := a.bridge.ExecuteTool(toolName, args)
+	result, err := Import(a.bridge, name, fields, rows, columns, mapping, strings.TrimSpace(params.Get("key")), dryRun)
 	if err != nil {
-		http.Error(w, `{"error":"`+err.Error()+`"}`, http.StatusBadGateway)
+		writeJSONError(w, err.Error(), http.StatusBadRequest)
 		return
 	}
+	if !dryRun && result.Created+result.Updated > 0 {
+		log.Printf("[Import] %s: %d created, %d updated, %d failed", name, result.Created, result.Updated, result.Failed)
+		a.kickMirror()
+	}
 
 	w.Header().Set("Content-Type", "application/json")
-	w.Write(result)
+	json.NewEncoder(w).Encode(result)
+}
+
+// writeJSONError writes {"error": msg}, escaping msg properly
+func writeJSONError(w http.ResponseWriter, msg string, code int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(map[string]string{"error": msg})
 }
 
 // handleSync triggers a plugin sync
 func (a *App) handleSync(w http.ResponseWriter, r *http.Request) {
 	if r.Method != "POST" {
-		http.Error(w, `{"error":"POST only"}`, http.StatusMethodNotAllowed)
+		writeJSONError(w, "POST only", http.StatusMethodNotAllowed)
 		return
 	}
 
 	if !a.IsConnected() {
-		http.Error(w, `{"error":"SyncHub not connected"}`, http.StatusServiceUnavailable)
+		writeJSONError(w, "SyncHub not connected", http.StatusServiceUnavailable)
 		return
 	}
 
 	var req struct {
-		Plugin string `json:"plugin"`
-		All    bool   `json:"all"`
+		Plugin  string `json:"plugin"`
+		All     bool   `json:"all"`
+		Trigger string `json:"trigger"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, `{"error":"invalid JSON"}`, http.StatusBadRequest)
+		writeJSONError(w, "invalid JSON", http.StatusBadRequest)
 		return
 	}
 
-	var err error
-	if req.All {
-		err = a.bridge.SyncAll()
-	} else if req.Plugin != "" {
-		err = a.bridge.Sync(req.Plugin)
-	} else {
-		http.Error(w, `{"error":"plugin or all required"}`, http.StatusBadRequest)
+	if !req.All && req.Plugin == "" {
+		writeJSONError(w, "plugin or all required", http.StatusBadRequest)
 		return
 	}
 
+	plugin := req.Plugin
+	if req.All {
+		plugin = ""
+	}
+	trigger := req.Trigger
+	if trigger == "" {
+		trigger = TriggerAPI
+	}
+	job, err := a.StartSync(plugin, trigger)
 	if err != nil {
-		http.Error(w, `{"error":"`+err.Error()+`"}`, http.StatusBadGateway)
+		writeJSONError(w, err.Error(), http.StatusBadGateway)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	w.Write([]byte(`{"success":true}`))
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"job_id":  job.ID,
+		"job":     job,
+	})
 }
 
-// handleCapture creates a quick capture
-func (a *App) handleCapture(w http.ResponseWriter, r *http.Request) {
-	if r.Method != "POST" {
-		http.Error(w, `{"error":"POST only"}`, http.StatusMethodNotAllowed)
-		return
+// handleSyncs lists sync history, newest first. Filters: plugin, status, limit.
+func (a *App) handleSyncs(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+	limit := 20
+	if l := params.Get("limit"); l != "" {
+		n, err := strconv.Atoi(l)
+		if err != nil || n < 0 {
+			http.Error(w, `{"error":"invalid limit"}`, http.StatusBadRequest)
+			return
+		}
+		limit = n
 	}
 
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(a.syncs.List(params.Get("plugin"), params.Get("status"), limit))
+}
+
+// handleSyncJob serves /api/syncs/{id}, and /api/syncs/{id}/events as a
+// stream of progress events ending with "done"
+func (a *App) handleSyncJob(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, "/api/syncs/")
+	id, rest, _ := strings.Cut(path, "/")
+
+	switch rest {
+	case "":
+		job, ok := a.syncs.Get(id)
+		if !ok {
+			http.Error(w, `{"error":"sync job not found"}`, http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(job)
+	case "events":
+		a.streamSyncJob(w, r, id)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// handlePlugins lists sync plugins with their settings and status
+func (a *App) handlePlugins(w http.ResponseWriter, r *http.Request) {
 	if !a.IsConnected() {
 		http.Error(w, `{"error":"SyncHub not connected"}`, http.StatusServiceUnavailable)
 		return
 	}
 
-	body, _ := io.ReadAll(r.Body)
+	plugins, err := a.bridge.RefreshPlugins()
+	if err != nil {
+		// Older SyncHubs don't answer get_plugins; use what they pushed
+		log.Printf("[Plugins] Refresh failed: %v", err)
+		plugins = a.bridge.GetPlugins()
+	}
 
-	var req struct {
-		Text   string   `json:"text"`
-		Source string   `json:"source"`
-		Tags   []string `json:"tags"`
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(plugins)
+}
+
+// handlePlugin serves /api/plugins/{id} and changes to it:
+// POST .../enable, .../disable, and .../config with a JSON object of settings
+func (a *App) handlePlugin(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, "/api/plugins/")
+	id, action, _ := strings.Cut(path, "/")
+
+	if !a.IsConnected() {
+		writeJSONError(w, "SyncHub not connected", http.StatusServiceUnavailable)
+		return
 	}
 
-	if err := json.Unmarshal(body, &req); err != nil {
-		// Treat as plain text
-		req.Text = string(body)
-		req.Source = "cli"
+	var known bool
+	for _, p := range a.bridge.GetPlugins() {
+		if p.ID == id {
+			known = true
+		}
+	}
+	if !known {
+		writeJSONError(w, "plugin not found: "+id, http.StatusNotFound)
+		return
 	}
 
-	if req.Text == "" {
-		http.Error(w, `{"error":"text required"}`, http.StatusBadRequest)
+	if action == "" {
+		plugins, _ := a.bridge.RefreshPlugins()
+		for _, p := range plugins {
+			if p.ID == id {
+				w.Header().Set("Content-Type", "application/json")
+				json.NewEncoder(w).Encode(p)
+				return
+			}
+		}
+		writeJSONError(w, "plugin not found: "+id, http.StatusNotFound)
 		return
 	}
 
-	// Use the log_to_journal tool for quick captures
-	result, err := a.bridge.ExecuteTool("log_to_journal", map[string]interface{}{
-		"content": req.Text,
-	})
+	if r.Method != "POST" {
+		writeJSONError(w, "POST only", http.StatusMethodNotAllowed)
+		return
+	}
 
+	var plugin Plugin
+	var err error
+	switch action {
+	case "enable", "disable":
+		plugin, err = a.bridge.SetPluginEnabled(id, action == "enable")
+	case "config":
+		var settings map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&settings); err != nil {
+			writeJSONError(w, "invalid JSON", http.StatusBadRequest)
+			return
+		}
+		if len(settings) == 0 {
+			writeJSONError(w, "no settings to change", http.StatusBadRequest)
+			return
+		}
+		plugin, err = a.bridge.SetPluginConfig(id, settings)
+	default:
+		http.NotFound(w, r)
+		return
+	}
 	if err != nil {
-		http.Error(w, `{"error":"`+err.Error()+`"}`, http.StatusBadGateway)
+		// SyncHub rejects unknown choices and secret keys
+		writeJSONError(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
+	log.Printf("[Plugins] %s: %s", id, action)
 	w.Header().Set("Content-Type", "application/json")
-	w.Write(result)
+	json.NewEncoder(w).Encode(plugin)
+}
+
+func (a *App) streamSyncJob(w http.ResponseWriter, r *http.Request, id string) {
+	job, events, cancel, ok := a.syncs.Watch(id)
+	if !ok {
+		http.Error(w, `{"error":"sync job not found"}`, http.StatusNotFound)
+		return
+	}
+	defer cancel()
+
+	flusher, _ := w.(http.Flusher)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	send := func(event string, v interface{}) {
+		data, _ := json.Marshal(v)
+		fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, data)
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+
+	// Start with the job as it stands, so late watchers see earlier progress
+	send("job", job)
+
+	for events != nil {
+		select {
+		case event, open := <-events:
+			if !open {
+				events = nil
+				break
+			}
+			if event.Type == "done" {
+				continue // sent below from the final state
+			}
+			send(event.Type, event)
+		case <-r.Context().Done():
+			return
+		}
+	}
+
+	final, _ := a.syncs.Get(id)
+	send("done", SyncEvent{Type: "done", Job: &final})
+}
+
+// handleCapture creates a quick capture in the Captures collection or
+// the journal, as the request's to says. Captures are JSON, plain text, or
+// multipart forms with files.
+func (a *App) handleCapture(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		writeJSONError(w, "POST only", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if !a.IsConnected() {
+		writeJSONError(w, "SyncHub not connected", http.StatusServiceUnavailable)
+		return
+	}
+
+	var req CaptureRequest
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType == "multipart/form-data" {
+		var err error
+		if req, err = readCaptureForm(w, r); err != nil {
+			writeJSONError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	} else {
+		body, _ := io.ReadAll(http.MaxBytesReader(w, r.Body, maxCaptureSize))
+		if err := json.Unmarshal(body, &req); err != nil {
+			// Treat as plain text
+			req = CaptureRequest{Text: string(body), Source: "cli"}
+		}
+	}
+
+	if strings.TrimSpace(req.Text) == "" && req.URL == "" && len(req.Files) == 0 {
+		writeJSONError(w, "text, url or file required", http.StatusBadRequest)
+		return
+	}
+
+	result, err := a.Capture(req)
+	if err != nil {
+		code := http.StatusBadGateway
+		if _, ok := err.(captureError); ok {
+			code = http.StatusBadRequest
+		}
+		writeJSONError(w, err.Error(), code)
+		return
+	}
+	log.Printf("[Capture] Captured to %s", result.To)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// readCaptureForm reads a multipart capture: text, source, tags (comma
+// separated or repeated), to and url fields, and file parts
+func readCaptureForm(w http.ResponseWriter, r *http.Request) (CaptureRequest, error) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxCaptureSize)
+	if err := r.ParseMultipartForm(maxCaptureSize); err != nil {
+		return CaptureRequest{}, fmt.Errorf("invalid upload: %w", err)
+	}
+	req := CaptureRequest{
+		Text:   r.FormValue("text"),
+		Source: r.FormValue("source"),
+		To:     r.FormValue("to"),
+		URL:    r.FormValue("url"),
+	}
+	for _, tags := range r.MultipartForm.Value["tags"] {
+		req.Tags = append(req.Tags, strings.Split(tags, ",")...)
+	}
+	for _, fh := range r.MultipartForm.File["file"] {
+		f, err := fh.Open()
+		if err != nil {
+			return CaptureRequest{}, err
+		}
+		data, err := io.ReadAll(f)
+		f.Close()
+		if err != nil {
+			return CaptureRequest{}, err
+		}
+		req.Files = append(req.Files, CaptureFile{Name: fh.Filename, Data: data})
+	}
+	return req, nil
 }
 
 // handleMCPTools returns available tools in MCP format
@@ -171,12 +832,7 @@ func (a *App) handleMCPTools(w http.ResponseWriter, r *http.Request) {
 // handleMCPCall executes a tool call
 func (a *App) handleMCPCall(w http.ResponseWriter, r *http.Request) {
 	if r.Method != "POST" {
-		http.Error(w, `{"error":"POST only"}`, http.StatusMethodNotAllowed)
-		return
-	}
-
-	if !a.IsConnected() {
-		http.Error(w, `{"error":"SyncHub not connected"}`, http.StatusServiceUnavailable)
+		writeJSONError(w, "POST only", http.StatusMethodNotAllowed)
 		return
 	}
 
@@ -186,16 +842,164 @@ func (a *App) handleMCPCall(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, `{"error":"invalid JSON"}`, http.StatusBadRequest)
+		writeJSONError(w, "invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	// Local tools work without SyncHub
+	if !a.IsConnected() && !a.bridge.IsLocalTool(req.Name) {
+		writeJSONError(w, "SyncHub not connected", http.StatusServiceUnavailable)
 		return
 	}
 
 	result, err := a.bridge.ExecuteTool(req.Name, req.Args)
 	if err != nil {
-		http.Error(w, `{"error":"`+err.Error()+`"}`, http.StatusBadGateway)
+		writeJSONError(w, err.Error(), http.StatusBadGateway)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	w.Write(result)
 }
+
+// handleOpen opens a note (or the workspace) in the browser
+func (a *App) handleOpen(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, `{"error":"POST only"}`, http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		GUID string `json:"guid"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error":"invalid JSON"}`, http.StatusBadRequest)
+		return
+	}
+
+	target := a.config.ThymerURL()
+	if req.GUID != "" {
+		target = a.config.NoteURL(req.GUID)
+	}
+	openBrowser(target)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"url": target})
+}
+
+// handleLLMStatus reports the local LLM process and endpoint health
+func (a *App) handleLLMStatus(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(a.llm.Status())
+}
+
+// handleLLMControl starts, stops or restarts the local LLM process
+func (a *App) handleLLMControl(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		writeJSONError(w, "POST only", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var err error
+	switch strings.TrimPrefix(r.URL.Path, "/api/llm/") {
+	case "start":
+		err = a.llm.Start()
+	case "stop":
+		err = a.llm.Stop()
+	case "restart":
+		err = a.llm.Restart()
+	}
+
+	if err != nil {
+		writeJSONError(w, err.Error(), http.StatusConflict)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(a.llm.Status())
+}
+
+// handleLLMLogs returns recent output from the local LLM process
+func (a *App) handleLLMLogs(w http.ResponseWriter, r *http.Request) {
+	lines, _ := strconv.Atoi(r.URL.Query().Get("lines"))
+	if lines <= 0 {
+		lines = 100
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"lines": a.llm.Logs(lines),
+	})
+}
+
+// handleChatCompletions proxies OpenAI-compatible chat requests to the local
+// LLM, filling in the configured model when the client doesn't pick one.
+// Requests to /agent/v1/chat/completions, or with "thymer_tools": true, run
+// the tool-calling loop here with the workspace tools instead.
+func (a *App) handleChatCompletions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		writeJSONError(w, "POST only", http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeJSONError(w, "failed to read body", http.StatusBadRequest)
+		return
+	}
+
+	var req map[string]interface{}
+	if err := json.Unmarshal(body, &req); err != nil {
+		writeJSONError(w, "invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	// AgentHub sends "default" when no custom model is set
+	if model, _ := req["model"].(string); (model == "" || model == "default") && a.config.LLMModel != "" {
+		req["model"] = a.config.LLMModel
+		body, _ = json.Marshal(req)
+	}
+
+	if strings.HasPrefix(r.URL.Path, "/agent/") || getBool(req, "thymer_tools") {
+		delete(req, "thymer_tools")
+		a.handleAgentChat(w, r, req)
+		return
+	}
+
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	r.ContentLength = int64(len(body))
+	a.llm.Proxy().ServeHTTP(w, r)
+}
+
+// handleAgentChat answers a chat request with the agent loop, streaming if
+// the client asked for it
+func (a *App) handleAgentChat(w http.ResponseWriter, r *http.Request, req map[string]interface{}) {
+	if !a.IsConnected() {
+		http.Error(w, `{"error":"SyncHub not connected"}`, http.StatusServiceUnavailable)
+		return
+	}
+
+	model, _ := req["model"].(string)
+	if getBool(req, "stream") {
+		stream := newAgentStream(w, model)
+		if err := a.agent.Run(r.Context(), req, stream); err != nil {
+			log.Printf("[Agent] %v", err)
+			stream.fail(err)
+		}
+		return
+	}
+
+	result := &agentJSON{model: model}
+	if err := a.agent.Run(r.Context(), req, result); err != nil {
+		log.Printf("[Agent] %v", err)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadGateway)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"error": map[string]string{"message": err.Error()},
+		})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result.response())
+}
@@ -6,6 +6,7 @@ import (
 	"log"
 	"net/http"
 	"sync"
+	"time"
 )
 
 // App coordinates all desktop services
@@ -18,6 +19,13 @@ type App struct {
 	bridge     *Bridge
 	httpServer *http.Server
 	mcpServer  *MCPServer
+	llm        *LLMManager
+	agent      *Agent
+	syncs      *SyncJobs
+	scheduler  *Scheduler
+	mirror     *Mirror   // nil when disabled
+	embedder   *Embedder // nil without an embeddings model
+	mirrorKick chan bool
 
 	mu     sync.RWMutex
 	ctx    context.Context
@@ -39,35 +47,90 @@ func NewApp(cfg *Config, httpPort, wsPort, mcpPort int) *App {
 func (a *App) Start() error {
 	// Start WebSocket bridge
 	a.bridge = NewBridge(a.wsPort)
+	a.bridge.OnSample = a.sample
 
-	// Set up MCP lifecycle callbacks
-	if a.mcpPort > 0 {
-		a.bridge.OnConnect = func() {
-			log.Println("[App] SyncHub connected, starting MCP server")
-			a.mu.Lock()
-			defer a.mu.Unlock()
-			if a.mcpServer == nil {
-				a.mcpServer = NewMCPServer(a.mcpPort, a.bridge)
-				if err := a.mcpServer.Start(); err != nil {
-					log.Printf("[App] Failed to start MCP server: %v", err)
-				}
+	// Track sync jobs from the progress SyncHub reports
+	a.syncs = NewSyncJobs(syncHistoryPath())
+	a.bridge.OnSyncProgress = a.syncs.Progress
+	a.bridge.OnSyncComplete = a.syncs.Finish
+
+	// Scheduled syncs; runs missed while disconnected go when SyncHub is back
+	a.scheduler = NewScheduler(a.config.Schedule,
+		func(plugin string) (SyncJob, error) { return a.StartSync(plugin, TriggerSchedule) },
+		a.IsConnected, a.syncs.Running)
+
+	// Feed readers can't sign in, so each feed has a token in its URL
+	if a.config.EnsureFeedTokens(feedNames()) {
+		if err := a.config.Save(); err != nil {
+			log.Printf("[Feeds] Failed to save feed tokens: %v", err)
+		}
+	}
+
+	// Mirror records for offline queries
+	if a.config.MirrorInterval() > 0 {
+		mirror, err := OpenMirror(mirrorPath())
+		if err != nil {
+			log.Printf("[Mirror] Failed to open, offline queries disabled: %v", err)
+		} else {
+			a.mirror = mirror
+			a.mirrorKick = make(chan bool, 1)
+			a.bridge.AddLocalTool(searchLocalTool, mirror.runSearchLocal)
+			if cfg, ok := a.config.EmbeddingsSettings(); ok {
+				a.embedder = NewEmbedder(mirror, cfg)
+				mirror.OnChange = a.embedder.Kick
+				a.bridge.AddLocalTool(semanticSearchTool, a.embedder.runSemanticSearch)
 			}
 		}
-		a.bridge.OnDisconnect = func() {
-			log.Println("[App] SyncHub disconnected, stopping MCP server")
-			a.mu.Lock()
-			defer a.mu.Unlock()
-			if a.mcpServer != nil {
-				a.mcpServer.Stop()
-				a.mcpServer = nil
+	}
+
+	// Set up MCP lifecycle callbacks
+	a.bridge.OnConnect = func() {
+		a.scheduler.Kick()
+		a.kickMirror()
+		if a.mcpPort == 0 {
+			return
+		}
+		log.Println("[App] SyncHub connected, starting MCP server")
+		a.mu.Lock()
+		defer a.mu.Unlock()
+		if a.mcpServer == nil {
+			a.mcpServer = NewMCPServer(a.mcpPort, a.bridge)
+			if err := a.mcpServer.Start(); err != nil {
+				log.Printf("[App] Failed to start MCP server: %v", err)
 			}
 		}
 	}
+	a.bridge.OnDisconnect = func() {
+		a.syncs.FailRunning("SyncHub disconnected")
+		if a.mcpPort == 0 {
+			return
+		}
+		log.Println("[App] SyncHub disconnected, stopping MCP server")
+		a.mu.Lock()
+		defer a.mu.Unlock()
+		if a.mcpServer != nil {
+			a.mcpServer.Stop()
+			a.mcpServer = nil
+		}
+	}
 
 	if err := a.bridge.Start(); err != nil {
 		return fmt.Errorf("bridge: %w", err)
 	}
 
+	a.scheduler.Run()
+	if a.mirror != nil {
+		go a.runMirror(a.config.MirrorInterval())
+	}
+	if a.embedder != nil {
+		go a.embedder.Run(a.ctx)
+	}
+
+	// Supervise the local LLM (health checks, optional auto-start)
+	a.llm = NewLLMManager(a.config)
+	a.llm.Run()
+	a.agent = NewAgent(a.bridge, a.llm)
+
 	// Start HTTP API
 	if err := a.startHTTP(); err != nil {
 		return fmt.Errorf("http: %w", err)
@@ -79,6 +142,10 @@ func (a *App) Start() error {
 func (a *App) Stop() {
 	a.cancel()
 
+	if a.scheduler != nil {
+		a.scheduler.Stop()
+	}
+
 	if a.mcpServer != nil {
 		a.mcpServer.Stop()
 	}
@@ -87,9 +154,79 @@ func (a *App) Stop() {
 		a.httpServer.Shutdown(context.Background())
 	}
 
+	if a.llm != nil {
+		a.llm.Shutdown()
+	}
+
 	if a.bridge != nil {
 		a.bridge.Stop()
 	}
+
+	if a.mirror != nil {
+		a.mirror.Close()
+	}
+}
+
+// runMirror snapshots records into the mirror every interval, and fully
+// whenever SyncHub connects and once a day
+func (a *App) runMirror(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	var lastFull time.Time
+	for {
+		full := false
+		select {
+		case <-ticker.C:
+		case full = <-a.mirrorKick:
+		case <-a.ctx.Done():
+			return
+		}
+		if !a.IsConnected() {
+			continue
+		}
+		if time.Since(lastFull) > mirrorFullSnapshotInterval {
+			full = true
+		}
+		err := a.mirror.Snapshot(a.bridge, full)
+		logMirrorError("snapshot", err)
+		if err == nil && full {
+			lastFull = time.Now()
+		}
+	}
+}
+
+// kickMirror takes a full snapshot now
+func (a *App) kickMirror() {
+	if a.mirror == nil {
+		return
+	}
+	select {
+	case a.mirrorKick <- true:
+	default:
+	}
+}
+
+// StartSync syncs one plugin, or all of them when plugin is empty, as a
+// tracked job. trigger records where the request came from.
+func (a *App) StartSync(plugin, trigger string) (SyncJob, error) {
+	id := a.syncs.Start(plugin, trigger).ID
+
+	var accepted bool
+	var err error
+	if plugin == "" {
+		accepted, err = a.bridge.SyncAll(id)
+	} else {
+		accepted, err = a.bridge.Sync(plugin, id)
+	}
+
+	if err != nil {
+		a.syncs.Finish(id, nil, err.Error())
+	} else if !accepted {
+		// Older SyncHub: the reply means the sync is already over
+		a.syncs.Finish(id, nil, "")
+	}
+	job, _ := a.syncs.Get(id)
+	return job, err
 }
 
 func (a *App) startHTTP() error {
@@ -98,19 +235,54 @@ func (a *App) startHTTP() error {
 	// Status
 	mux.HandleFunc("/api/status", a.handleStatus)
 
-	// Query collections
+	// Query, search, export and import collections
 	mux.HandleFunc("/api/query", a.handleQuery)
+	mux.HandleFunc("/api/search", a.handleSearch)
+	mux.HandleFunc("/api/export", a.handleExport)
+	mux.HandleFunc("/api/import", a.handleImport)
 
 	// Trigger sync
 	mux.HandleFunc("/api/sync", a.handleSync)
+	mux.HandleFunc("/api/syncs", a.handleSyncs)
+	mux.HandleFunc("/api/syncs/", a.handleSyncJob)
+
+	// Sync plugins and their settings
+	mux.HandleFunc("/api/plugins", a.handlePlugins)
+	mux.HandleFunc("/api/plugins/", a.handlePlugin)
 
 	// Capture
 	mux.HandleFunc("/api/capture", a.handleCapture)
+	mux.HandleFunc("/attachments/", a.handleAttachment)
+
+	// Calendar subscription
+	mux.HandleFunc("/calendar.ics", a.handleCalendarFeed)
+
+	// Atom feeds for feed readers
+	mux.HandleFunc("/api/feeds", a.handleFeeds)
+	mux.HandleFunc("/feeds/", a.handleFeed)
+
+	// Open a note in the browser
+	mux.HandleFunc("/api/open", a.handleOpen)
 
 	// MCP tools
 	mux.HandleFunc("/api/mcp/tools", a.handleMCPTools)
 	mux.HandleFunc("/api/mcp/call", a.handleMCPCall)
 
+	// Local LLM management
+	mux.HandleFunc("/api/llm/status", a.handleLLMStatus)
+	mux.HandleFunc("/api/llm/start", a.handleLLMControl)
+	mux.HandleFunc("/api/llm/stop", a.handleLLMControl)
+	mux.HandleFunc("/api/llm/restart", a.handleLLMControl)
+	mux.HandleFunc("/api/llm/logs", a.handleLLMLogs)
+
+	// OpenAI-compatible proxy to the local LLM
+	mux.HandleFunc("/v1/chat/completions", a.handleChatCompletions)
+	mux.Handle("/v1/", a.llm.Proxy())
+
+	// Same endpoint with the Thymer tool-calling loop run server-side
+	mux.HandleFunc("/agent/v1/chat/completions", a.handleChatCompletions)
+	mux.Handle("/agent/v1/", http.StripPrefix("/agent", a.llm.Proxy()))
+
 	// Health check
 	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
 		w.Write([]byte(`{"status":"ok"}`))
@@ -131,12 +303,32 @@ func (a *App) startHTTP() error {
 	return nil
 }
 
+// privatePaths return secrets, so they get no CORS headers: a web page open
+// in the browser can't read them, while the CLI still can
+var privatePaths = map[string]bool{
+	"/api/feeds": true,
+}
+
 func corsMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if privatePaths[r.URL.Path] {
+			if r.Method == "OPTIONS" {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+
 		w.Header().Set("Access-Control-Allow-Origin", "*")
 		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
 		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+		if requested := r.Header.Get("Access-Control-Request-Headers"); requested != "" {
+			// OpenAI client libraries send their own headers (x-stainless-*)
+			w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, "+requested)
+		}
 		w.Header().Set("Access-Control-Allow-Private-Network", "true")
+		w.Header().Set("Access-Control-Expose-Headers", "X-Total-Count, X-Next-Cursor, X-Stale, X-Snapshot-At, Content-Disposition")
 
 		if r.Method == "OPTIONS" {
 			w.WriteHeader(http.StatusOK)
@@ -155,6 +347,25 @@ func (a *App) IsConnected() bool {
 	return a.bridge.IsConnected()
 }
 
+// SamplingAvailable returns true if an MCP client can serve sampling requests
+func (a *App) SamplingAvailable() bool {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.mcpServer != nil && a.mcpServer.SamplingAvailable()
+}
+
+// sample forwards a SyncHub sampling request to the connected MCP client
+func (a *App) sample(ctx context.Context, req SampleRequest) (*SampleResult, error) {
+	a.mu.RLock()
+	server := a.mcpServer
+	a.mu.RUnlock()
+
+	if server == nil {
+		return nil, fmt.Errorf("MCP server not running")
+	}
+	return server.Sample(ctx, req)
+}
+
 // ToolCount returns the number of registered tools
 func (a *App) ToolCount() int {
 	if a.bridge == nil {
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCORSMiddleware(t *testing.T) {
+	handler := corsMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("{}"))
+	}))
+	serve := func(method, path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set("Origin", "https://example.com")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := serve("GET", "/api/status"); rec.Header().Get("Access-Control-Allow-Origin") != "*" {
+		t.Errorf("/api/status: no CORS headers")
+	}
+	if rec := serve("OPTIONS", "/api/status"); rec.Code != http.StatusOK {
+		t.Errorf("/api/status preflight: %d", rec.Code)
+	}
+
+	// Feed tokens stay unreadable from web pages
+	if rec := serve("GET", "/api/feeds"); rec.Header().Get("Access-Control-Allow-Origin") != "" || rec.Body.String() != "{}" {
+		t.Errorf("/api/feeds: %d %v", rec.Code, rec.Header())
+	}
+	if rec := serve("OPTIONS", "/api/feeds"); rec.Code != http.StatusForbidden || rec.Header().Get("Access-Control-Allow-Origin") != "" {
+		t.Errorf("/api/feeds preflight: %d %v", rec.Code, rec.Header())
+	}
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"log"
@@ -25,10 +26,29 @@ type Tool struct {
 	Parameters  map[string]interface{} `json:"parameters,omitempty"`
 }
 
-// Plugin represents a registered sync plugin
+// localTool is a tool thymer-bar answers itself, without SyncHub
+type localTool struct {
+	tool Tool
+	run  func(args map[string]interface{}) (interface{}, error)
+}
+
+// Plugin represents a sync plugin and its settings in SyncHub. Older
+// SyncHubs only send Name (the plugin id) and Enabled.
 type Plugin struct {
-	Name    string `json:"name"`
-	Enabled bool   `json:"enabled"`
+	ID          string                 `json:"id"`
+	Name        string                 `json:"name"`
+	Enabled     bool                   `json:"enabled"`
+	Loaded      bool                   `json:"loaded"` // Plugin code is running in the browser
+	Version     string                 `json:"version,omitempty"`
+	Collection  string                 `json:"collection,omitempty"` // Where it syncs to
+	Interval    string                 `json:"interval,omitempty"`   // SyncHub's own interval, e.g. "5m" or "manual"
+	Status      string                 `json:"status,omitempty"`     // idle, syncing or error
+	LastSync    *time.Time             `json:"last_sync,omitempty"`
+	LastError   string                 `json:"last_error,omitempty"`
+	HasToken    bool                   `json:"has_token"`
+	Settings    map[string]string      `json:"settings,omitempty"` // journal, toast, log_level
+	Config      map[string]interface{} `json:"config,omitempty"`   // Secret values are redacted
+	ConfigError string                 `json:"config_error,omitempty"`
 }
 
 // PendingCall tracks an outgoing request waiting for response
@@ -44,9 +64,13 @@ type Bridge struct {
 	server *http.Server
 	client *websocket.Conn
 	mu     sync.RWMutex
+	// The connection allows one writer at a time; replies to sampling
+	// requests and tool calls are sent from their own goroutines
+	writeMu sync.Mutex
 
 	tools   []Tool
 	plugins []Plugin
+	local   []localTool
 
 	callID    atomic.Int64
 	pending   map[string]*PendingCall
@@ -55,7 +79,11 @@ type Bridge struct {
 	// Callbacks
 	OnConnect    func()
 	OnDisconnect func()
-	connected    bool // tracks if OnConnect was called
+	OnSample     func(ctx context.Context, req SampleRequest) (*SampleResult, error)
+	// Sync progress pushed by SyncHub for jobs started with Sync/SyncAll
+	OnSyncProgress func(job, event string, result SyncPluginResult)
+	OnSyncComplete func(job string, results []SyncPluginResult, errMsg string)
+	connected      bool // tracks if OnConnect was called
 }
 
 func NewBridge(port int) *Bridge {
@@ -219,11 +247,13 @@ func (b *Bridge) handleMessage(data []byte) {
 				}
 			}
 			// Call OnConnect after first tool registration
-			if !b.connected && b.OnConnect != nil {
+			if !b.connected {
 				b.connected = true
 				b.mu.Unlock()
 				log.Printf("[Bridge] Received %d tools from SyncHub", len(b.tools))
-				b.OnConnect()
+				if b.OnConnect != nil {
+					b.OnConnect()
+				}
 			} else {
 				b.mu.Unlock()
 				log.Printf("[Bridge] Received %d tools from SyncHub", len(b.tools))
@@ -232,31 +262,81 @@ func (b *Bridge) handleMessage(data []byte) {
 		return
 
 	case "plugins":
-		if pluginsRaw, ok := msg["plugins"].([]interface{}); ok {
+		var push struct {
+			Plugins json.RawMessage `json:"plugins"`
+		}
+		json.Unmarshal(data, &push)
+		if plugins, err := parsePlugins(push.Plugins); err == nil {
 			b.mu.Lock()
-			b.plugins = make([]Plugin, 0, len(pluginsRaw))
-			for _, p := range pluginsRaw {
-				if pluginMap, ok := p.(map[string]interface{}); ok {
-					b.plugins = append(b.plugins, Plugin{
-						Name:    getString(pluginMap, "name"),
-						Enabled: getBool(pluginMap, "enabled"),
-					})
-				}
-			}
+			b.plugins = plugins
 			b.mu.Unlock()
-			log.Printf("[Bridge] Received %d plugins from SyncHub", len(b.plugins))
+			log.Printf("[Bridge] Received %d plugins from SyncHub", len(plugins))
 		}
 
 	case "register":
 		version := getString(msg, "version")
 		log.Printf("[Bridge] SyncHub registered: %s", version)
 
+	case "sync_progress":
+		var progress struct {
+			Job    string           `json:"job"`
+			Event  string           `json:"event"`
+			Result SyncPluginResult `json:"result"`
+		}
+		if err := json.Unmarshal(data, &progress); err == nil && b.OnSyncProgress != nil {
+			b.OnSyncProgress(progress.Job, progress.Event, progress.Result)
+		}
+
 	case "sync_complete":
-		plugin := getString(msg, "plugin")
-		log.Printf("[Bridge] Sync complete: %s", plugin)
+		var complete struct {
+			Job     string             `json:"job"`
+			Plugin  string             `json:"plugin"`
+			Results []SyncPluginResult `json:"results"`
+			Error   string             `json:"error"`
+		}
+		json.Unmarshal(data, &complete)
+		log.Printf("[Bridge] Sync complete: %s", complete.Plugin)
+		if complete.Job != "" && b.OnSyncComplete != nil {
+			b.OnSyncComplete(complete.Job, complete.Results, complete.Error)
+		}
+
+	case "sample":
+		// Don't block the read loop while the client's LLM runs
+		go b.handleSample(getString(msg, "id"), data)
 	}
 }
 
+// handleSample forwards a sampling request from SyncHub and replies with the result
+func (b *Bridge) handleSample(id string, data []byte) {
+	if b.OnSample == nil {
+		b.replyError(id, fmt.Errorf("sampling not available"))
+		return
+	}
+
+	var req SampleRequest
+	if err := json.Unmarshal(data, &req); err != nil {
+		b.replyError(id, err)
+		return
+	}
+
+	result, err := b.OnSample(context.Background(), req)
+	if err != nil {
+		log.Printf("[Bridge] Sampling failed: %v", err)
+		b.replyError(id, err)
+		return
+	}
+	b.reply(id, result)
+}
+
+// reply answers a request that SyncHub initiated
+func (b *Bridge) reply(id string, result interface{}) error {
+	return b.send(map[string]interface{}{"id": id, "result": result})
+}
+
+func (b *Bridge) replyError(id string, err error) error {
+	return b.send(map[string]interface{}{"id": id, "error": err.Error()})
+}
+
 func (b *Bridge) send(msg map[string]interface{}) error {
 	b.mu.RLock()
 	conn := b.client
@@ -271,6 +351,8 @@ func (b *Bridge) send(msg map[string]interface{}) error {
 		return err
 	}
 
+	b.writeMu.Lock()
+	defer b.writeMu.Unlock()
 	return conn.WriteMessage(websocket.TextMessage, data)
 }
 
@@ -321,10 +403,42 @@ func (b *Bridge) IsConnected() bool {
 	return b.client != nil
 }
 
+// GetTools returns SyncHub's tools followed by the local ones
 func (b *Bridge) GetTools() []Tool {
 	b.mu.RLock()
 	defer b.mu.RUnlock()
-	return b.tools
+	if len(b.local) == 0 {
+		return b.tools
+	}
+	tools := append([]Tool(nil), b.tools...)
+	for _, lt := range b.local {
+		tools = append(tools, lt.tool)
+	}
+	return tools
+}
+
+// AddLocalTool registers a tool that thymer-bar runs itself. It is listed
+// and called like SyncHub's tools, and works while SyncHub is disconnected.
+func (b *Bridge) AddLocalTool(tool Tool, run func(args map[string]interface{}) (interface{}, error)) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.local = append(b.local, localTool{tool: tool, run: run})
+}
+
+// IsLocalTool reports whether name is answered by thymer-bar
+func (b *Bridge) IsLocalTool(name string) bool {
+	return b.findLocalTool(name) != nil
+}
+
+func (b *Bridge) findLocalTool(name string) *localTool {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for i := range b.local {
+		if b.local[i].tool.Name == name {
+			return &b.local[i]
+		}
+	}
+	return nil
 }
 
 func (b *Bridge) GetPlugins() []Plugin {
@@ -333,26 +447,116 @@ func (b *Bridge) GetPlugins() []Plugin {
 	return b.plugins
 }
 
-// ExecuteTool calls a tool via SyncHub
+// RefreshPlugins asks SyncHub for its current plugin list
+func (b *Bridge) RefreshPlugins() ([]Plugin, error) {
+	result, err := b.Call("get_plugins", nil)
+	if err != nil {
+		return nil, err
+	}
+	plugins, err := parsePlugins(result)
+	if err != nil {
+		return nil, err
+	}
+	b.mu.Lock()
+	b.plugins = plugins
+	b.mu.Unlock()
+	return plugins, nil
+}
+
+// SetPluginEnabled enables or pauses a plugin in SyncHub
+func (b *Bridge) SetPluginEnabled(pluginID string, enabled bool) (Plugin, error) {
+	return b.updatePlugin("set_plugin_enabled", map[string]interface{}{
+		"plugin":  pluginID,
+		"enabled": enabled,
+	})
+}
+
+// SetPluginConfig changes a plugin's settings or config keys in SyncHub; a
+// nil value removes a config key
+func (b *Bridge) SetPluginConfig(pluginID string, settings map[string]interface{}) (Plugin, error) {
+	return b.updatePlugin("set_plugin_config", map[string]interface{}{
+		"plugin":   pluginID,
+		"settings": settings,
+	})
+}
+
+// updatePlugin sends a change and caches the updated plugin SyncHub returns
+func (b *Bridge) updatePlugin(msgType string, params map[string]interface{}) (Plugin, error) {
+	result, err := b.Call(msgType, params)
+	if err != nil {
+		return Plugin{}, err
+	}
+	var plugin Plugin
+	if err := json.Unmarshal(result, &plugin); err != nil {
+		return Plugin{}, fmt.Errorf("invalid plugin from SyncHub: %w", err)
+	}
+
+	b.mu.Lock()
+	for i := range b.plugins {
+		if b.plugins[i].ID == plugin.ID {
+			b.plugins[i] = plugin
+		}
+	}
+	b.mu.Unlock()
+	return plugin, nil
+}
+
+func parsePlugins(data json.RawMessage) ([]Plugin, error) {
+	var plugins []Plugin
+	if err := json.Unmarshal(data, &plugins); err != nil {
+		return nil, err
+	}
+	for i := range plugins {
+		if plugins[i].ID == "" {
+			plugins[i].ID = plugins[i].Name
+		}
+	}
+	if plugins == nil {
+		plugins = []Plugin{}
+	}
+	return plugins, nil
+}
+
+// ExecuteTool calls a tool via SyncHub, or runs it if it is local
 func (b *Bridge) ExecuteTool(name string, args map[string]interface{}) (json.RawMessage, error) {
+	if lt := b.findLocalTool(name); lt != nil {
+		result, err := lt.run(args)
+		if err != nil {
+			return nil, err
+		}
+		return json.Marshal(result)
+	}
 	return b.Call("tool_call", map[string]interface{}{
 		"name": name,
 		"args": args,
 	})
 }
 
-// Sync triggers a plugin sync
-func (b *Bridge) Sync(pluginID string) error {
-	_, err := b.Call("sync", map[string]interface{}{
+// Sync triggers a plugin sync tagged with a job id. It returns true when
+// SyncHub accepted the job and will report progress; older SyncHubs only
+// reply once the sync is over.
+func (b *Bridge) Sync(pluginID, jobID string) (bool, error) {
+	result, err := b.Call("sync", map[string]interface{}{
 		"plugin": pluginID,
+		"job":    jobID,
 	})
-	return err
+	return syncAccepted(result), err
 }
 
-// SyncAll triggers sync for all plugins
-func (b *Bridge) SyncAll() error {
-	_, err := b.Call("sync_all", nil)
-	return err
+// SyncAll triggers sync for all plugins, like Sync
+func (b *Bridge) SyncAll(jobID string) (bool, error) {
+	result, err := b.Call("sync_all", map[string]interface{}{
+		"job": jobID,
+	})
+	return syncAccepted(result), err
+}
+
+func syncAccepted(result json.RawMessage) bool {
+	var r struct {
+		Accepted bool `json:"accepted"`
+	}
+	json.Unmarshal(result, &r)
+	return r.Accepted
 }
 
 func getString(m map[string]interface{}, key string) string {
@@ -0,0 +1,324 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// calendarFeedLimit caps the events in /calendar.ics
+const calendarFeedLimit = 1000
+
+// calendarFeedRefresh is how often subscribed calendar apps are asked to
+// fetch the feed again
+const calendarFeedRefresh = 15 * time.Minute
+
+// icsEvent is one VEVENT. All-day events use only the dates of Start and
+// End, and End is the day after the last one.
+type icsEvent struct {
+	UID         string
+	Summary     string
+	Start       time.Time
+	End         time.Time // Optional
+	AllDay      bool
+	Location    string
+	URL         string
+	Status      string
+	Description string
+}
+
+// writeICS writes an iCalendar file. Timed events are written in UTC, so
+// calendar apps show them in their own timezone. A refresh interval is
+// suggested to subscribers when given.
+func writeICS(w io.Writer, name string, events []icsEvent, refresh time.Duration) error {
+	var b bytes.Buffer
+	line := func(name, value string) {
+		icsLine(&b, name+":"+value)
+	}
+	line("BEGIN", "VCALENDAR")
+	line("VERSION", "2.0")
+	line("PRODID", "-//Thymer//thymer-bar//EN")
+	line("CALSCALE", "GREGORIAN")
+	line("X-WR-CALNAME", icsText(name))
+	if refresh > 0 {
+		minutes := strconv.Itoa(int(refresh.Minutes()))
+		line("REFRESH-INTERVAL;VALUE=DURATION", "PT"+minutes+"M")
+		line("X-PUBLISHED-TTL", "PT"+minutes+"M")
+	}
+
+	stamp := time.Now().UTC().Format("20060102T150405Z")
+	for _, e := range events {
+		line("BEGIN", "VEVENT")
+		line("UID", e.UID)
+		line("DTSTAMP", stamp)
+		if e.AllDay {
+			start := time.Date(e.Start.Year(), e.Start.Month(), e.Start.Day(), 0, 0, 0, 0, time.UTC)
+			end := start.AddDate(0, 0, 1)
+			if !e.End.IsZero() {
+				end = time.Date(e.End.Year(), e.End.Month(), e.End.Day(), 0, 0, 0, 0, time.UTC)
+			}
+			line("DTSTART;VALUE=DATE", start.Format("20060102"))
+			line("DTEND;VALUE=DATE", end.Format("20060102"))
+		} else {
+			line("DTSTART", e.Start.UTC().Format("20060102T150405Z"))
+			if !e.End.IsZero() && e.End.After(e.Start) {
+				line("DTEND", e.End.UTC().Format("20060102T150405Z"))
+			}
+		}
+		line("SUMMARY", icsText(e.Summary))
+		if e.Location != "" {
+			line("LOCATION", icsText(e.Location))
+		}
+		if e.URL != "" {
+			line("URL", e.URL)
+		}
+		switch status := strings.ToUpper(e.Status); status {
+		case "CONFIRMED", "TENTATIVE", "CANCELLED":
+			line("STATUS", status)
+		}
+		if e.Description != "" {
+			line("DESCRIPTION", icsText(e.Description))
+		}
+		line("END", "VEVENT")
+	}
+	line("END", "VCALENDAR")
+
+	_, err := w.Write(b.Bytes())
+	return err
+}
+
+// icsText escapes an iCalendar TEXT value
+func icsText(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`).Replace(s)
+}
+
+// icsLine writes a content line folded at 75 octets, as RFC 5545 requires
+func icsLine(b *bytes.Buffer, s string) {
+	limit := 75
+	for len(s) > limit {
+		cut := limit
+		for cut > 0 && !isRuneStart(s[cut]) {
+			cut--
+		}
+		b.WriteString(s[:cut] + "\r\n ")
+		s = s[cut:]
+		limit = 74 // The leading space counts
+	}
+	b.WriteString(s + "\r\n")
+}
+
+// calendarWhen is an event's time as the Calendar tools format it. start
+// and end are exact instants; date and time are wall clock, in the
+// browser's timezone.
+type calendarWhen struct {
+	Date    string `json:"date"`
+	Time    string `json:"time"`
+	EndDate string `json:"end_date"`
+	EndTime string `json:"end_time"`
+	AllDay  bool   `json:"all_day"`
+	Start   string `json:"start"`
+	End     string `json:"end"`
+}
+
+// calendarEvent is an event from calendar_find, calendar_upcoming or
+// calendar_needs_followup
+type calendarEvent struct {
+	GUID       string        `json:"guid"`
+	ExternalID string        `json:"external_id"`
+	Title      string        `json:"title"`
+	When       *calendarWhen `json:"when"`
+	Status     string        `json:"status"`
+	Location   string        `json:"location"`
+	MeetLink   string        `json:"meet_link"`
+}
+
+// parseCalendarEvents reads a Calendar tool result: a list of events, or
+// {events: [...]} from calendar_upcoming
+func parseCalendarEvents(result json.RawMessage) ([]calendarEvent, error) {
+	var events []calendarEvent
+	if trimmed := bytes.TrimSpace(result); len(trimmed) > 0 && trimmed[0] == '[' {
+		if err := json.Unmarshal(trimmed, &events); err != nil {
+			return nil, fmt.Errorf("invalid calendar result: %w", err)
+		}
+		return events, nil
+	}
+	var wrapped struct {
+		Events []calendarEvent `json:"events"`
+		Error  string          `json:"error"`
+	}
+	if err := json.Unmarshal(result, &wrapped); err != nil {
+		return nil, fmt.Errorf("invalid calendar result: %w", err)
+	}
+	if wrapped.Error != "" {
+		return nil, fmt.Errorf("%s", wrapped.Error)
+	}
+	return wrapped.Events, nil
+}
+
+// icsEvent converts an event with a date. The UID comes from external_id
+// when the event was synced, so it survives the record being recreated.
+// Without exact instants, wall clock times are read in the local timezone.
+func (c calendarEvent) icsEvent() (icsEvent, bool) {
+	if c.When == nil || c.When.Date == "" {
+		return icsEvent{}, false
+	}
+	uid := c.ExternalID
+	if uid == "" {
+		uid = c.GUID
+	}
+	e := icsEvent{
+		UID:      uid + "@thymer",
+		Summary:  c.Title,
+		AllDay:   c.When.AllDay,
+		Location: c.Location,
+		URL:      c.MeetLink,
+		Status:   c.Status,
+	}
+
+	var err error
+	if e.AllDay {
+		if e.Start, err = time.Parse("2006-01-02", c.When.Date); err != nil {
+			return icsEvent{}, false
+		}
+		if end, err := time.Parse("2006-01-02", c.When.EndDate); err == nil {
+			e.End = end.AddDate(0, 0, 1)
+		}
+		return e, true
+	}
+
+	if c.When.Start != "" {
+		e.Start, err = time.Parse(time.RFC3339Nano, c.When.Start)
+	} else {
+		e.Start, err = time.ParseInLocation("2006-01-02 15:04", c.When.Date+" "+c.When.Time, time.Local)
+	}
+	if err != nil {
+		return icsEvent{}, false
+	}
+	if c.When.End != "" {
+		e.End, _ = time.Parse(time.RFC3339Nano, c.When.End)
+	} else if c.When.EndDate != "" && c.When.EndTime != "" {
+		e.End, _ = time.ParseInLocation("2006-01-02 15:04", c.When.EndDate+" "+c.When.EndTime, time.Local)
+	}
+	return e, true
+}
+
+// calendarFeedQuery picks the Calendar tool and its arguments for the feed
+// parameters, and names the feed
+func calendarFeedQuery(params map[string][]string) (string, map[string]interface{}, string, error) {
+	get := func(key string) string {
+		if v := params[key]; len(v) > 0 {
+			return strings.TrimSpace(v[0])
+		}
+		return ""
+	}
+
+	limit := calendarFeedLimit
+	if v := get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return "", nil, "", fmt.Errorf("limit must be a positive number")
+		}
+		limit = n
+	}
+	args := map[string]interface{}{"limit": limit}
+
+	if v := get("needs_followup"); v != "" {
+		followup, err := strconv.ParseBool(v)
+		if err != nil {
+			return "", nil, "", fmt.Errorf("needs_followup must be true or false")
+		}
+		if followup {
+			return "calendar_needs_followup", args, "Thymer Calendar: Needs follow-up", nil
+		}
+	}
+
+	var filters []string
+	if v := get("calendar"); v != "" {
+		args["calendar"] = v
+		filters = append(filters, v)
+	}
+	name := "Thymer Calendar"
+
+	if v := get("days"); v != "" {
+		days, err := strconv.Atoi(v)
+		if err != nil || days < 1 {
+			return "", nil, "", fmt.Errorf("days must be a positive number")
+		}
+		args["days"] = days
+		filters = append(filters, fmt.Sprintf("next %d days", days))
+		return "calendar_upcoming", args, name + ": " + strings.Join(filters, ", "), nil
+	}
+
+	for _, key := range []string{"status", "timing"} {
+		if v := get(key); v != "" {
+			args[key] = v
+			filters = append(filters, v)
+		}
+	}
+	if len(filters) > 0 {
+		name += ": " + strings.Join(filters, ", ")
+	}
+	return "calendar_find", args, name, nil
+}
+
+// handleCalendarFeed serves the Calendar collection as an iCalendar feed
+// calendar apps can subscribe to. needs_followup=true lists events marked
+// for follow-up, days=N the next N days, and otherwise events are found by
+// calendar, status and timing. The ETag changes only when the events do.
+// Like the Atom feeds, it needs the calendar feed's token.
+func (a *App) handleCalendarFeed(w http.ResponseWriter, r *http.Request) {
+	if !a.checkFeedToken(w, r, calendarFeed) {
+		return
+	}
+	if !a.IsConnected() {
+		// Calendar apps keep their last copy and try again
+		w.Header().Set("Retry-After", "60")
+		writeJSONError(w, "SyncHub not connected", http.StatusServiceUnavailable)
+		return
+	}
+
+	tool, args, name, err := calendarFeedQuery(r.URL.Query())
+	if err != nil {
+		writeJSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	result, err := a.bridge.ExecuteTool(tool, args)
+	if err != nil {
+		writeJSONError(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	found, err := parseCalendarEvents(result)
+	if err != nil {
+		code := http.StatusBadGateway
+		if strings.HasPrefix(err.Error(), "Unknown tool") || strings.Contains(err.Error(), "not found") {
+			code = http.StatusNotFound
+		}
+		writeJSONError(w, err.Error(), code)
+		return
+	}
+
+	if notModified(w, r, append([]byte(name+"\n"), result...)) {
+		return
+	}
+
+	var events []icsEvent
+	for _, c := range found {
+		if e, ok := c.icsEvent(); ok {
+			e.Description = "Open in Thymer: " + a.config.NoteURL(c.GUID)
+			events = append(events, e)
+		}
+	}
+	var buf bytes.Buffer
+	if err := writeICS(&buf, name, events, calendarFeedRefresh); err != nil {
+		writeJSONError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Content-Disposition", `inline; filename="calendar.ics"`)
+	w.Write(buf.Bytes())
+}
@@ -0,0 +1,551 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Where a capture goes
+const (
+	CaptureToJournal  = "journal"
+	CaptureToCaptures = "captures"
+	CaptureToBoth     = "both"
+)
+
+const capturesCollection = "Captures"
+
+var errNoCaptures = errors.New("Captures collection not found")
+
+// captureError is a problem with the capture itself rather than with
+// saving it
+type captureError string
+
+func (e captureError) Error() string { return string(e) }
+
+// captureTitleLength caps titles taken from the first line of a capture
+const captureTitleLength = 80
+
+// captureChunkSize is the most note text sent in one tool call; longer
+// notes are created with the first chunk and appended to
+const captureChunkSize = 16 << 10
+
+// maxCaptureSize limits a capture upload, files included
+const maxCaptureSize = 32 << 20
+
+// CaptureRequest is a quick capture from /api/capture
+type CaptureRequest struct {
+	Text   string        `json:"text"`
+	Source string        `json:"source"`
+	Tags   []string      `json:"tags"`
+	To     string        `json:"to"`  // journal, captures (default) or both
+	URL    string        `json:"url"` // Page to fetch and keep
+	Files  []CaptureFile `json:"-"`   // From multipart uploads
+}
+
+// CaptureFile is an uploaded file. Text files become the capture's text;
+// images and PDFs are kept by thymer-bar and linked from the note.
+type CaptureFile struct {
+	Name string
+	Data []byte
+}
+
+// CaptureResult says where a capture ended up. Fallback is set when it was
+// meant for the Captures collection but went to the journal.
+type CaptureResult struct {
+	To          string   `json:"to"`
+	Title       string   `json:"title"`
+	GUID        string   `json:"guid,omitempty"`
+	ExternalID  string   `json:"external_id,omitempty"`
+	JournalGUID string   `json:"journal_guid,omitempty"`
+	Duplicate   bool     `json:"duplicate,omitempty"`
+	Fallback    string   `json:"fallback,omitempty"`
+	Attachments []string `json:"attachments,omitempty"`
+	Warning     string   `json:"warning,omitempty"`
+}
+
+// captureDraft is a capture ready to save. Key is what makes two captures
+// the same: their text, URL and files.
+type captureDraft struct {
+	Title        string
+	Content      string
+	Body         string
+	Key          string
+	Source       string
+	SourceURL    string
+	SourceTitle  string
+	SourceAuthor string
+	Journal      string // Journal line for --to journal
+}
+
+// captureExternalID identifies a capture by its key, so capturing the same
+// thing twice finds the first record
+func captureExternalID(key string) (string, string) {
+	sum := sha256.Sum256([]byte(strings.Join(strings.Fields(key), " ")))
+	hash := hex.EncodeToString(sum[:])
+	return "capture_" + hash[:16], hash
+}
+
+// captureTitle is the first line of the text without markdown markers
+func captureTitle(text string) string {
+	line := strings.TrimSpace(text)
+	if i := strings.IndexByte(line, '\n'); i >= 0 {
+		line = strings.TrimSpace(line[:i])
+	}
+	line = strings.Join(strings.Fields(strings.TrimLeft(line, "#>-* ")), " ")
+	if utf8.RuneCountInString(line) > captureTitleLength {
+		runes := []rune(line)
+		line = strings.TrimSpace(string(runes[:captureTitleLength-1])) + "…"
+	}
+	return line
+}
+
+// captureTags cleans tags: no #, no blanks, no repeats
+func captureTags(tags []string) []string {
+	var out []string
+	seen := make(map[string]bool)
+	for _, tag := range tags {
+		tag = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(tag), "#"))
+		if tag == "" || seen[strings.ToLower(tag)] {
+			continue
+		}
+		seen[strings.ToLower(tag)] = true
+		out = append(out, tag)
+	}
+	return out
+}
+
+// captureSource picks the source field's choice for a source name. Sources
+// that aren't a choice, like "cli", are Manual.
+func captureSource(field CollectionField, source string) string {
+	for _, c := range field.Choices {
+		if normalizeKey(c.Label) == normalizeKey(source) || normalizeKey(c.ID) == normalizeKey(source) {
+			return c.Label
+		}
+	}
+	for _, c := range field.Choices {
+		if normalizeKey(c.Label) == "manual" {
+			return c.Label
+		}
+	}
+	return ""
+}
+
+// Attachment types by extension, for files the content sniffer can't tell
+var attachmentExts = map[string]bool{
+	".png": true, ".jpg": true, ".jpeg": true, ".gif": true, ".webp": true,
+	".heic": true, ".pdf": true,
+}
+
+// Types attachments are shown as in the browser; others are downloaded.
+// SVG isn't one: it can carry script.
+var viewableTypes = map[string]bool{
+	"image/png": true, "image/jpeg": true, "image/gif": true, "image/webp": true,
+	"application/pdf": true,
+}
+
+// attachmentType is the type an attachment is served and sent as, from its
+// content rather than its name
+func attachmentType(data []byte) string {
+	if kind := http.DetectContentType(data); viewableTypes[kind] {
+		return kind
+	}
+	return "application/octet-stream"
+}
+
+// isAttachment reports whether a file is kept as an attachment, and fails
+// for files that are neither text nor an image or PDF
+func isAttachment(f CaptureFile) (bool, error) {
+	kind := http.DetectContentType(f.Data)
+	switch {
+	case strings.HasPrefix(kind, "image/"), kind == "application/pdf", attachmentExts[strings.ToLower(filepath.Ext(f.Name))]:
+		return true, nil
+	case strings.HasPrefix(kind, "text/") && utf8.Valid(f.Data):
+		return false, nil
+	}
+	return false, captureError(f.Name + ": only text, image and PDF files can be captured")
+}
+
+func attachmentsDir() string {
+	return filepath.Join(configDir(), "attachments")
+}
+
+var attachmentPath = regexp.MustCompile(`^/attachments/([0-9a-f]{64})/([^/]+)$`)
+
+// saveAttachment keeps a file under its hash, and returns the hash and the
+// URL thymer-bar serves it at
+func (a *App) saveAttachment(f CaptureFile) (string, string, error) {
+	sum := sha256.Sum256(f.Data)
+	hash := hex.EncodeToString(sum[:])
+	name := filepath.Base(f.Name)
+	if name == "." || name == "/" || strings.HasPrefix(name, ".") {
+		name = "attachment" + filepath.Ext(f.Name)
+	}
+
+	dir := filepath.Join(attachmentsDir(), hash)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", "", err
+	}
+	path := filepath.Join(dir, name)
+	if _, err := os.Stat(path); err != nil {
+		if err := os.WriteFile(path, f.Data, 0600); err != nil {
+			return "", "", err
+		}
+	}
+	return hash, fmt.Sprintf("http://127.0.0.1:%d/attachments/%s/%s", a.httpPort, hash, url.PathEscape(name)), nil
+}
+
+// handleAttachment serves a captured file
+func (a *App) handleAttachment(w http.ResponseWriter, r *http.Request) {
+	m := attachmentPath.FindStringSubmatch(r.URL.Path)
+	if m == nil {
+		writeJSONError(w, "Attachment not found", http.StatusNotFound)
+		return
+	}
+	path := filepath.Join(attachmentsDir(), m[1], filepath.Base(m[2]))
+	data, err := os.ReadFile(path)
+	if err != nil {
+		writeJSONError(w, "Attachment not found", http.StatusNotFound)
+		return
+	}
+
+	// Uploads share an origin with the API, so nothing served here may run
+	// script: the type comes from the content, and only images and PDFs are
+	// shown inline
+	kind := attachmentType(data)
+	w.Header().Set("Content-Type", kind)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	if kind != "application/pdf" {
+		// Browsers' PDF viewers don't load in a sandbox
+		w.Header().Set("Content-Security-Policy", "sandbox; default-src 'none'; img-src 'self'")
+	}
+	disposition := "inline"
+	if !viewableTypes[kind] {
+		disposition = "attachment"
+	}
+	w.Header().Set("Content-Disposition", mime.FormatMediaType(disposition, map[string]string{"filename": filepath.Base(path)}))
+	w.Header().Set("Cache-Control", "private, max-age=31536000, immutable")
+	http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(data))
+}
+
+// prepareCapture gathers a capture's text, page and files into a draft.
+// A page that can't be fetched is still captured by its URL, with a warning.
+func (a *App) prepareCapture(req CaptureRequest, result *CaptureResult) (*captureDraft, error) {
+	text := strings.TrimSpace(req.Text)
+	d := &captureDraft{Source: req.Source}
+	keys := []string{text}
+
+	var sections, links, failed []string
+	for _, f := range req.Files {
+		attachment, err := isAttachment(f)
+		if err != nil {
+			return nil, err
+		}
+		if !attachment {
+			content := strings.TrimSpace(string(f.Data))
+			if text == "" && len(req.Files) == 1 {
+				text = content
+				d.SourceTitle = filepath.Base(f.Name)
+			} else {
+				sections = append(sections, "## "+filepath.Base(f.Name)+"\n\n"+content)
+			}
+			keys = append(keys, content)
+			continue
+		}
+		hash, fileURL, err := a.saveAttachment(f)
+		if err != nil {
+			// The rest of the capture is still worth keeping
+			log.Printf("[Capture] Failed to save %s: %v", f.Name, err)
+			failed = append(failed, fmt.Sprintf("%s: %v", filepath.Base(f.Name), err))
+			continue
+		}
+		keys = append(keys, hash)
+		// Thymer keeps a link's text and drops its target, so the URL is
+		// written out
+		links = append(links, filepath.Base(f.Name)+" "+fileURL)
+		result.Attachments = append(result.Attachments, fileURL)
+	}
+	if len(failed) > 0 {
+		result.Warning = "Couldn't keep " + strings.Join(failed, "; ")
+	}
+
+	var page *WebPage
+	if req.URL != "" {
+		u, err := url.Parse(req.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, captureError("url must be an http or https URL")
+		}
+		page, err = FetchPage(req.URL)
+		if err != nil {
+			log.Printf("[Capture] Failed to fetch %s: %v", req.URL, err)
+			warning := "Couldn't fetch the page: " + err.Error()
+			if result.Warning != "" {
+				warning = result.Warning + ". " + warning
+			}
+			result.Warning = warning
+			page = &WebPage{URL: req.URL}
+		}
+		keys = append(keys, req.URL)
+		d.SourceURL = req.URL
+		d.SourceTitle = page.Title
+		d.SourceAuthor = page.Author
+		if d.Source == "" || normalizeKey(d.Source) == "cli" {
+			d.Source = "Web"
+		}
+	}
+
+	if text == "" && page == nil && len(links) == 0 && len(sections) == 0 {
+		if len(failed) > 0 {
+			return nil, fmt.Errorf("couldn't keep %s", strings.Join(failed, "; "))
+		}
+		return nil, captureError("nothing to capture")
+	}
+
+	// Title: the page's, the text's first line, or the first file's name
+	switch {
+	case page != nil && page.Title != "":
+		d.Title = page.Title
+	case captureTitle(text) != "":
+		d.Title = captureTitle(text)
+	case page != nil:
+		d.Title = page.URL
+	case len(req.Files) > 0:
+		d.Title = filepath.Base(req.Files[0].Name)
+	default:
+		d.Title = "Capture"
+	}
+
+	// The field holds a preview of long text; the note has all of it
+	d.Content = truncateText(text, captureChunkSize)
+	if d.Content == "" && page != nil {
+		d.Content = page.Description
+	}
+
+	// The note: what the title had to cut, the page, extra files, attachments
+	var body []string
+	if text != "" && text != d.Title {
+		body = append(body, text)
+	}
+	if page != nil {
+		if page.Description != "" {
+			body = append(body, "> "+page.Description)
+		}
+		body = append(body, fmt.Sprintf("[%s](%s)", d.Title, page.URL))
+		if page.Text != "" {
+			body = append(body, "## From the page", page.Text)
+		}
+	}
+	body = append(body, sections...)
+	if len(links) > 0 {
+		body = append(body, "## Attachments", "- "+strings.Join(links, "\n- "))
+	}
+	d.Body = strings.Join(body, "\n\n")
+	d.Key = strings.Join(keys, "\n")
+
+	// The journal gets the text with links to the page and files
+	journal := text
+	if journal == "" && page == nil {
+		journal = d.Title
+	}
+	if page != nil {
+		links = append([]string{fmt.Sprintf("[%s](%s)", d.Title, page.URL)}, links...)
+	}
+	d.Journal = strings.TrimSpace(journal + " " + strings.Join(links, " "))
+	return d, nil
+}
+
+// Capture saves a quick capture. Captures go to the Captures collection,
+// deduplicated by a hash of their text, URL and files, and with "both" are
+// also linked from today's journal. Without the collection they go to the
+// journal.
+func (a *App) Capture(req CaptureRequest) (*CaptureResult, error) {
+	req.Tags = captureTags(req.Tags)
+	switch req.To {
+	case "":
+		req.To = CaptureToCaptures
+	case CaptureToJournal, CaptureToCaptures, CaptureToBoth:
+	default:
+		return nil, captureError("to must be journal, captures or both")
+	}
+
+	result := &CaptureResult{To: req.To}
+	draft, err := a.prepareCapture(req, result)
+	if err != nil {
+		return nil, err
+	}
+	result.Title = draft.Title
+
+	if req.To != CaptureToJournal {
+		err := a.captureToCollection(draft, req.Tags, result)
+		if err == errNoCaptures {
+			log.Printf("[Capture] Captures collection not found, capturing to journal")
+			result.To = CaptureToJournal
+			result.Fallback = errNoCaptures.Error()
+		} else if err != nil {
+			return nil, err
+		}
+	}
+
+	content := draft.Journal
+	switch {
+	case result.To == CaptureToCaptures:
+		return result, nil
+	case result.To == CaptureToBoth:
+		if result.Duplicate {
+			return result, nil
+		}
+		content = "captured [[" + result.GUID + "]]"
+	case len(req.Tags) > 0:
+		content += " #" + strings.Join(req.Tags, " #")
+	}
+
+	raw, err := a.bridge.ExecuteTool("log_to_journal", map[string]interface{}{"content": content})
+	if err != nil {
+		return nil, err
+	}
+	var journal struct {
+		GUID  string `json:"guid"`
+		Error string `json:"error"`
+	}
+	if err := json.Unmarshal(raw, &journal); err != nil {
+		return nil, fmt.Errorf("invalid log_to_journal result: %w", err)
+	}
+	if journal.Error != "" {
+		return nil, fmt.Errorf("%s", journal.Error)
+	}
+	result.JournalGUID = journal.GUID
+	return result, nil
+}
+
+// captureToCollection creates the capture record, or finds the one made
+// from the same capture before. Long notes are sent in chunks, so no tool
+// call carries more than captureChunkSize of text.
+func (a *App) captureToCollection(d *captureDraft, tags []string, result *CaptureResult) error {
+	collections, err := a.bridge.ExecuteTool("list_collections", map[string]interface{}{})
+	if err != nil {
+		return err
+	}
+	name, _, err := collectionSchema(collections, capturesCollection)
+	if err != nil {
+		return errNoCaptures
+	}
+	fields, err := collectionFields(collections, name)
+	if err != nil {
+		return err
+	}
+	has := make(map[string]CollectionField, len(fields))
+	for _, f := range fields {
+		has[f.ID] = f
+	}
+
+	externalID, hash := captureExternalID(d.Key)
+	record := importRecord{ExternalID: externalID, Title: d.Title, Fields: map[string]interface{}{}}
+	values := map[string]interface{}{
+		"content":       d.Content,
+		"content_hash":  hash,
+		"captured_at":   time.Now().UTC().Format(time.RFC3339),
+		"tags":          strings.Join(tags, ", "),
+		"source_url":    d.SourceURL,
+		"source_title":  d.SourceTitle,
+		"source_author": d.SourceAuthor,
+	}
+	for id, v := range values {
+		if _, ok := has[id]; ok && v != "" {
+			record.Fields[id] = v
+		}
+	}
+	if f, ok := has["source"]; ok {
+		if source := captureSource(f, d.Source); source != "" {
+			record.Fields["source"] = source
+		}
+	}
+	chunks := splitChunks(d.Body, captureChunkSize)
+	if len(chunks) > 0 {
+		record.Body = chunks[0]
+	}
+
+	result.ExternalID = externalID
+	for _, dryRun := range []bool{true, false} {
+		outcomes, err := importBatch(a.bridge, name, []importRecord{record}, dryRun)
+		if err != nil {
+			return err
+		}
+		if len(outcomes) != 1 {
+			return fmt.Errorf("no result from SyncHub")
+		}
+		o := outcomes[0]
+		switch {
+		case o.Action == "failed":
+			return fmt.Errorf("%s", o.Error)
+		case dryRun && o.Action == "updated":
+			// Already captured; keep its original time and tags
+			result.GUID = o.GUID
+			result.Duplicate = true
+			return nil
+		case !dryRun:
+			result.GUID = o.GUID
+		}
+	}
+
+	for i, chunk := range chunks[min(1, len(chunks)):] {
+		raw, err := a.bridge.ExecuteTool("append_to_note", map[string]interface{}{
+			"guid":    result.GUID,
+			"content": chunk,
+		})
+		if err == nil {
+			var r struct {
+				Error string `json:"error"`
+			}
+			if json.Unmarshal(raw, &r) == nil && r.Error != "" {
+				err = fmt.Errorf("%s", r.Error)
+			}
+		}
+		if err != nil {
+			// The record exists; say what's missing rather than fail
+			log.Printf("[Capture] Failed to append part %d of %d to %s: %v", i+2, len(chunks), result.GUID, err)
+			result.Warning = fmt.Sprintf("Only %d of %d parts of the text were saved: %v", i+1, len(chunks), err)
+			break
+		}
+	}
+	a.kickMirror()
+	return nil
+}
+
+// splitChunks cuts markdown into pieces of at most size bytes, between
+// paragraphs where it can, else between lines or runes
+func splitChunks(s string, size int) []string {
+	var chunks []string
+	for len(s) > size {
+		cut := strings.LastIndex(s[:size], "\n\n")
+		if cut <= 0 {
+			cut = strings.LastIndex(s[:size], "\n")
+		}
+		if cut <= 0 {
+			cut = size
+			for cut > 0 && !isRuneStart(s[cut]) {
+				cut--
+			}
+		}
+		if chunk := strings.Trim(s[:cut], "\n"); strings.TrimSpace(chunk) != "" {
+			chunks = append(chunks, chunk)
+		}
+		s = s[cut:]
+	}
+	if s = strings.Trim(s, "\n"); strings.TrimSpace(s) != "" {
+		chunks = append(chunks, s)
+	}
+	return chunks
+}
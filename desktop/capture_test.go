@@ -0,0 +1,67 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var testPNG = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR\x00\x00\x00\x01\x00\x00\x00\x01\x08\x06\x00\x00\x00\x1f\x15\xc4\x89")
+
+func TestPrepareCaptureAttachments(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	a := &App{httpPort: 9847}
+
+	result := &CaptureResult{}
+	d, err := a.prepareCapture(CaptureRequest{
+		Text:  "Whiteboard",
+		Files: []CaptureFile{{Name: "board.png", Data: testPNG}},
+	}, result)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Attachments) != 1 || !strings.HasPrefix(result.Attachments[0], "http://127.0.0.1:9847/attachments/") {
+		t.Fatalf("attachments = %v", result.Attachments)
+	}
+	// The note links thymer-bar's copy and carries no file data
+	if !strings.Contains(d.Body, "board.png "+result.Attachments[0]) || strings.Contains(d.Body, "data:") {
+		t.Errorf("body = %q", d.Body)
+	}
+	if !strings.Contains(d.Journal, result.Attachments[0]) {
+		t.Errorf("journal = %q", d.Journal)
+	}
+}
+
+func TestPrepareCaptureUnsavedAttachment(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	// A file where the attachments directory should be
+	if err := os.MkdirAll(filepath.Join(dir, "thymer-desktop"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(attachmentsDir(), nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	a := &App{httpPort: 9847}
+
+	// The text is still captured, with a warning about the file
+	result := &CaptureResult{}
+	d, err := a.prepareCapture(CaptureRequest{
+		Text:  "Whiteboard",
+		Files: []CaptureFile{{Name: "board.png", Data: testPNG}},
+	}, result)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Title != "Whiteboard" || len(result.Attachments) != 0 || !strings.Contains(result.Warning, "Couldn't keep board.png") {
+		t.Errorf("draft %+v, result %+v", d, result)
+	}
+
+	// With nothing else, the capture fails
+	if _, err := a.prepareCapture(CaptureRequest{
+		Files: []CaptureFile{{Name: "board.png", Data: testPNG}},
+	}, &CaptureResult{}); err == nil {
+		t.Error("capture of an unsaved file alone succeeded")
+	}
+}
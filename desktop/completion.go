@@ -0,0 +1,197 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+const (
+	// completionTTL keeps values around between keystrokes so a client typing
+	// an argument doesn't trigger a browser round trip per character.
+	completionTTL = 30 * time.Second
+
+	// maxCompletions is the most values MCP allows in a single response.
+	maxCompletions = 100
+)
+
+// completionSources maps argument names to the workspace data they accept
+var completionSources = map[string]string{
+	"collection": "collections",
+	"repo":       "repos",
+	"assignee":   "assignees",
+	"author":     "assignees",
+	"person":     "people",
+	"habit":      "habits",
+}
+
+type completionEntry struct {
+	values  []string
+	fetched time.Time
+}
+
+// Completer answers completion/complete requests from live workspace data
+type Completer struct {
+	bridge *Bridge
+	ttl    time.Duration
+
+	mu    sync.Mutex
+	cache map[string]completionEntry
+}
+
+func NewCompleter(bridge *Bridge) *Completer {
+	return &Completer{
+		bridge: bridge,
+		ttl:    completionTTL,
+		cache:  make(map[string]completionEntry),
+	}
+}
+
+// Complete returns values for an argument that start with (or contain) prefix.
+// The prompt reference isn't needed: arguments are matched by name so the
+// same "repo" completion works for every prompt that takes one.
+func (c *Completer) Complete(argName, prefix string) mcp.CompletionResultDetails {
+	source, ok := completionSources[strings.ToLower(argName)]
+	if !ok {
+		return mcp.CompletionResultDetails{Values: []string{}}
+	}
+
+	values := matchCompletions(c.values(source), prefix)
+	details := mcp.CompletionResultDetails{
+		Values: values,
+		Total:  len(values),
+	}
+	if len(values) > maxCompletions {
+		details.Values = values[:maxCompletions]
+		details.HasMore = true
+	}
+	return details
+}
+
+// values returns cached values for a source, refreshing them once stale
+func (c *Completer) values(source string) []string {
+	c.mu.Lock()
+	entry, ok := c.cache[source]
+	c.mu.Unlock()
+
+	if ok && time.Since(entry.fetched) < c.ttl {
+		return entry.values
+	}
+
+	values, err := c.fetch(source)
+	if err != nil {
+		log.Printf("[MCP] Completion fetch for %s failed: %v", source, err)
+		// Serve stale values rather than nothing while the browser is busy
+		return entry.values
+	}
+
+	c.mu.Lock()
+	c.cache[source] = completionEntry{values: values, fetched: time.Now()}
+	c.mu.Unlock()
+	return values
+}
+
+func (c *Completer) fetch(source string) ([]string, error) {
+	switch source {
+	case "collections":
+		result, err := c.bridge.ExecuteTool("list_collections", nil)
+		if err != nil {
+			return nil, err
+		}
+		var resp struct {
+			Collections map[string]interface{} `json:"collections"`
+		}
+		json.Unmarshal(result, &resp)
+		names := make([]string, 0, len(resp.Collections))
+		for name := range resp.Collections {
+			names = append(names, name)
+		}
+		return uniqueSorted(names), nil
+
+	case "repos", "assignees":
+		records, err := c.findRecords("issues_find")
+		if err != nil {
+			return nil, err
+		}
+		var values []string
+		for _, r := range records {
+			if source == "repos" {
+				values = append(values, getString(r, "repo"))
+			} else {
+				values = append(values, getString(r, "assignee"), getString(r, "author"))
+			}
+		}
+		return uniqueSorted(values), nil
+
+	case "people":
+		records, err := c.findRecords("people_find")
+		if err != nil {
+			return nil, err
+		}
+		var values []string
+		for _, r := range records {
+			values = append(values, getString(r, "name"))
+		}
+		return uniqueSorted(values), nil
+
+	case "habits":
+		records, err := c.findRecords("habithub_list")
+		if err != nil {
+			return nil, err
+		}
+		var values []string
+		for _, r := range records {
+			values = append(values, getString(r, "title"))
+		}
+		return uniqueSorted(values), nil
+	}
+	return nil, nil
+}
+
+// findRecords runs a collection tool with a generous limit and returns its rows.
+// Tools that aren't installed come back as an {error} object, which yields no rows.
+func (c *Completer) findRecords(toolName string) ([]map[string]interface{}, error) {
+	result, err := c.bridge.ExecuteTool(toolName, map[string]interface{}{
+		"limit": 1000,
+	})
+	if err != nil {
+		return nil, err
+	}
+	var records []map[string]interface{}
+	json.Unmarshal(result, &records)
+	return records, nil
+}
+
+// matchCompletions returns prefix matches first, then substring matches
+func matchCompletions(values []string, prefix string) []string {
+	needle := strings.ToLower(prefix)
+	var starts, contains []string
+	for _, v := range values {
+		lower := strings.ToLower(v)
+		if strings.HasPrefix(lower, needle) {
+			starts = append(starts, v)
+		} else if strings.Contains(lower, needle) {
+			contains = append(contains, v)
+		}
+	}
+	return append(append([]string{}, starts...), contains...)
+}
+
+func uniqueSorted(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	out := make([]string, 0, len(values))
+	for _, v := range values {
+		if v == "" || seen[v] {
+			continue
+		}
+		seen[v] = true
+		out = append(out, v)
+	}
+	sort.Strings(out)
+	return out
+}
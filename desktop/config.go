@@ -1,18 +1,160 @@
 package main
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"encoding/json"
 	"log"
+	"net/url"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 )
 
 type Config struct {
-	Workspace  string `json:"workspace"`
-	ThymerURLv string `json:"thymerUrl,omitempty"` // From Electron config
-	Token      string `json:"token,omitempty"`
-	path       string
+	Workspace    string            `json:"workspace"`
+	ThymerURLv   string            `json:"thymerUrl,omitempty"` // From Electron config
+	Token        string            `json:"token,omitempty"`
+	LLMModel     string            `json:"llmModel,omitempty"`
+	AutoStartLLM bool              `json:"autoStartLLM,omitempty"`
+	LLM          *LLMConfig        `json:"llm,omitempty"`
+	Schedule     *ScheduleConfig   `json:"schedule,omitempty"`
+	Mirror       *MirrorConfig     `json:"mirror,omitempty"`
+	Embeddings   *EmbeddingsConfig `json:"embeddings,omitempty"`
+	Feeds        *FeedsConfig      `json:"feeds,omitempty"`
+	path         string
+}
+
+// MirrorConfig controls the offline copy of collection records
+type MirrorConfig struct {
+	Disabled bool   `json:"disabled,omitempty"`
+	Interval string `json:"interval,omitempty"` // Snapshot interval, e.g. "15m"
+}
+
+const DefaultMirrorInterval = 15 * time.Minute
+
+// MirrorInterval returns how often to snapshot collections, or 0 when the
+// mirror is disabled
+func (c *Config) MirrorInterval() time.Duration {
+	if c.Mirror == nil {
+		return DefaultMirrorInterval
+	}
+	if c.Mirror.Disabled {
+		return 0
+	}
+	d, err := time.ParseDuration(c.Mirror.Interval)
+	if err != nil || d <= 0 {
+		return DefaultMirrorInterval
+	}
+	if d < time.Minute {
+		return time.Minute
+	}
+	return d
+}
+
+// EmbeddingsConfig enables semantic search over the mirror, with vectors
+// from an OpenAI-compatible /v1/embeddings endpoint
+type EmbeddingsConfig struct {
+	Model    string `json:"model"`              // e.g. "nomic-embed-text"
+	Endpoint string `json:"endpoint,omitempty"` // Base URL; defaults to the local LLM's
+	APIKey   string `json:"apiKey,omitempty"`
+}
+
+// EmbeddingsSettings returns the embeddings config with defaults applied,
+// and whether semantic search is enabled
+func (c *Config) EmbeddingsSettings() (EmbeddingsConfig, bool) {
+	if c.Embeddings == nil || c.Embeddings.Model == "" {
+		return EmbeddingsConfig{}, false
+	}
+	e := *c.Embeddings
+	if e.Endpoint == "" {
+		e.Endpoint = c.LLMSettings().Endpoint
+	}
+	e.Endpoint = apiBaseURL(e.Endpoint)
+	return e, true
+}
+
+// apiBaseURL trims a trailing slash and /v1 from an OpenAI-compatible base
+// URL: the paths added to it (/v1/chat/completions...) carry their own /v1
+func apiBaseURL(endpoint string) string {
+	return strings.TrimSuffix(strings.TrimRight(endpoint, "/"), "/v1")
+}
+
+// FeedsConfig holds the token each Atom feed is read with
+type FeedsConfig struct {
+	Tokens map[string]string `json:"tokens,omitempty"` // Feed name to token
+}
+
+// EnsureFeedTokens gives every named feed a random token if it has none,
+// and reports whether any were added
+func (c *Config) EnsureFeedTokens(names []string) bool {
+	if c.Feeds == nil {
+		c.Feeds = &FeedsConfig{}
+	}
+	if c.Feeds.Tokens == nil {
+		c.Feeds.Tokens = map[string]string{}
+	}
+	added := false
+	for _, name := range names {
+		if c.Feeds.Tokens[name] != "" {
+			continue
+		}
+		b := make([]byte, 16)
+		if _, err := rand.Read(b); err != nil {
+			log.Printf("[Config] Failed to generate feed token: %v", err)
+			continue
+		}
+		c.Feeds.Tokens[name] = hex.EncodeToString(b)
+		added = true
+	}
+	return added
+}
+
+// FeedToken returns a feed's token, or "" if it has none
+func (c *Config) FeedToken(name string) string {
+	if c.Feeds == nil {
+		return ""
+	}
+	return c.Feeds.Tokens[name]
+}
+
+// ScheduleConfig has thymer-bar trigger syncs itself, so they run without
+// a Thymer tab in the foreground
+type ScheduleConfig struct {
+	Plugins    map[string]string `json:"plugins,omitempty"`    // Plugin name (or "all") to cron expression
+	QuietHours string            `json:"quietHours,omitempty"` // e.g. "22:00-07:00"
+}
+
+// LLMConfig describes the local inference server thymer-bar supervises.
+// Command is optional: without it thymer-bar only proxies to Endpoint,
+// e.g. an Ollama instance started elsewhere.
+type LLMConfig struct {
+	Command    string   `json:"command,omitempty"`    // e.g. "llama-server"
+	Args       []string `json:"args,omitempty"`       // "{model}" is replaced with llmModel
+	Endpoint   string   `json:"endpoint,omitempty"`   // OpenAI-compatible base URL
+	HealthPath string   `json:"healthPath,omitempty"` // Polled to decide readiness
+}
+
+const (
+	DefaultLLMEndpoint   = "http://127.0.0.1:8080"
+	DefaultLLMHealthPath = "/v1/models"
+)
+
+// LLMSettings returns the LLM config with defaults applied
+func (c *Config) LLMSettings() LLMConfig {
+	var llm LLMConfig
+	if c.LLM != nil {
+		llm = *c.LLM
+	}
+	if llm.Endpoint == "" {
+		llm.Endpoint = DefaultLLMEndpoint
+	}
+	llm.Endpoint = apiBaseURL(llm.Endpoint)
+	if llm.HealthPath == "" {
+		llm.HealthPath = DefaultLLMHealthPath
+	}
+	return llm
 }
 
 func configDir() string {
@@ -78,3 +220,8 @@ func (c *Config) ThymerURL() string {
 	}
 	return "https://" + c.Workspace
 }
+
+// NoteURL links to a note in the Thymer web app
+func (c *Config) NoteURL(guid string) string {
+	return c.ThymerURL() + "/?open=" + url.QueryEscape(guid)
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseCron(t *testing.T) {
+	valid := []string{
+		"* * * * *",
+		"*/15 * * * *",
+		"0 9-17 * * mon-fri",
+		"5/15 0 1,15 jan-jun 7",
+		"@hourly",
+		"@Weekly",
+	}
+	for _, expr := range valid {
+		if _, err := parseCron(expr); err != nil {
+			t.Errorf("parseCron(%q): %v", expr, err)
+		}
+	}
+
+	invalid := []string{
+		"",
+		"* * * *",
+		"* * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"* * * foo *",
+		"@sometimes",
+	}
+	for _, expr := range invalid {
+		if _, err := parseCron(expr); err == nil {
+			t.Errorf("parseCron(%q) succeeded", expr)
+		}
+	}
+}
+
+func TestParseCronDayFields(t *testing.T) {
+	tests := []struct {
+		expr           string
+		domAny, dowAny bool
+	}{
+		{"0 0 * * *", true, true},
+		{"0 0 1 * *", false, true},
+		{"0 0 * * mon", true, false},
+		{"0 0 */2 * 1", true, false},
+		{"0 0 1-31/2 * *", false, true},
+		{"0 0 13 * fri", false, false},
+	}
+	for _, tt := range tests {
+		c, err := parseCron(tt.expr)
+		if err != nil {
+			t.Fatalf("parseCron(%q): %v", tt.expr, err)
+		}
+		if c.domAny != tt.domAny || c.dowAny != tt.dowAny {
+			t.Errorf("parseCron(%q): domAny=%v dowAny=%v, want %v %v", tt.expr, c.domAny, c.dowAny, tt.domAny, tt.dowAny)
+		}
+	}
+}
+
+func TestCronNext(t *testing.T) {
+	utc := func(s string) time.Time {
+		v, err := time.Parse("2006-01-02 15:04", s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return v
+	}
+	tests := []struct {
+		expr, from, want string
+	}{
+		{"*/15 * * * *", "2026-10-16 10:07", "2026-10-16 10:15"},
+		{"*/15 * * * *", "2026-10-16 10:45", "2026-10-16 11:00"},
+		{"0 9-17 * * mon-fri", "2026-10-16 17:30", "2026-10-19 09:00"}, // Friday to Monday
+		{"@hourly", "2026-12-31 23:59", "2027-01-01 00:00"},
+		{"@monthly", "2026-01-31 12:00", "2026-02-01 00:00"},
+		{"0 0 29 2 *", "2026-03-01 00:00", "2028-02-29 00:00"},
+		{"0 0 31 * *", "2026-04-01 00:00", "2026-05-31 00:00"},
+		{"0 12 * * 7", "2026-10-16 00:00", "2026-10-18 12:00"},   // 7 is Sunday
+		{"0 0 13 * fri", "2026-10-16 12:00", "2026-10-23 00:00"}, // Either day field matches
+		{"0 0 13 * fri", "2026-11-07 12:00", "2026-11-13 00:00"},
+		{"0 0 */2 * mon", "2026-10-16 12:00", "2026-10-19 00:00"}, // */2 is unrestricted
+		{"0 0 1-31/2 * mon", "2026-10-16 12:00", "2026-10-17 00:00"},
+		{"30 8 * jan *", "2026-10-16 12:00", "2027-01-01 08:30"},
+	}
+	for _, tt := range tests {
+		c, err := parseCron(tt.expr)
+		if err != nil {
+			t.Fatalf("parseCron(%q): %v", tt.expr, err)
+		}
+		if got := c.Next(utc(tt.from)); !got.Equal(utc(tt.want)) {
+			t.Errorf("%q after %s = %s, want %s", tt.expr, tt.from, got.Format("2006-01-02 15:04 Mon"), tt.want)
+		}
+	}
+}
+
+func TestCronNextNever(t *testing.T) {
+	for _, expr := range []string{"0 0 30 2 *", "0 0 31 4,6,9,11 *"} {
+		c, err := parseCron(expr)
+		if err != nil {
+			t.Fatalf("parseCron(%q): %v", expr, err)
+		}
+		if got := c.Next(time.Now()); !got.IsZero() {
+			t.Errorf("%q matched %s", expr, got)
+		}
+	}
+}
+
+func TestCronNextDST(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skip("no time zone data:", err)
+	}
+	at := func(s string) time.Time {
+		v, err := time.ParseInLocation("2006-01-02 15:04 MST", s, berlin)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return v
+	}
+	// Clocks go from 02:00 CET to 03:00 CEST on 2026-03-29, and from
+	// 03:00 CEST back to 02:00 CET on 2026-10-25
+	tests := []struct {
+		expr, from, want string
+	}{
+		{"0 * * * *", "2026-03-29 01:30 CET", "2026-03-29 03:00 CEST"},
+		{"30 2 * * *", "2026-03-29 00:00 CET", "2026-03-29 03:30 CEST"}, // Skipped time runs late
+		{"30 2 * * *", "2026-03-29 03:30 CEST", "2026-03-30 02:30 CEST"},
+		{"30 2 * * *", "2026-10-25 00:00 CEST", "2026-10-25 02:30 CEST"},
+		{"30 2 * * *", "2026-10-25 02:30 CEST", "2026-10-26 02:30 CET"}, // Repeated hour runs once
+		{"30 2 * * *", "2026-10-25 02:10 CET", "2026-10-26 02:30 CET"},
+		{"0 9 * * *", "2026-10-24 09:00 CEST", "2026-10-25 09:00 CET"},
+	}
+	for _, tt := range tests {
+		c, err := parseCron(tt.expr)
+		if err != nil {
+			t.Fatalf("parseCron(%q): %v", tt.expr, err)
+		}
+		if got := c.Next(at(tt.from)); !got.Equal(at(tt.want)) {
+			t.Errorf("%q after %s = %s, want %s", tt.expr, tt.from, got.Format("2006-01-02 15:04 MST"), tt.want)
+		}
+	}
+}
@@ -0,0 +1,566 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"log"
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// Vectors live in the mirror database too:
+//
+//	"vectors":         doc key -> text hash (8 bytes) + unit float32s
+//	"vectors_pending": doc keys whose record changed since it was embedded
+//
+// indexRecord queues every changed record, so the Embedder only has to
+// drain the queue; a record whose text hash is unchanged isn't re-embedded.
+var (
+	vectorsBucket        = []byte("vectors")
+	vectorsPendingBucket = []byte("vectors_pending")
+)
+
+const (
+	embedBatchSize   = 32
+	embedInterval    = time.Minute // Retry pending records this often
+	embedTextLimit   = 2000        // Characters of a record embedded
+	embedCandidates  = 50          // Results per ranking fused in hybrid search
+	hybridRRFK       = 60          // Reciprocal rank fusion constant
+	embedHTTPTimeout = 2 * time.Minute
+)
+
+// errNoEmbedding is returned for similar_to records that are unknown or not
+// embedded yet
+var errNoEmbedding = errors.New("no embedding for this record yet")
+
+// EmbeddingsStatus is reported in /api/status
+type EmbeddingsStatus struct {
+	Model     string `json:"model"`
+	Endpoint  string `json:"endpoint"`
+	Embedded  int    `json:"embedded"`
+	Pending   int    `json:"pending"`
+	LastError string `json:"last_error,omitempty"`
+}
+
+// Embedder keeps vectors for the mirrored records and answers semantic
+// searches with them
+type Embedder struct {
+	mirror *Mirror
+	cfg    EmbeddingsConfig
+	client *http.Client
+	wake   chan struct{}
+
+	mu        sync.Mutex
+	lastError string
+}
+
+func NewEmbedder(mirror *Mirror, cfg EmbeddingsConfig) *Embedder {
+	return &Embedder{
+		mirror: mirror,
+		cfg:    cfg,
+		client: &http.Client{Timeout: embedHTTPTimeout},
+		wake:   make(chan struct{}, 1),
+	}
+}
+
+// Run embeds pending records until ctx is done
+func (e *Embedder) Run(ctx context.Context) {
+	if err := e.mirror.resetVectors(e.cfg.Model); err != nil {
+		log.Printf("[Embeddings] Failed to reset vectors: %v", err)
+	}
+
+	ticker := time.NewTicker(embedInterval)
+	defer ticker.Stop()
+	for {
+		e.drain(ctx)
+		select {
+		case <-ticker.C:
+		case <-e.wake:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Kick embeds pending records now, e.g. after a snapshot
+func (e *Embedder) Kick() {
+	select {
+	case e.wake <- struct{}{}:
+	default:
+	}
+}
+
+// drain embeds pending records in batches until none are left or the
+// endpoint fails
+func (e *Embedder) drain(ctx context.Context) {
+	embedded := 0
+	for ctx.Err() == nil {
+		batch, err := e.mirror.pendingEmbeddings(embedBatchSize)
+		if err != nil || len(batch) == 0 {
+			break
+		}
+
+		texts := make([]string, len(batch))
+		for i, p := range batch {
+			texts[i] = p.text
+		}
+		vectors, err := e.Embed(ctx, texts)
+		e.mu.Lock()
+		e.lastError = ""
+		if err != nil {
+			e.lastError = err.Error()
+		}
+		e.mu.Unlock()
+		if err != nil {
+			log.Printf("[Embeddings] %v", err)
+			break
+		}
+
+		if err := e.mirror.putVectors(batch, vectors); err != nil {
+			log.Printf("[Embeddings] Failed to store vectors: %v", err)
+			break
+		}
+		embedded += len(batch)
+	}
+	if embedded > 0 {
+		log.Printf("[Embeddings] Embedded %d record(s)", embedded)
+	}
+}
+
+// Embed returns unit vectors for texts from the embeddings endpoint
+func (e *Embedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	data, _ := json.Marshal(map[string]interface{}{
+		"model": e.cfg.Model,
+		"input": texts,
+	})
+	req, err := http.NewRequestWithContext(ctx, "POST", e.cfg.Endpoint+"/v1/embeddings", bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if e.cfg.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+e.cfg.APIKey)
+	}
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("embeddings endpoint: %w", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("embeddings endpoint returned %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	var result struct {
+		Data []struct {
+			Index     int       `json:"index"`
+			Embedding []float32 `json:"embedding"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("invalid embeddings response: %w", err)
+	}
+	if len(result.Data) != len(texts) {
+		return nil, fmt.Errorf("embeddings endpoint returned %d vectors for %d texts", len(result.Data), len(texts))
+	}
+
+	vectors := make([][]float32, len(texts))
+	for _, d := range result.Data {
+		if d.Index < 0 || d.Index >= len(texts) || len(d.Embedding) == 0 {
+			return nil, fmt.Errorf("invalid embeddings response: bad index %d", d.Index)
+		}
+		vectors[d.Index] = normalizeVector(d.Embedding)
+	}
+	return vectors, nil
+}
+
+func (e *Embedder) Status() EmbeddingsStatus {
+	status := EmbeddingsStatus{Model: e.cfg.Model, Endpoint: e.cfg.Endpoint}
+	e.mirror.db.View(func(tx *bolt.Tx) error {
+		status.Embedded = countKeys(tx.Bucket(vectorsBucket))
+		status.Pending = countKeys(tx.Bucket(vectorsPendingBucket))
+		return nil
+	})
+	e.mu.Lock()
+	status.LastError = e.lastError
+	e.mu.Unlock()
+	return status
+}
+
+// SemanticSearch ranks records by meaning: by similarity to query, or to
+// the record similarTo. With a query the ranking is fused with keyword
+// matches (reciprocal rank fusion), so exact terms still count.
+func (e *Embedder) SemanticSearch(ctx context.Context, query, similarTo, collection string, limit int) ([]SearchResult, error) {
+	if limit <= 0 {
+		limit = defaultSearchLimit
+	}
+	// Catch up on pending records, e.g. after the endpoint was down
+	e.Kick()
+
+	var target []float32
+	var exclude []byte
+	if similarTo != "" {
+		key, vector, err := e.mirror.vectorFor(similarTo)
+		if err != nil {
+			return nil, err
+		}
+		target, exclude = vector, key
+	} else {
+		vectors, err := e.Embed(ctx, []string{query})
+		if err != nil {
+			return nil, err
+		}
+		target = vectors[0]
+	}
+
+	semantic, err := e.mirror.nearest(target, collection, exclude, embedCandidates)
+	if err != nil {
+		return nil, err
+	}
+
+	var keyword []SearchResult
+	var clauses []searchClause
+	if query != "" {
+		// Natural-language queries rarely match every word, so any will do
+		if clauses, err = parseSearchQuery(query); err == nil {
+			keyword, _ = e.mirror.rank(clauses, collection, embedCandidates, true)
+		}
+	}
+
+	results := fuseRankings(semantic, keyword)
+	if len(results) > limit {
+		results = results[:limit]
+	}
+	return results, e.mirror.describe(results, clauses)
+}
+
+// fuseRankings merges rankings by reciprocal rank, keeping each record's
+// similarity from the semantic one
+func fuseRankings(semantic, keyword []SearchResult) []SearchResult {
+	byKey := map[string]*SearchResult{}
+	var order []string
+	add := func(ranking []SearchResult, similarity bool) {
+		for rank, r := range ranking {
+			key := r.Collection + "\x00" + r.GUID
+			fused, ok := byKey[key]
+			if !ok {
+				fused = &SearchResult{GUID: r.GUID, Collection: r.Collection}
+				byKey[key] = fused
+				order = append(order, key)
+			}
+			fused.Score += 1 / float64(hybridRRFK+rank+1)
+			if similarity {
+				fused.Similarity = r.Similarity
+			}
+		}
+	}
+	add(semantic, true)
+	add(keyword, false)
+
+	results := make([]SearchResult, 0, len(order))
+	for _, key := range order {
+		r := *byKey[key]
+		r.Score = math.Round(r.Score*1e5) / 1e5
+		results = append(results, r)
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Score > results[j].Score
+	})
+	return results
+}
+
+// pendingEmbedding is a queued record and the text to embed for it
+type pendingEmbedding struct {
+	key  []byte
+	text string
+	hash uint64
+}
+
+// embeddingText is what gets embedded for a record: title, fields, then
+// the start of its text
+func embeddingText(r mirrorRecord) string {
+	var b strings.Builder
+	b.WriteString(r.Title)
+	keys := make([]string, 0, len(r.Fields))
+	for k := range r.Fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if v := searchText(r.Fields[k]); v != "" {
+			fmt.Fprintf(&b, "\n%s: %s", k, v)
+		}
+	}
+	if body := bodyText(r); body != "" {
+		b.WriteString("\n\n")
+		b.WriteString(body)
+	}
+
+	text := b.String()
+	if utf8.RuneCountInString(text) > embedTextLimit {
+		text = string([]rune(text)[:embedTextLimit])
+	}
+	return text
+}
+
+func textHash(text string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(text))
+	return h.Sum64()
+}
+
+// queueEmbedding marks a record for (re-)embedding. Callers hold a write
+// transaction.
+func queueEmbedding(tx *bolt.Tx, key []byte) error {
+	return tx.Bucket(vectorsPendingBucket).Put(key, []byte{})
+}
+
+// dropEmbedding forgets a removed record's vector
+func dropEmbedding(tx *bolt.Tx, key []byte) error {
+	if err := tx.Bucket(vectorsPendingBucket).Delete(key); err != nil {
+		return err
+	}
+	return tx.Bucket(vectorsBucket).Delete(key)
+}
+
+// mirrorRecordByKey loads a record by its doc key
+func mirrorRecordByKey(tx *bolt.Tx, key []byte) (mirrorRecord, bool) {
+	var r mirrorRecord
+	collection, guid, _ := strings.Cut(string(key), "\x00")
+	bucket := tx.Bucket(mirrorRecordsBucket).Bucket([]byte(collection))
+	if bucket == nil {
+		return r, false
+	}
+	data := bucket.Get([]byte(guid))
+	return r, data != nil && json.Unmarshal(data, &r) == nil
+}
+
+// resetVectors drops all vectors and queues every record when the model
+// changed, since vectors from different models don't compare
+func (m *Mirror) resetVectors(model string) error {
+	return m.db.Update(func(tx *bolt.Tx) error {
+		meta := tx.Bucket(searchMetaBucket)
+		if string(meta.Get([]byte("vectors_model"))) == model {
+			return nil
+		}
+		if err := tx.DeleteBucket(vectorsBucket); err != nil {
+			return err
+		}
+		if _, err := tx.CreateBucket(vectorsBucket); err != nil {
+			return err
+		}
+		err := tx.Bucket(searchDocsBucket).ForEach(func(key, _ []byte) error {
+			return queueEmbedding(tx, key)
+		})
+		if err != nil {
+			return err
+		}
+		return meta.Put([]byte("vectors_model"), []byte(model))
+	})
+}
+
+// pendingEmbeddings returns up to n queued records that need a new vector.
+// Queued records whose text is unchanged since they were embedded are
+// dequeued on the way.
+func (m *Mirror) pendingEmbeddings(n int) ([]pendingEmbedding, error) {
+	var batch []pendingEmbedding
+	var unchanged [][]byte
+	err := m.db.View(func(tx *bolt.Tx) error {
+		vectors := tx.Bucket(vectorsBucket)
+		c := tx.Bucket(vectorsPendingBucket).Cursor()
+		for k, _ := c.First(); k != nil && len(batch) < n; k, _ = c.Next() {
+			key := append([]byte(nil), k...)
+			r, ok := mirrorRecordByKey(tx, key)
+			if !ok {
+				unchanged = append(unchanged, key)
+				continue
+			}
+			text := embeddingText(r)
+			hash := textHash(text)
+			if v := vectors.Get(key); len(v) >= 8 && binary.LittleEndian.Uint64(v) == hash {
+				unchanged = append(unchanged, key)
+				continue
+			}
+			batch = append(batch, pendingEmbedding{key: key, text: text, hash: hash})
+		}
+		return nil
+	})
+	if err != nil || len(unchanged) == 0 {
+		return batch, err
+	}
+	return batch, m.db.Update(func(tx *bolt.Tx) error {
+		for _, key := range unchanged {
+			if err := tx.Bucket(vectorsPendingBucket).Delete(key); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// putVectors stores a batch's vectors. A record that changed while it was
+// being embedded stays queued.
+func (m *Mirror) putVectors(batch []pendingEmbedding, vectors [][]float32) error {
+	return m.db.Update(func(tx *bolt.Tx) error {
+		for i, p := range batch {
+			r, ok := mirrorRecordByKey(tx, p.key)
+			if !ok || textHash(embeddingText(r)) != p.hash {
+				continue
+			}
+			if err := tx.Bucket(vectorsBucket).Put(p.key, encodeVector(p.hash, vectors[i])); err != nil {
+				return err
+			}
+			if err := tx.Bucket(vectorsPendingBucket).Delete(p.key); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// vectorFor returns the stored vector of a record by guid
+func (m *Mirror) vectorFor(guid string) ([]byte, []float32, error) {
+	var key []byte
+	var vector []float32
+	err := m.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(mirrorRecordsBucket).ForEach(func(name, _ []byte) error {
+			k := searchDocKey(string(name), guid)
+			if v := tx.Bucket(vectorsBucket).Get(k); v != nil {
+				key, vector = k, decodeVector(v)
+			}
+			return nil
+		})
+	})
+	if err == nil && vector == nil {
+		err = fmt.Errorf("%s: %w", guid, errNoEmbedding)
+	}
+	return key, vector, err
+}
+
+// nearest returns the n records most similar to target
+func (m *Mirror) nearest(target []float32, collection string, exclude []byte, n int) ([]SearchResult, error) {
+	var results []SearchResult
+	err := m.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(vectorsBucket).ForEach(func(k, v []byte) error {
+			if bytes.Equal(k, exclude) {
+				return nil
+			}
+			name, guid, _ := strings.Cut(string(k), "\x00")
+			if collection != "" && !strings.EqualFold(name, collection) {
+				return nil
+			}
+			vector := decodeVector(v)
+			if len(vector) != len(target) {
+				return nil
+			}
+			var dot float64
+			for i := range vector {
+				dot += float64(vector[i]) * float64(target[i])
+			}
+			results = append(results, SearchResult{
+				GUID:       guid,
+				Collection: name,
+				Similarity: math.Round(dot*1000) / 1000,
+			})
+			return nil
+		})
+	})
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].Similarity > results[j].Similarity
+	})
+	if len(results) > n {
+		results = results[:n]
+	}
+	return results, err
+}
+
+func normalizeVector(v []float32) []float32 {
+	var sum float64
+	for _, x := range v {
+		sum += float64(x) * float64(x)
+	}
+	if sum == 0 {
+		return v
+	}
+	norm := float32(math.Sqrt(sum))
+	out := make([]float32, len(v))
+	for i, x := range v {
+		out[i] = x / norm
+	}
+	return out
+}
+
+func encodeVector(hash uint64, v []float32) []byte {
+	data := make([]byte, 8+4*len(v))
+	binary.LittleEndian.PutUint64(data, hash)
+	for i, x := range v {
+		binary.LittleEndian.PutUint32(data[8+4*i:], math.Float32bits(x))
+	}
+	return data
+}
+
+func decodeVector(data []byte) []float32 {
+	if len(data) < 8 {
+		return nil
+	}
+	v := make([]float32, (len(data)-8)/4)
+	for i := range v {
+		v[i] = math.Float32frombits(binary.LittleEndian.Uint32(data[8+4*i:]))
+	}
+	return v
+}
+
+var semanticSearchTool = Tool{
+	Name:        "semantic_search",
+	Description: "Find records by meaning rather than exact words, e.g. captures and notes related to an idea, using Thymer Desktop's local embeddings. Keyword matches still rank high. Give a query, or similar_to with a record GUID to find related records.",
+	Parameters: map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"query":      map[string]interface{}{"type": "string", "description": "What to look for, in natural language"},
+			"similar_to": map[string]interface{}{"type": "string", "description": "GUID of a record to find related records for"},
+			"collection": map[string]interface{}{"type": "string", "description": "Only search this collection"},
+			"limit":      map[string]interface{}{"type": "number", "description": "Maximum results (default 10)"},
+		},
+	},
+}
+
+// runSemanticSearch is the semantic_search tool
+func (e *Embedder) runSemanticSearch(args map[string]interface{}) (interface{}, error) {
+	query, _ := args["query"].(string)
+	similarTo, _ := args["similar_to"].(string)
+	collection, _ := args["collection"].(string)
+	limit := defaultSearchLimit
+	if n, ok := args["limit"].(float64); ok && n > 0 {
+		limit = int(n)
+	}
+	if query == "" && similarTo == "" {
+		return map[string]interface{}{"error": "query or similar_to required"}, nil
+	}
+
+	results, err := e.SemanticSearch(context.Background(), query, similarTo, collection, limit)
+	if err != nil {
+		return map[string]interface{}{"error": err.Error()}, nil
+	}
+	result := map[string]interface{}{"results": results}
+	if query != "" {
+		result["query"] = query
+	}
+	if similarTo != "" {
+		result["similar_to"] = similarTo
+	}
+	return result, nil
+}
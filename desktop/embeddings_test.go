@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestEmbed(t *testing.T) {
+	var gotPath string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		var req struct {
+			Input []string `json:"input"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		data := []map[string]interface{}{}
+		for i := len(req.Input) - 1; i >= 0; i-- {
+			data = append(data, map[string]interface{}{"index": i, "embedding": []float32{3, float32(4 * i)}})
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"data": data})
+	}))
+	defer srv.Close()
+
+	cfg, ok := (&Config{Embeddings: &EmbeddingsConfig{Model: "test", Endpoint: srv.URL + "/v1/"}}).EmbeddingsSettings()
+	if !ok {
+		t.Fatal("embeddings not enabled")
+	}
+	vectors, err := NewEmbedder(nil, cfg).Embed(context.Background(), []string{"a", "b"})
+	if err != nil {
+		t.Fatalf("Embed: %v", err)
+	}
+	if gotPath != "/v1/embeddings" {
+		t.Errorf("path = %q", gotPath)
+	}
+
+	// Vectors come back in input order, scaled to unit length
+	want := [][]float32{{1, 0}, {0.6, 0.8}}
+	for i := range want {
+		for j := range want[i] {
+			if math.Abs(float64(vectors[i][j]-want[i][j])) > 1e-6 {
+				t.Fatalf("vectors = %v, want %v", vectors, want)
+			}
+		}
+	}
+}
+
+func TestDataURLsSkipped(t *testing.T) {
+	body := "board.png (image/png)\n\ndata:image/png;base64,iVBORw0KGgoAAAANSUhEUgAAAAEAAAAB\n\nSketch of the plan"
+	r := mirrorRecord{GUID: "g", Title: "Whiteboard", Body: &body}
+
+	for name, text := range map[string]string{"embedding": embeddingText(r), "index": recordTexts(r)["body"]} {
+		if strings.Contains(text, "base64") || strings.Contains(text, "iVBOR") || !strings.Contains(text, "Sketch of the plan") {
+			t.Errorf("%s text = %q", name, text)
+		}
+	}
+}
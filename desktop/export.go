@@ -0,0 +1,617 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Export formats
+const (
+	ExportCSV      = "csv"
+	ExportJSON     = "json"
+	ExportNDJSON   = "ndjson"
+	ExportMarkdown = "markdown" // A zip of one file per record
+	ExportICS      = "ics"
+)
+
+var exportFormats = []string{ExportCSV, ExportJSON, ExportNDJSON, ExportMarkdown, ExportICS}
+
+// exportPageSize is how many records each get_collection_records call returns
+const exportPageSize = 100
+
+// errNoEventDate is returned when an ics export has no date field to use
+var errNoEventDate = errors.New("collection has no date field to export as events")
+
+// CollectionField is a field's type as list_collections reports it
+type CollectionField struct {
+	ID      string        `json:"id"`
+	Label   string        `json:"label"`
+	Type    string        `json:"type"`
+	Choices []FieldChoice `json:"choices,omitempty"`
+	Many    bool          `json:"many,omitempty"` // Holds several values
+}
+
+// FieldChoice is one option of a choice field
+type FieldChoice struct {
+	ID    string `json:"id"`
+	Label string `json:"label"`
+}
+
+// Field types named by schemas of collections without registered tools
+var knownFieldTypes = map[string]bool{
+	"text": true, "number": true, "datetime": true, "choice": true, "url": true,
+}
+
+// collectionFields returns a collection's fields from a list_collections
+// result. When SyncHub couldn't read the collection's configuration, types
+// are guessed from the schema: "A | B" descriptions list choices, and
+// *_at fields or ones described as dates hold dates.
+func collectionFields(listResult json.RawMessage, name string) ([]CollectionField, error) {
+	var list struct {
+		Collections map[string]struct {
+			Schema map[string]interface{} `json:"schema"`
+			Fields []CollectionField      `json:"fields"`
+		} `json:"collections"`
+	}
+	if err := json.Unmarshal(listResult, &list); err != nil {
+		return nil, fmt.Errorf("invalid list_collections result: %w", err)
+	}
+	col, ok := list.Collections[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown collection %q", name)
+	}
+
+	var fields []CollectionField
+	if len(col.Fields) > 0 {
+		fields = col.Fields
+	} else {
+		ids := make([]string, 0, len(col.Schema))
+		for id := range col.Schema {
+			ids = append(ids, id)
+		}
+		sort.Strings(ids)
+		for _, id := range ids {
+			fields = append(fields, inferField(id, fmt.Sprint(col.Schema[id])))
+		}
+	}
+
+	// Title is its own column, banners aren't data
+	kept := fields[:0]
+	for _, f := range fields {
+		if normalizeKey(f.ID) != "title" && f.Type != "banner" {
+			kept = append(kept, f)
+		}
+	}
+	return kept, nil
+}
+
+func inferField(id, desc string) CollectionField {
+	f := CollectionField{ID: id, Label: fieldLabel(id), Type: "text"}
+	lower := strings.ToLower(desc)
+	switch {
+	case knownFieldTypes[lower]:
+		f.Type = lower
+	case strings.Contains(desc, " | "):
+		f.Type = "choice"
+		for _, label := range strings.Split(desc, " | ") {
+			// "Upcoming | Past (auto-updated)"
+			if i := strings.Index(label, " ("); i > 0 {
+				label = label[:i]
+			}
+			label = strings.TrimSpace(label)
+			f.Choices = append(f.Choices, FieldChoice{ID: normalizeKey(label), Label: label})
+		}
+	case strings.HasSuffix(id, "_at") || strings.Contains(lower, "date"):
+		f.Type = "datetime"
+	}
+	return f
+}
+
+// fieldLabel turns "source_title" into "Source Title"
+func fieldLabel(id string) string {
+	words := strings.Fields(strings.NewReplacer("_", " ", "-", " ").Replace(id))
+	for i, w := range words {
+		r := []rune(w)
+		r[0] = unicode.ToUpper(r[0])
+		words[i] = string(r)
+	}
+	return strings.Join(words, " ")
+}
+
+// ExportRecord is a record with its values converted by field type
+type ExportRecord struct {
+	GUID   string
+	Title  string
+	Values map[string]interface{} // By field id
+	Body   string
+}
+
+// Export is a collection's records ready to write in an export format
+type Export struct {
+	Collection string
+	Fields     []CollectionField // Columns after guid and title
+	Records    []ExportRecord
+}
+
+// NewExport converts records by the collection's field types: choice ids
+// become labels, dates RFC 3339, and multi-value fields lists. Fields the
+// schema doesn't know are kept as text.
+func NewExport(collection string, fields []CollectionField, records []mirrorRecord) *Export {
+	e := &Export{Collection: collection, Fields: append([]CollectionField(nil), fields...)}
+
+	byKey := make(map[string]int, len(fields))
+	for i, f := range e.Fields {
+		byKey[normalizeKey(f.ID)] = i
+		byKey[normalizeKey(f.Label)] = i
+	}
+	var extra []string
+	for _, r := range records {
+		for key := range r.Fields {
+			k := normalizeKey(key)
+			if _, ok := byKey[k]; !ok && k != "title" {
+				byKey[k] = -1
+				extra = append(extra, key)
+			}
+		}
+	}
+	sort.Strings(extra)
+	for _, key := range extra {
+		byKey[normalizeKey(key)] = len(e.Fields)
+		e.Fields = append(e.Fields, CollectionField{ID: key, Label: fieldLabel(key), Type: "text"})
+	}
+
+	for _, r := range records {
+		rec := ExportRecord{GUID: r.GUID, Title: r.Title, Values: make(map[string]interface{})}
+		if r.Body != nil {
+			rec.Body = *r.Body
+		}
+		for key, v := range r.Fields {
+			i, ok := byKey[normalizeKey(key)]
+			if !ok || i < 0 || v == nil {
+				continue
+			}
+			f := e.Fields[i]
+			rec.Values[f.ID] = exportValue(f, v)
+		}
+		e.Records = append(e.Records, rec)
+	}
+	return e
+}
+
+func exportValue(f CollectionField, v interface{}) interface{} {
+	single := f
+	single.Many = false
+	if list, ok := v.([]interface{}); ok {
+		values := make([]interface{}, 0, len(list))
+		for _, item := range list {
+			values = append(values, exportValue(single, item))
+		}
+		return values
+	}
+
+	s, ok := v.(string)
+	if !ok {
+		return v
+	}
+	if f.Many {
+		var values []interface{}
+		for _, item := range strings.Split(s, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				values = append(values, exportValue(single, item))
+			}
+		}
+		return values
+	}
+
+	switch f.Type {
+	case "choice":
+		for _, c := range f.Choices {
+			if c.ID == s || normalizeKey(c.ID) == normalizeKey(s) || normalizeKey(c.Label) == normalizeKey(s) {
+				return c.Label
+			}
+		}
+	case "datetime":
+		if t, ok := parseQueryTime(s); ok && len(s) > len("2006-01-02") {
+			return t.Format(time.RFC3339)
+		}
+	}
+	return s
+}
+
+// Write writes the export in format
+func (e *Export) Write(w io.Writer, format string) error {
+	switch format {
+	case ExportCSV:
+		return e.WriteCSV(w)
+	case ExportJSON:
+		return e.WriteJSON(w)
+	case ExportNDJSON:
+		return e.WriteNDJSON(w)
+	case ExportMarkdown:
+		return e.WriteMarkdown(w)
+	case ExportICS:
+		return e.WriteICS(w)
+	}
+	return fmt.Errorf("unknown format %q (use %s)", format, strings.Join(exportFormats, ", "))
+}
+
+// exportContentType is the media type and file extension of a format
+func exportContentType(format string) (string, string) {
+	switch format {
+	case ExportCSV:
+		return "text/csv; charset=utf-8", "csv"
+	case ExportNDJSON:
+		return "application/x-ndjson", "ndjson"
+	case ExportMarkdown:
+		return "application/zip", "zip"
+	case ExportICS:
+		return "text/calendar; charset=utf-8", "ics"
+	}
+	return "application/json", "json"
+}
+
+// object is a record as json and ndjson write it
+func (e *Export) object(r ExportRecord) map[string]interface{} {
+	obj := map[string]interface{}{"guid": r.GUID, "title": r.Title}
+	for k, v := range r.Values {
+		obj[k] = v
+	}
+	if r.Body != "" {
+		obj["body"] = r.Body
+	}
+	return obj
+}
+
+// WriteCSV writes a header of guid, title and field ids, then a row per
+// record. Multi-value fields are joined with "; ".
+func (e *Export) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	header := []string{"guid", "title"}
+	for _, f := range e.Fields {
+		header = append(header, f.ID)
+	}
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+	for _, r := range e.Records {
+		row := []string{r.GUID, r.Title}
+		for _, f := range e.Fields {
+			row = append(row, csvCell(r.Values[f.ID]))
+		}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func csvCell(v interface{}) string {
+	switch val := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return val
+	case float64:
+		return strconv.FormatFloat(val, 'f', -1, 64)
+	case []interface{}:
+		parts := make([]string, len(val))
+		for i, item := range val {
+			parts[i] = csvCell(item)
+		}
+		return strings.Join(parts, "; ")
+	}
+	data, _ := json.Marshal(v)
+	return string(data)
+}
+
+// WriteJSON writes one array of records
+func (e *Export) WriteJSON(w io.Writer) error {
+	objects := make([]map[string]interface{}, 0, len(e.Records))
+	for _, r := range e.Records {
+		objects = append(objects, e.object(r))
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(objects)
+}
+
+// WriteNDJSON writes a record per line
+func (e *Export) WriteNDJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	for _, r := range e.Records {
+		if err := enc.Encode(e.object(r)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteMarkdown writes a zip with a Markdown file per record: YAML front
+// matter with the guid, title and fields, then the record's text
+func (e *Export) WriteMarkdown(w io.Writer) error {
+	zw := zip.NewWriter(w)
+	used := make(map[string]bool)
+	for _, r := range e.Records {
+		name := markdownFileName(r, used)
+		f, err := zw.Create(name)
+		if err != nil {
+			return err
+		}
+		var b bytes.Buffer
+		b.WriteString("---\n")
+		fmt.Fprintf(&b, "guid: %s\n", yamlValue(r.GUID))
+		fmt.Fprintf(&b, "title: %s\n", yamlValue(r.Title))
+		fmt.Fprintf(&b, "collection: %s\n", yamlValue(e.Collection))
+		for _, field := range e.Fields {
+			if v, ok := r.Values[field.ID]; ok {
+				fmt.Fprintf(&b, "%s: %s\n", field.ID, yamlValue(v))
+			}
+		}
+		b.WriteString("---\n")
+		if body := strings.TrimSpace(r.Body); body != "" {
+			b.WriteString("\n" + body + "\n")
+		}
+		if _, err := f.Write(b.Bytes()); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+// markdownFileName names a record's file after its title, adding the guid
+// when another record already has that name
+func markdownFileName(r ExportRecord, used map[string]bool) string {
+	var slug strings.Builder
+	dash := false
+	for _, c := range strings.ToLower(r.Title) {
+		if unicode.IsLetter(c) || unicode.IsDigit(c) {
+			slug.WriteRune(c)
+			dash = false
+		} else if !dash && slug.Len() > 0 {
+			slug.WriteByte('-')
+			dash = true
+		}
+	}
+	base := strings.TrimSuffix(slug.String(), "-")
+	if len(base) > 80 {
+		cut := 80
+		for cut > 0 && !isRuneStart(base[cut]) {
+			cut--
+		}
+		base = strings.TrimSuffix(base[:cut], "-")
+	}
+	name := base + ".md"
+	if base == "" || used[name] {
+		name = strings.TrimPrefix(base+"-"+r.GUID, "-") + ".md"
+	}
+	used[name] = true
+	return name
+}
+
+// yamlValue renders a front matter value, quoting strings YAML would read
+// as something else
+func yamlValue(v interface{}) string {
+	switch val := v.(type) {
+	case nil:
+		return "null"
+	case string:
+		if _, ok := parseQueryTime(val); !ok && yamlNeedsQuotes(val) {
+			return strconv.Quote(val)
+		}
+		return val
+	case float64:
+		return strconv.FormatFloat(val, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(val)
+	case []interface{}:
+		items := make([]string, len(val))
+		for i, item := range val {
+			items[i] = yamlValue(item)
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	}
+	data, _ := json.Marshal(v)
+	return string(data)
+}
+
+func yamlNeedsQuotes(s string) bool {
+	if s == "" || strings.TrimSpace(s) != s {
+		return true
+	}
+	switch strings.ToLower(s) {
+	case "true", "false", "yes", "no", "on", "off", "null", "~":
+		return true
+	}
+	if _, err := strconv.ParseFloat(s, 64); err == nil {
+		return true
+	}
+	if strings.ContainsAny(s[:1], "-?:[]{}#&*!|>'\"%@`") {
+		return true
+	}
+	// Commas and brackets end items of a [list]
+	return strings.Contains(s, ": ") || strings.Contains(s, " #") || strings.HasSuffix(s, ":") ||
+		strings.ContainsAny(s, ",[]{}\n\t")
+}
+
+// eventDateField picks the date field events start at. created_at and
+// updated_at style timestamps don't make events.
+func (e *Export) eventDateField() (CollectionField, bool) {
+	for _, f := range e.Fields {
+		if f.Type == "datetime" && !strings.HasSuffix(f.ID, "_at") {
+			return f, true
+		}
+	}
+	return CollectionField{}, false
+}
+
+// WriteICS writes an iCalendar feed with an event per record that has a
+// date. Records marked all day, or with only a date, become all-day events.
+func (e *Export) WriteICS(w io.Writer) error {
+	dateField, ok := e.eventDateField()
+	if !ok {
+		return fmt.Errorf("%s: %w", e.Collection, errNoEventDate)
+	}
+
+	var events []icsEvent
+	for _, r := range e.Records {
+		s, _ := r.Values[dateField.ID].(string)
+		start, ok := parseQueryTime(s)
+		if !ok {
+			continue
+		}
+		event := icsEvent{
+			UID:         r.GUID + "@thymer",
+			Summary:     r.Title,
+			Start:       start,
+			AllDay:      len(s) == len("2006-01-02") || normalizeKey(fmt.Sprint(r.Values["all_day"])) == "yes",
+			Description: strings.TrimSpace(r.Body),
+		}
+		if event.AllDay && len(s) > len("2006-01-02") {
+			event.Start = start.Local()
+		}
+		event.Location, _ = r.Values["location"].(string)
+		for _, key := range []string{"url", "meet_link", "source_url"} {
+			if v, ok := r.Values[key].(string); ok && v != "" {
+				event.URL = v
+				break
+			}
+		}
+		event.Status, _ = r.Values["status"].(string)
+		events = append(events, event)
+	}
+	return writeICS(w, e.Collection, events, 0)
+}
+
+// recordsPage is a get_collection_records result
+type recordsPage struct {
+	Total      int            `json:"total"`
+	SnapshotID string         `json:"snapshot_id"`
+	Records    []mirrorRecord `json:"records"`
+	Error      string         `json:"error"`
+}
+
+// decodeRecords reads a get_collection_records result
+func decodeRecords(recordsResult json.RawMessage) (*recordsPage, error) {
+	var page recordsPage
+	if err := json.Unmarshal(recordsResult, &page); err != nil {
+		return nil, fmt.Errorf("invalid records result: %w", err)
+	}
+	if page.Error != "" {
+		return nil, fmt.Errorf("%s", page.Error)
+	}
+	return &page, nil
+}
+
+// ExportRecords fetches every record of a collection with its text. A
+// collection whose _find tool pages is paged through that; any other is
+// paged through get_collection_records.
+func ExportRecords(bridge *Bridge, collection string) ([]mirrorRecord, error) {
+	if tool, ok := findTool(bridge, collection); ok && toolParam(tool, "offset") {
+		return exportByFind(bridge, collection, tool.Name)
+	}
+	return exportSnapshot(bridge, collection)
+}
+
+// exportByFind pages through _find's guids and fetches each page's records
+// by guid. _find pages the live list, so a record that moves to a later
+// page while the export runs is kept once; one created meanwhile may be
+// left out.
+func exportByFind(bridge *Bridge, collection, toolName string) ([]mirrorRecord, error) {
+	var records []mirrorRecord
+	seen := make(map[string]bool)
+	for offset := 0; ; offset += exportPageSize {
+		found, err := bridge.ExecuteTool(toolName, map[string]interface{}{
+			"limit":  exportPageSize,
+			"offset": offset,
+		})
+		if err != nil {
+			return nil, err
+		}
+		var page []struct {
+			GUID string `json:"guid"`
+		}
+		if err := json.Unmarshal(found, &page); err != nil {
+			var failed struct {
+				Error string `json:"error"`
+			}
+			if json.Unmarshal(found, &failed) == nil && failed.Error != "" {
+				return nil, fmt.Errorf("%s", failed.Error)
+			}
+			return nil, fmt.Errorf("invalid %s result: %w", toolName, err)
+		}
+
+		var guids []string
+		for _, r := range page {
+			if r.GUID != "" && !seen[r.GUID] {
+				seen[r.GUID] = true
+				guids = append(guids, r.GUID)
+			}
+		}
+		if len(guids) > 0 {
+			result, err := bridge.ExecuteTool("get_collection_records", map[string]interface{}{
+				"collection": collection,
+				"guids":      guids,
+				"bodies":     true,
+			})
+			if err != nil {
+				return nil, err
+			}
+			fetched, err := decodeRecords(result)
+			if err != nil {
+				return nil, err
+			}
+			records = append(records, fetched.Records...)
+		}
+		if len(page) < exportPageSize {
+			return records, nil
+		}
+	}
+}
+
+// exportSnapshot pages through get_collection_records. The pages come from
+// one snapshot of the collection's records, so records added or deleted
+// meanwhile aren't skipped or exported twice.
+func exportSnapshot(bridge *Bridge, collection string) ([]mirrorRecord, error) {
+	var records []mirrorRecord
+	args := map[string]interface{}{
+		"collection": collection,
+		"limit":      exportPageSize,
+		"bodies":     true,
+		"snapshot":   true,
+	}
+	for offset := 0; ; offset += exportPageSize {
+		args["offset"] = offset
+		result, err := bridge.ExecuteTool("get_collection_records", args)
+		if err != nil {
+			return nil, err
+		}
+		page, err := decodeRecords(result)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, page.Records...)
+		if page.SnapshotID == "" {
+			// An older SyncHub pages the live list
+			if len(page.Records) < exportPageSize || len(records) >= page.Total {
+				return records, nil
+			}
+			continue
+		}
+		if offset+exportPageSize >= page.Total {
+			return records, nil
+		}
+		args["snapshot_id"] = page.SnapshotID
+		delete(args, "snapshot")
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"maps"
+	"testing"
+)
+
+func TestExportRecordsByFind(t *testing.T) {
+	guids := guidList("P", 250)
+	var calls []map[string]interface{}
+
+	b := NewBridge(0)
+	b.AddLocalTool(Tool{Name: "people_find", Parameters: map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"limit":  map[string]interface{}{"type": "number"},
+			"offset": map[string]interface{}{"type": "number"},
+		},
+	}}, func(args map[string]interface{}) (interface{}, error) {
+		calls = append(calls, maps.Clone(args))
+		offset, limit := args["offset"].(int), args["limit"].(int)
+		var found []map[string]interface{}
+		for _, guid := range guids[min(offset, len(guids)):min(offset+limit, len(guids))] {
+			found = append(found, map[string]interface{}{"guid": guid})
+		}
+		// A record added at the front shifts the later pages by one
+		if offset == 0 {
+			guids = append([]string{"P-new"}, guids...)
+		}
+		return found, nil
+	})
+	b.AddLocalTool(Tool{Name: "get_collection_records"}, func(args map[string]interface{}) (interface{}, error) {
+		calls = append(calls, maps.Clone(args))
+		var records []mirrorRecord
+		for _, guid := range args["guids"].([]string) {
+			body := "text of " + guid
+			records = append(records, mirrorRecord{GUID: guid, Title: guid, Body: &body})
+		}
+		return map[string]interface{}{"collection": "People", "records": records}, nil
+	})
+
+	records, err := ExportRecords(b, "People")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The shifted record isn't exported twice
+	if len(records) != 250 || records[249].GUID != "P0249" || records[0].Body == nil {
+		t.Fatalf("%d records, last %+v", len(records), records[len(records)-1])
+	}
+	for _, args := range calls {
+		if args["snapshot"] != nil || (args["guids"] != nil && args["bodies"] != true) {
+			t.Errorf("call %v", args)
+		}
+	}
+	if len(calls) != 6 {
+		t.Errorf("%d calls, want 6", len(calls))
+	}
+}
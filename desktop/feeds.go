@@ -0,0 +1,392 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultFeedLimit = 50
+	maxFeedLimit     = 500
+)
+
+// feed is an Atom feed over a collection tool. Params are the query
+// parameters passed on to the tool as filters.
+type feed struct {
+	Name    string
+	Title   string
+	Tool    string
+	Args    map[string]interface{} // Always sent
+	Params  []string
+	Entries func(c *Config, result json.RawMessage) ([]atomEntry, error)
+}
+
+var feeds = []feed{
+	{
+		Name:    "captures",
+		Title:   "Thymer Captures",
+		Tool:    "captures_recent",
+		Params:  []string{"source", "tag"},
+		Entries: captureEntries,
+	},
+	{
+		Name:    "issues",
+		Title:   "Thymer Issues",
+		Tool:    "issues_find",
+		Args:    map[string]interface{}{"sort": "updated"},
+		Params:  []string{"repo", "state", "type", "assignee"},
+		Entries: issueEntries,
+	},
+}
+
+// calendarFeed names the /calendar.ics token, kept with the Atom feeds'
+const calendarFeed = "calendar"
+
+// feedNames lists the feeds that need tokens
+func feedNames() []string {
+	names := make([]string, len(feeds))
+	for i, f := range feeds {
+		names[i] = f.Name
+	}
+	return append(names, calendarFeed)
+}
+
+// checkFeedToken reports whether the request's token query parameter
+// matches the named feed's token from the config, answering 401 if not
+func (a *App) checkFeedToken(w http.ResponseWriter, r *http.Request, name string) bool {
+	token := a.config.FeedToken(name)
+	if token == "" || subtle.ConstantTimeCompare([]byte(r.URL.Query().Get("token")), []byte(token)) != 1 {
+		writeJSONError(w, "Invalid feed token", http.StatusUnauthorized)
+		return false
+	}
+	return true
+}
+
+func findFeed(name string) (feed, bool) {
+	for _, f := range feeds {
+		if f.Name == name {
+			return f, true
+		}
+	}
+	return feed{}, false
+}
+
+type atomFeed struct {
+	XMLName   xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	Title     string      `xml:"title"`
+	ID        string      `xml:"id"`
+	Updated   string      `xml:"updated"`
+	Generator string      `xml:"generator"`
+	Links     []atomLink  `xml:"link"`
+	Entries   []atomEntry `xml:"entry"`
+}
+
+type atomEntry struct {
+	Title      string         `xml:"title"`
+	ID         string         `xml:"id"`
+	Updated    time.Time      `xml:"-"`
+	UpdatedStr string         `xml:"updated"`
+	Author     *atomPerson    `xml:"author,omitempty"`
+	Links      []atomLink     `xml:"link"`
+	Categories []atomCategory `xml:"category"`
+	Summary    string         `xml:"summary,omitempty"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+}
+
+type atomPerson struct {
+	Name string `xml:"name"`
+}
+
+type atomCategory struct {
+	Term string `xml:"term,attr"`
+}
+
+// decodeToolList reads a tool result that is a list, or {error} when the
+// tool failed
+func decodeToolList(result json.RawMessage, v interface{}) error {
+	if trimmed := bytes.TrimSpace(result); len(trimmed) > 0 && trimmed[0] == '[' {
+		return json.Unmarshal(trimmed, v)
+	}
+	var e struct {
+		Error string `json:"error"`
+	}
+	if err := json.Unmarshal(result, &e); err == nil && e.Error != "" {
+		return fmt.Errorf("%s", e.Error)
+	}
+	return fmt.Errorf("unexpected result: %.200s", result)
+}
+
+// entryLinks links an entry to its source, falling back to the note, and
+// always to the note in Thymer
+func entryLinks(c *Config, guid, source string) []atomLink {
+	note := c.NoteURL(guid)
+	if source == "" {
+		return []atomLink{{Href: note, Rel: "alternate"}}
+	}
+	return []atomLink{{Href: source, Rel: "alternate"}, {Href: note, Rel: "related"}}
+}
+
+func captureEntries(c *Config, result json.RawMessage) ([]atomEntry, error) {
+	var captures []struct {
+		GUID         string   `json:"guid"`
+		Title        string   `json:"title"`
+		Content      string   `json:"content"`
+		Source       string   `json:"source"`
+		SourceTitle  string   `json:"source_title"`
+		SourceAuthor string   `json:"source_author"`
+		SourceURL    string   `json:"source_url"`
+		Tags         []string `json:"tags"`
+		CapturedAt   string   `json:"captured_at"`
+	}
+	if err := decodeToolList(result, &captures); err != nil {
+		return nil, err
+	}
+
+	entries := make([]atomEntry, 0, len(captures))
+	for _, cp := range captures {
+		e := atomEntry{
+			Title:   cp.Title,
+			ID:      "urn:thymer:" + cp.GUID,
+			Links:   entryLinks(c, cp.GUID, cp.SourceURL),
+			Summary: cp.Content,
+		}
+		e.Updated, _ = time.Parse(time.RFC3339Nano, cp.CapturedAt)
+		if cp.SourceTitle != "" && cp.SourceTitle != cp.Title {
+			e.Title = cp.Title + " (" + cp.SourceTitle + ")"
+		}
+		if cp.SourceAuthor != "" {
+			e.Author = &atomPerson{Name: cp.SourceAuthor}
+		}
+		if cp.Source != "" {
+			e.Categories = append(e.Categories, atomCategory{Term: cp.Source})
+		}
+		for _, tag := range cp.Tags {
+			e.Categories = append(e.Categories, atomCategory{Term: tag})
+		}
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+func issueEntries(c *Config, result json.RawMessage) ([]atomEntry, error) {
+	var issues []struct {
+		GUID      string  `json:"guid"`
+		Title     string  `json:"title"`
+		State     string  `json:"state"`
+		Type      string  `json:"type"`
+		Repo      string  `json:"repo"`
+		Number    float64 `json:"number"`
+		Assignee  string  `json:"assignee"`
+		URL       string  `json:"url"`
+		UpdatedAt string  `json:"updated_at"`
+	}
+	if err := decodeToolList(result, &issues); err != nil {
+		return nil, err
+	}
+
+	entries := make([]atomEntry, 0, len(issues))
+	for _, is := range issues {
+		e := atomEntry{
+			Title: is.Title,
+			ID:    "urn:thymer:" + is.GUID,
+			Links: entryLinks(c, is.GUID, is.URL),
+		}
+		e.Updated, _ = time.Parse(time.RFC3339Nano, is.UpdatedAt)
+		if is.Repo != "" && is.Number > 0 {
+			e.Title = fmt.Sprintf("%s#%d %s", is.Repo, int(is.Number), is.Title)
+		}
+
+		var summary []string
+		for _, v := range []string{is.State, is.Type} {
+			if v != "" {
+				summary = append(summary, v)
+				e.Categories = append(e.Categories, atomCategory{Term: v})
+			}
+		}
+		if is.Assignee != "" {
+			summary = append(summary, "assigned to "+is.Assignee)
+		}
+		if is.Repo != "" {
+			e.Categories = append(e.Categories, atomCategory{Term: is.Repo})
+		}
+		e.Summary = strings.Join(summary, " · ")
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+// writeAtom renders a feed. Entries without a date get the newest one, so
+// readers don't see them change on every fetch.
+func writeAtom(buf *bytes.Buffer, title, self string, entries []atomEntry) error {
+	var updated time.Time
+	for _, e := range entries {
+		if e.Updated.After(updated) {
+			updated = e.Updated
+		}
+	}
+	if updated.IsZero() {
+		updated = time.Now()
+	}
+	for i := range entries {
+		if entries[i].Updated.IsZero() {
+			entries[i].Updated = updated
+		}
+		entries[i].UpdatedStr = entries[i].Updated.UTC().Format(time.RFC3339)
+	}
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].Updated.After(entries[j].Updated) })
+
+	id := sha256.Sum256([]byte(title))
+	f := atomFeed{
+		Title:     title,
+		ID:        "urn:thymer:feed:" + hex.EncodeToString(id[:8]),
+		Updated:   updated.UTC().Format(time.RFC3339),
+		Generator: "thymer-bar",
+		Links:     []atomLink{{Href: self, Rel: "self"}},
+		Entries:   entries,
+	}
+	buf.WriteString(xml.Header)
+	enc := xml.NewEncoder(buf)
+	enc.Indent("", "  ")
+	return enc.Encode(f)
+}
+
+// notModified sets an ETag from key and reports whether the client
+// already has that version. Feeds hash the tool result, so the ETag
+// changes only when the data does.
+func notModified(w http.ResponseWriter, r *http.Request, key []byte) bool {
+	sum := sha256.Sum256(key)
+	etag := `"` + hex.EncodeToString(sum[:12]) + `"`
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", "no-cache")
+	for _, tag := range strings.Split(r.Header.Get("If-None-Match"), ",") {
+		if t := strings.TrimPrefix(strings.TrimSpace(tag), "W/"); t == etag || t == "*" {
+			w.WriteHeader(http.StatusNotModified)
+			return true
+		}
+	}
+	return false
+}
+
+// handleFeed serves /feeds/{name}.atom. The token query parameter must
+// match the feed's token from the config.
+func (a *App) handleFeed(w http.ResponseWriter, r *http.Request) {
+	name, ok := strings.CutSuffix(strings.TrimPrefix(r.URL.Path, "/feeds/"), ".atom")
+	f, found := findFeed(name)
+	if !ok || !found {
+		writeJSONError(w, "Unknown feed", http.StatusNotFound)
+		return
+	}
+
+	if !a.checkFeedToken(w, r, f.Name) {
+		return
+	}
+	params := r.URL.Query()
+
+	limit := defaultFeedLimit
+	if v := params.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			writeJSONError(w, "limit must be a positive number", http.StatusBadRequest)
+			return
+		}
+		limit = min(n, maxFeedLimit)
+	}
+
+	if !a.IsConnected() {
+		// Readers keep what they have and try again
+		w.Header().Set("Retry-After", "60")
+		writeJSONError(w, "SyncHub not connected", http.StatusServiceUnavailable)
+		return
+	}
+
+	args := map[string]interface{}{"limit": limit}
+	for k, v := range f.Args {
+		args[k] = v
+	}
+	title := f.Title
+	var filters []string
+	for _, p := range f.Params {
+		if v := strings.TrimSpace(params.Get(p)); v != "" {
+			args[p] = v
+			filters = append(filters, v)
+		}
+	}
+	if len(filters) > 0 {
+		title += ": " + strings.Join(filters, ", ")
+	}
+
+	result, err := a.bridge.ExecuteTool(f.Tool, args)
+	if err != nil {
+		writeJSONError(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	entries, err := f.Entries(a.config, result)
+	if err != nil {
+		code := http.StatusBadGateway
+		if strings.HasPrefix(err.Error(), "Unknown tool") || strings.Contains(err.Error(), "not found") {
+			code = http.StatusNotFound
+		}
+		writeJSONError(w, err.Error(), code)
+		return
+	}
+
+	if notModified(w, r, append([]byte(title+"\n"), result...)) {
+		return
+	}
+
+	var buf bytes.Buffer
+	self := "http://" + r.Host + r.URL.RequestURI()
+	if err := writeAtom(&buf, title, self, entries); err != nil {
+		writeJSONError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/atom+xml; charset=utf-8")
+	w.Write(buf.Bytes())
+}
+
+// handleFeeds lists the feeds with their subscription URLs
+func (a *App) handleFeeds(w http.ResponseWriter, r *http.Request) {
+	type feedInfo struct {
+		Name    string   `json:"name"`
+		Title   string   `json:"title"`
+		URL     string   `json:"url"`
+		Filters []string `json:"filters"`
+	}
+	list := make([]feedInfo, 0, len(feeds))
+	for _, f := range feeds {
+		q := url.Values{}
+		q.Set("token", a.config.FeedToken(f.Name))
+		list = append(list, feedInfo{
+			Name:    f.Name,
+			Title:   f.Title,
+			URL:     "http://" + r.Host + "/feeds/" + f.Name + ".atom?" + q.Encode(),
+			Filters: append(append([]string{}, f.Params...), "limit"),
+		})
+	}
+	q := url.Values{}
+	q.Set("token", a.config.FeedToken(calendarFeed))
+	list = append(list, feedInfo{
+		Name:    calendarFeed,
+		Title:   "Thymer Calendar",
+		URL:     "http://" + r.Host + "/calendar.ics?" + q.Encode(),
+		Filters: []string{"calendar", "status", "timing", "days", "needs_followup", "limit"},
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(list)
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestFeedTokens(t *testing.T) {
+	cfg := &Config{}
+	cfg.EnsureFeedTokens(feedNames())
+	a := &App{config: cfg}
+
+	// Every feed, the calendar included, checks its own token before
+	// anything else
+	tests := []struct {
+		path    string
+		handler http.HandlerFunc
+		feed    string
+	}{
+		{"/feeds/captures.atom", a.handleFeed, "captures"},
+		{"/feeds/issues.atom", a.handleFeed, "issues"},
+		{"/calendar.ics", a.handleCalendarFeed, calendarFeed},
+	}
+	for _, tt := range tests {
+		for _, token := range []string{"", "wrong", cfg.FeedToken("captures") + "x"} {
+			rec := httptest.NewRecorder()
+			tt.handler(rec, httptest.NewRequest("GET", tt.path+"?token="+token, nil))
+			if rec.Code != http.StatusUnauthorized {
+				t.Errorf("%s with token %q: %d", tt.path, token, rec.Code)
+			}
+		}
+		// The right token gets as far as the bridge, which isn't connected
+		rec := httptest.NewRecorder()
+		tt.handler(rec, httptest.NewRequest("GET", tt.path+"?token="+cfg.FeedToken(tt.feed), nil))
+		if rec.Code != http.StatusServiceUnavailable {
+			t.Errorf("%s with its token: %d %s", tt.path, rec.Code, rec.Body.String())
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	a.handleFeeds(rec, httptest.NewRequest("GET", "http://127.0.0.1:9847/api/feeds", nil))
+	var list []struct {
+		Name string `json:"name"`
+		URL  string `json:"url"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &list); err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != len(feedNames()) {
+		t.Fatalf("/api/feeds = %s", rec.Body.String())
+	}
+	for _, f := range list {
+		if !strings.Contains(f.URL, "token="+cfg.FeedToken(f.Name)) {
+			t.Errorf("%s URL %s lacks its token", f.Name, f.URL)
+		}
+	}
+	if list[len(list)-1].URL != "http://127.0.0.1:9847/calendar.ics?token="+cfg.FeedToken(calendarFeed) {
+		t.Errorf("calendar URL = %s", list[len(list)-1].URL)
+	}
+}
@@ -5,6 +5,7 @@ go 1.23.0
 require (
 	fyne.io/systray v1.11.0
 	github.com/gorilla/websocket v1.5.3
+	go.etcd.io/bbolt v1.3.10
 )
 
 require (
@@ -0,0 +1,480 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// importBatchSize is how many records each import_records call carries, so
+// a batch finishes well within the bridge's 30 second timeout
+const importBatchSize = 25
+
+// Import targets that aren't collection fields
+const (
+	importTitle      = "title"
+	importBody       = "body"
+	importExternalID = "external_id"
+)
+
+// ImportRow is the outcome of one row of an import file
+type ImportRow struct {
+	Row        int    `json:"row"` // 1 is the first data row
+	ExternalID string `json:"external_id,omitempty"`
+	Title      string `json:"title,omitempty"`
+	Action     string `json:"action"` // created, updated or failed
+	GUID       string `json:"guid,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+// ImportResult is what /api/import reports
+type ImportResult struct {
+	Collection     string      `json:"collection"`
+	DryRun         bool        `json:"dry_run"`
+	Created        int         `json:"created"`
+	Updated        int         `json:"updated"`
+	Failed         int         `json:"failed"`
+	IgnoredColumns []string    `json:"ignored_columns,omitempty"`
+	Rows           []ImportRow `json:"rows"`
+}
+
+// importRecord is a row ready for import_records
+type importRecord struct {
+	ExternalID string                 `json:"external_id"`
+	Title      string                 `json:"title"`
+	Fields     map[string]interface{} `json:"fields"`
+	Body       string                 `json:"body,omitempty"`
+}
+
+// ParseImportMap parses "Column=field,Other Column=field"
+func ParseImportMap(s string) (map[string]string, error) {
+	mapping := make(map[string]string)
+	for _, pair := range strings.Split(s, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		column, field, ok := strings.Cut(pair, "=")
+		column, field = strings.TrimSpace(column), strings.TrimSpace(field)
+		if !ok || column == "" || field == "" {
+			return nil, fmt.Errorf("invalid mapping %q (use Column=field)", pair)
+		}
+		mapping[column] = field
+	}
+	return mapping, nil
+}
+
+// ReadImportRows reads a CSV file with a header row, or JSON: an array of
+// objects or one object per line. An empty format is guessed from the data.
+// Columns are in file order.
+func ReadImportRows(data []byte, format string) ([]map[string]interface{}, []string, error) {
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf")) // Spreadsheet BOM
+	if format == "" {
+		format = "csv"
+		if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && (trimmed[0] == '[' || trimmed[0] == '{') {
+			format = "json"
+		}
+	}
+
+	switch format {
+	case "csv":
+		r := csv.NewReader(bytes.NewReader(data))
+		r.FieldsPerRecord = -1
+		lines, err := r.ReadAll()
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid CSV: %w", err)
+		}
+		if len(lines) == 0 {
+			return nil, nil, fmt.Errorf("empty file")
+		}
+		columns := lines[0]
+		for i := range columns {
+			columns[i] = strings.TrimSpace(columns[i])
+		}
+		var rows []map[string]interface{}
+		for _, line := range lines[1:] {
+			row := make(map[string]interface{}, len(columns))
+			for i, column := range columns {
+				if i < len(line) {
+					row[column] = line[i]
+				}
+			}
+			rows = append(rows, row)
+		}
+		return rows, columns, nil
+
+	case "json", "ndjson":
+		var rows []map[string]interface{}
+		if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '[' {
+			if err := json.Unmarshal(trimmed, &rows); err != nil {
+				return nil, nil, fmt.Errorf("invalid JSON: %w", err)
+			}
+		} else {
+			dec := json.NewDecoder(bytes.NewReader(data))
+			for dec.More() {
+				var row map[string]interface{}
+				if err := dec.Decode(&row); err != nil {
+					return nil, nil, fmt.Errorf("invalid JSON on record %d: %w", len(rows)+1, err)
+				}
+				rows = append(rows, row)
+			}
+		}
+		seen := make(map[string]bool)
+		var columns []string
+		for _, row := range rows {
+			var keys []string
+			for k := range row {
+				if !seen[k] {
+					seen[k] = true
+					keys = append(keys, k)
+				}
+			}
+			sort.Strings(keys)
+			columns = append(columns, keys...)
+		}
+		return rows, columns, nil
+	}
+	return nil, nil, fmt.Errorf("unknown format %q (use csv or json)", format)
+}
+
+// importTargets decides which field each column goes to: the mapping
+// first, then columns named like a field (by id or label). Other columns
+// are ignored. Rows are matched by an external_id column or, without one,
+// by the key column.
+func importTargets(collection string, columns []string, mapping map[string]string, key string, fields []CollectionField) (map[string]CollectionField, []string, error) {
+	byKey := map[string]CollectionField{
+		importTitle:      {ID: importTitle, Type: "text"},
+		importBody:       {ID: importBody, Type: "text"},
+		importExternalID: {ID: importExternalID, Type: "text"},
+	}
+	names := []string{importTitle, importBody, importExternalID}
+	for _, f := range fields {
+		if _, ok := byKey[normalizeKey(f.ID)]; !ok {
+			names = append(names, f.ID)
+		}
+		byKey[normalizeKey(f.ID)] = f
+		if f.Label != "" {
+			if _, ok := byKey[normalizeKey(f.Label)]; !ok {
+				byKey[normalizeKey(f.Label)] = f
+			}
+		}
+	}
+
+	hasColumn := make(map[string]bool, len(columns))
+	for _, c := range columns {
+		hasColumn[c] = true
+	}
+	targets := make(map[string]CollectionField)
+	for column, target := range mapping {
+		if !hasColumn[column] {
+			return nil, nil, fmt.Errorf("column %q is not in the file (columns: %s)", column, strings.Join(columns, ", "))
+		}
+		f, ok := byKey[normalizeKey(target)]
+		if !ok {
+			return nil, nil, fmt.Errorf("unknown field %q for %s (fields: %s)", target, collection, strings.Join(names, ", "))
+		}
+		targets[column] = f
+	}
+
+	var ignored []string
+	for _, column := range columns {
+		if _, ok := targets[column]; ok {
+			continue
+		}
+		if _, mapped := mapping[column]; mapped {
+			continue
+		}
+		if f, ok := byKey[normalizeKey(column)]; ok {
+			targets[column] = f
+		} else {
+			ignored = append(ignored, column)
+		}
+	}
+
+	// Two columns for the same field would race
+	used := make(map[string]string)
+	for _, column := range columns {
+		if f, ok := targets[column]; ok {
+			if other, dup := used[f.ID]; dup {
+				return nil, nil, fmt.Errorf("columns %q and %q both go to %s", other, column, f.ID)
+			}
+			used[f.ID] = column
+		}
+	}
+	if _, ok := used[importTitle]; !ok {
+		return nil, nil, fmt.Errorf("no column for title; map one with Column=title")
+	}
+	_, hasID := used[importExternalID]
+	switch {
+	case key == "" && !hasID:
+		return nil, nil, fmt.Errorf("no column for external_id, so re-imports couldn't find their records; map one with Column=external_id, or name a column whose values identify the rows as the key")
+	case key != "" && hasID:
+		return nil, nil, fmt.Errorf("the file has an external_id column (%s); records are matched by it, so leave out the key", used[importExternalID])
+	case key != "" && !hasColumn[key]:
+		return nil, nil, fmt.Errorf("key column %q is not in the file (columns: %s)", key, strings.Join(columns, ", "))
+	}
+	return targets, ignored, nil
+}
+
+// toImportRecord validates a row against the field types. Without an
+// external_id column, the record's external_id comes from the key column's
+// value, so a row keeps its record when other values change or it moves.
+func toImportRecord(row map[string]interface{}, targets map[string]CollectionField, key string) (importRecord, error) {
+	rec := importRecord{Fields: make(map[string]interface{})}
+	columns := make([]string, 0, len(targets))
+	for column := range targets {
+		columns = append(columns, column)
+	}
+	sort.Strings(columns)
+
+	var problems []string
+	for _, column := range columns {
+		f := targets[column]
+		raw, ok := row[column]
+		if !ok || raw == nil {
+			continue
+		}
+		text := importText(raw)
+		switch f.ID {
+		case importTitle:
+			rec.Title = strings.TrimSpace(text)
+			continue
+		case importBody:
+			rec.Body = text
+			continue
+		case importExternalID:
+			rec.ExternalID = strings.TrimSpace(text)
+			continue
+		}
+		value, err := importValue(f, raw)
+		if err != nil {
+			problems = append(problems, err.Error())
+			continue
+		}
+		if value != nil {
+			rec.Fields[f.ID] = value
+		}
+	}
+
+	if key != "" {
+		value := ""
+		if raw, ok := row[key]; ok && raw != nil {
+			value = strings.TrimSpace(importText(raw))
+		}
+		if value == "" {
+			return rec, fmt.Errorf("key %s is empty", key)
+		}
+		sum := sha1.Sum([]byte(value))
+		rec.ExternalID = "import_" + hex.EncodeToString(sum[:8])
+	}
+	if rec.Title == "" {
+		return rec, fmt.Errorf("title is empty")
+	}
+	if rec.ExternalID == "" {
+		return rec, fmt.Errorf("external_id is empty")
+	}
+	if len(problems) > 0 {
+		return rec, fmt.Errorf("%s", strings.Join(problems, "; "))
+	}
+	return rec, nil
+}
+
+// importText is a cell as text; lists are joined with ", "
+func importText(v interface{}) string {
+	switch val := v.(type) {
+	case string:
+		return val
+	case float64:
+		return strconv.FormatFloat(val, 'f', -1, 64)
+	case []interface{}:
+		parts := make([]string, 0, len(val))
+		for _, item := range val {
+			parts = append(parts, importText(item))
+		}
+		return strings.Join(parts, ", ")
+	}
+	return fmt.Sprint(v)
+}
+
+// importValue converts a cell to what import_records expects for the
+// field's type. Empty cells are skipped.
+func importValue(f CollectionField, raw interface{}) (interface{}, error) {
+	if list, ok := raw.([]interface{}); ok {
+		if !f.Many && f.Type != "text" {
+			return nil, fmt.Errorf("%s takes one value, not a list", f.ID)
+		}
+		return importText(list), nil
+	}
+	if num, ok := raw.(float64); ok && f.Type == "number" {
+		return num, nil
+	}
+
+	text := strings.TrimSpace(importText(raw))
+	if text == "" {
+		return nil, nil
+	}
+	if f.Many {
+		// Exports join values with "; "
+		var parts []string
+		for _, part := range strings.FieldsFunc(text, func(r rune) bool { return r == ';' || r == ',' }) {
+			if part = strings.TrimSpace(part); part != "" {
+				parts = append(parts, part)
+			}
+		}
+		return strings.Join(parts, ", "), nil
+	}
+
+	switch f.Type {
+	case "number":
+		n, err := strconv.ParseFloat(text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s must be a number, not %q", f.ID, text)
+		}
+		return n, nil
+	case "datetime":
+		t, ok := parseImportTime(text)
+		if !ok {
+			return nil, fmt.Errorf("%s must be a date like 2026-01-31 or 2026-01-31T09:00:00Z, not %q", f.ID, text)
+		}
+		return t.Format(time.RFC3339), nil
+	case "choice":
+		if len(f.Choices) == 0 {
+			return text, nil
+		}
+		labels := make([]string, len(f.Choices))
+		for i, c := range f.Choices {
+			if normalizeKey(c.ID) == normalizeKey(text) || normalizeKey(c.Label) == normalizeKey(text) {
+				return c.Label, nil
+			}
+			labels[i] = c.Label
+		}
+		return nil, fmt.Errorf("%s must be one of %s, not %q", f.ID, strings.Join(labels, ", "), text)
+	}
+	return text, nil
+}
+
+// parseImportTime reads RFC 3339, or dates and times without a zone as
+// local time
+func parseImportTime(s string) (time.Time, bool) {
+	if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+		return t, true
+	}
+	for _, layout := range []string{"2006-01-02T15:04:05", "2006-01-02T15:04", "2006-01-02 15:04:05", "2006-01-02 15:04", "2006-01-02", "2006/01/02"} {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// Import validates rows and upserts the valid ones through import_records in
+// batches. Rows that fail validation are reported and never sent.
+func Import(bridge *Bridge, collection string, fields []CollectionField, rows []map[string]interface{}, columns []string, mapping map[string]string, key string, dryRun bool) (*ImportResult, error) {
+	if !slices.ContainsFunc(fields, func(f CollectionField) bool { return f.ID == importExternalID }) {
+		return nil, fmt.Errorf("%s has no external_id field to match records by; add a text field with that id to the collection", collection)
+	}
+	targets, ignored, err := importTargets(collection, columns, mapping, key, fields)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ImportResult{Collection: collection, DryRun: dryRun, IgnoredColumns: ignored, Rows: []ImportRow{}}
+	var batch []importRecord
+	var batchRows []int // Index into result.Rows
+	seen := make(map[string]int)
+
+	fail := func(i int, msg string) {
+		result.Rows[i].Action = "failed"
+		result.Rows[i].Error = msg
+		result.Failed++
+	}
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		outcomes, err := importBatch(bridge, collection, batch, dryRun)
+		for j, i := range batchRows {
+			switch {
+			case err != nil:
+				fail(i, err.Error())
+			case j >= len(outcomes):
+				fail(i, "no result from SyncHub")
+			case outcomes[j].Action == "created" || outcomes[j].Action == "updated":
+				result.Rows[i].Action = outcomes[j].Action
+				result.Rows[i].GUID = outcomes[j].GUID
+				if outcomes[j].Action == "created" {
+					result.Created++
+				} else {
+					result.Updated++
+				}
+			default:
+				fail(i, outcomes[j].Error)
+			}
+		}
+		batch, batchRows = nil, nil
+	}
+
+	for n, row := range rows {
+		rec, err := toImportRecord(row, targets, key)
+		result.Rows = append(result.Rows, ImportRow{Row: n + 1, ExternalID: rec.ExternalID, Title: rec.Title})
+		i := len(result.Rows) - 1
+		if err != nil {
+			fail(i, err.Error())
+			continue
+		}
+		if first, dup := seen[rec.ExternalID]; dup {
+			matchedBy := importExternalID
+			if key != "" {
+				matchedBy = key
+			}
+			fail(i, fmt.Sprintf("same %s as row %d", matchedBy, first))
+			continue
+		}
+		seen[rec.ExternalID] = n + 1
+
+		batch = append(batch, rec)
+		batchRows = append(batchRows, i)
+		if len(batch) >= importBatchSize {
+			flush()
+		}
+	}
+	flush()
+	return result, nil
+}
+
+// importOutcome is one record's result from import_records
+type importOutcome struct {
+	ExternalID string `json:"external_id"`
+	Action     string `json:"action"`
+	GUID       string `json:"guid"`
+	Error      string `json:"error"`
+}
+
+func importBatch(bridge *Bridge, collection string, batch []importRecord, dryRun bool) ([]importOutcome, error) {
+	raw, err := bridge.ExecuteTool("import_records", map[string]interface{}{
+		"collection": collection,
+		"records":    batch,
+		"dry_run":    dryRun,
+	})
+	if err != nil {
+		return nil, err
+	}
+	var result struct {
+		Results []importOutcome `json:"results"`
+		Error   string          `json:"error"`
+	}
+	if err := json.Unmarshal(raw, &result); err != nil {
+		return nil, fmt.Errorf("invalid import_records result: %w", err)
+	}
+	if result.Error != "" {
+		return nil, fmt.Errorf("%s", result.Error)
+	}
+	return result.Results, nil
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+var importTestFields = []CollectionField{
+	{ID: "external_id", Label: "External ID", Type: "text"},
+	{ID: "repo", Label: "Repository", Type: "text"},
+	{ID: "state", Label: "State", Type: "choice", Choices: []FieldChoice{{ID: "open", Label: "Open"}, {ID: "closed", Label: "Closed"}}},
+}
+
+func TestImportTargetsNeedAnID(t *testing.T) {
+	columns := []string{"Title", "Repository", "Card ID"}
+	tests := []struct {
+		mapping map[string]string
+		key     string
+		err     string
+	}{
+		{nil, "", "no column for external_id"},
+		{map[string]string{"Card ID": "external_id"}, "", ""},
+		{nil, "Card ID", ""},
+		{nil, "Title", ""},
+		{map[string]string{"Card ID": "external_id"}, "Title", "leave out the key"},
+		{nil, "Missing", `key column "Missing" is not in the file`},
+	}
+	for _, tt := range tests {
+		_, _, err := importTargets("Issues", columns, tt.mapping, tt.key, importTestFields)
+		switch {
+		case tt.err == "" && err != nil:
+			t.Errorf("map %v key %q: %v", tt.mapping, tt.key, err)
+		case tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)):
+			t.Errorf("map %v key %q: error %v, want %q", tt.mapping, tt.key, err, tt.err)
+		}
+	}
+}
+
+func TestImportKeyID(t *testing.T) {
+	columns := []string{"Title", "Repository", "State"}
+	targets, _, err := importTargets("Issues", columns, nil, "Title", importTestFields)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	row := map[string]interface{}{"Title": "Fix login", "Repository": "acme/web", "State": "open"}
+	rec, err := toImportRecord(row, targets, "Title")
+	if err != nil {
+		t.Fatalf("toImportRecord: %v", err)
+	}
+	if !strings.HasPrefix(rec.ExternalID, "import_") || rec.Fields["state"] != "Open" || rec.Fields["repo"] != "acme/web" {
+		t.Errorf("record = %+v", rec)
+	}
+
+	// Editing the row's other values keeps its record
+	edited := map[string]interface{}{"Title": " Fix login ", "Repository": "acme/api", "State": "closed"}
+	if again, err := toImportRecord(edited, targets, "Title"); err != nil || again.ExternalID != rec.ExternalID {
+		t.Errorf("edited row: %s, %v; want %s", again.ExternalID, err, rec.ExternalID)
+	}
+	other := map[string]interface{}{"Title": "Fix logout"}
+	if again, _ := toImportRecord(other, targets, "Title"); again.ExternalID == rec.ExternalID {
+		t.Errorf("different key got the same external_id")
+	}
+	if _, err := toImportRecord(map[string]interface{}{"Title": ""}, targets, "Title"); err == nil {
+		t.Error("empty key accepted")
+	}
+}
+
+func TestImportExternalIDColumn(t *testing.T) {
+	columns := []string{"Title", "External ID"}
+	targets, _, err := importTargets("Issues", columns, nil, "", importTestFields)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec, err := toImportRecord(map[string]interface{}{"Title": "Fix login", "External ID": " trello_42 "}, targets, "")
+	if err != nil || rec.ExternalID != "trello_42" {
+		t.Errorf("record = %+v, %v", rec, err)
+	}
+	if _, err := toImportRecord(map[string]interface{}{"Title": "Fix login", "External ID": ""}, targets, ""); err == nil {
+		t.Error("empty external_id accepted")
+	}
+}
@@ -0,0 +1,373 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	llmHealthInterval = 10 * time.Second
+	llmStopTimeout    = 5 * time.Second
+	llmMaxRestarts    = 3
+	llmLogLines       = 500
+)
+
+// LLMStatus is reported by /api/llm/status and /api/status
+type LLMStatus struct {
+	Running   bool       `json:"running"` // Endpoint answers health checks
+	Managed   bool       `json:"managed"` // thymer-bar supervises the process
+	Process   bool       `json:"process"` // Supervised process is alive
+	PID       int        `json:"pid,omitempty"`
+	Model     string     `json:"model,omitempty"`
+	Endpoint  string     `json:"endpoint"`
+	StartedAt *time.Time `json:"started_at,omitempty"`
+	Restarts  int        `json:"restarts,omitempty"`
+	LastError string     `json:"last_error,omitempty"`
+}
+
+// LLMManager supervises a local inference process and tracks its health
+type LLMManager struct {
+	config *Config
+
+	mu        sync.Mutex
+	cmd       *exec.Cmd
+	exited    chan struct{}
+	stopping  bool
+	healthy   bool
+	startedAt time.Time
+	restarts  int
+	lastError string
+
+	logs  *logBuffer
+	proxy http.Handler
+
+	stopHealth chan struct{}
+}
+
+func NewLLMManager(cfg *Config) *LLMManager {
+	l := &LLMManager{
+		config:     cfg,
+		logs:       newLogBuffer(llmLogLines),
+		stopHealth: make(chan struct{}),
+	}
+	l.proxy = newLLMProxy(l.Endpoint())
+	return l
+}
+
+// Run starts health checks and, if configured, the inference process
+func (l *LLMManager) Run() {
+	go l.healthLoop()
+
+	if l.config.AutoStartLLM && l.config.LLMSettings().Command != "" {
+		if err := l.Start(); err != nil {
+			log.Printf("[LLM] Auto-start failed: %v", err)
+		}
+	}
+}
+
+// Shutdown stops health checks and the supervised process
+func (l *LLMManager) Shutdown() {
+	close(l.stopHealth)
+	l.Stop()
+}
+
+// Endpoint returns the upstream OpenAI-compatible base URL
+func (l *LLMManager) Endpoint() string {
+	return l.config.LLMSettings().Endpoint
+}
+
+// Start launches the configured inference command
+func (l *LLMManager) Start() error {
+	settings := l.config.LLMSettings()
+	if settings.Command == "" {
+		return fmt.Errorf("no LLM command configured")
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.cmd != nil {
+		return fmt.Errorf("already running (pid %d)", l.cmd.Process.Pid)
+	}
+	l.stopping = false
+	l.restarts = 0
+	return l.spawn(settings)
+}
+
+// spawn starts the process; caller holds l.mu
+func (l *LLMManager) spawn(settings LLMConfig) error {
+	args := make([]string, len(settings.Args))
+	for i, arg := range settings.Args {
+		args[i] = strings.ReplaceAll(arg, "{model}", l.config.LLMModel)
+	}
+
+	cmd := exec.Command(settings.Command, args...)
+	cmd.Stdout = l.logs
+	cmd.Stderr = l.logs
+	// Don't wait forever on output pipes held open by grandchildren
+	cmd.WaitDelay = llmStopTimeout
+
+	if err := cmd.Start(); err != nil {
+		l.lastError = err.Error()
+		return err
+	}
+
+	log.Printf("[LLM] Started %s (pid %d)", settings.Command, cmd.Process.Pid)
+	l.logs.Write([]byte(fmt.Sprintf("--- started %s %s ---\n", settings.Command, strings.Join(args, " "))))
+
+	l.cmd = cmd
+	l.exited = make(chan struct{})
+	l.startedAt = time.Now()
+	l.lastError = ""
+
+	go l.wait(cmd, l.exited)
+	return nil
+}
+
+// wait reaps the process and restarts it if it died unexpectedly
+func (l *LLMManager) wait(cmd *exec.Cmd, exited chan struct{}) {
+	err := cmd.Wait()
+
+	// Signal Stop only after state is updated, so a Restart can't race us
+	defer close(exited)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.cmd == cmd {
+		l.cmd = nil
+		l.healthy = false
+	}
+	if l.stopping {
+		log.Println("[LLM] Stopped")
+		return
+	}
+
+	if err != nil {
+		l.lastError = err.Error()
+	} else {
+		l.lastError = "process exited"
+	}
+	log.Printf("[LLM] Process exited unexpectedly: %s", l.lastError)
+
+	if l.restarts >= llmMaxRestarts {
+		log.Printf("[LLM] Giving up after %d restarts", l.restarts)
+		return
+	}
+	l.restarts++
+	delay := time.Duration(l.restarts) * 2 * time.Second
+
+	go func() {
+		time.Sleep(delay)
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		if l.stopping || l.cmd != nil {
+			return
+		}
+		log.Printf("[LLM] Restarting (attempt %d)", l.restarts)
+		l.spawn(l.config.LLMSettings())
+	}()
+}
+
+// Stop terminates the supervised process, killing it if it doesn't exit
+func (l *LLMManager) Stop() error {
+	l.mu.Lock()
+	cmd := l.cmd
+	exited := l.exited
+	l.stopping = true
+	l.mu.Unlock()
+
+	if cmd == nil {
+		return nil
+	}
+
+	// os.Interrupt isn't supported on Windows; Kill is the fallback
+	if err := cmd.Process.Signal(os.Interrupt); err != nil {
+		cmd.Process.Kill()
+	}
+
+	select {
+	case <-exited:
+	case <-time.After(llmStopTimeout):
+		log.Println("[LLM] Process didn't exit, killing")
+		cmd.Process.Kill()
+		<-exited
+	}
+	return nil
+}
+
+// Restart stops and starts the process
+func (l *LLMManager) Restart() error {
+	if err := l.Stop(); err != nil {
+		return err
+	}
+	return l.Start()
+}
+
+// Status reports the process and endpoint health
+func (l *LLMManager) Status() LLMStatus {
+	settings := l.config.LLMSettings()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	status := LLMStatus{
+		Running:   l.healthy,
+		Managed:   settings.Command != "",
+		Process:   l.cmd != nil,
+		Model:     l.config.LLMModel,
+		Endpoint:  settings.Endpoint,
+		Restarts:  l.restarts,
+		LastError: l.lastError,
+	}
+	if l.cmd != nil {
+		status.PID = l.cmd.Process.Pid
+		startedAt := l.startedAt
+		status.StartedAt = &startedAt
+	}
+	return status
+}
+
+// Proxy forwards OpenAI-compatible requests (/v1/...) to the LLM endpoint
+func (l *LLMManager) Proxy() http.Handler {
+	return l.proxy
+}
+
+// newLLMProxy builds the reverse proxy to an endpoint. thymer-bar answers
+// CORS itself, so upstream CORS headers are dropped and the browser's Origin
+// isn't forwarded (Ollama rejects origins it doesn't know).
+func newLLMProxy(endpoint string) http.Handler {
+	unavailable := func(w http.ResponseWriter, err error) {
+		log.Printf("[LLM] Proxy error: %v", err)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadGateway)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"error": map[string]string{"message": "LLM endpoint unavailable: " + err.Error()},
+		})
+	}
+
+	target, err := url.Parse(endpoint)
+	if err != nil {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			unavailable(w, err)
+		})
+	}
+	return &httputil.ReverseProxy{
+		Rewrite: func(r *httputil.ProxyRequest) {
+			r.SetURL(target)
+			r.Out.Header.Del("Origin")
+			r.Out.Header.Del("Referer")
+		},
+		// Stream tokens as they arrive
+		FlushInterval: -1,
+		ModifyResponse: func(resp *http.Response) error {
+			for key := range resp.Header {
+				if strings.HasPrefix(key, "Access-Control-") {
+					resp.Header.Del(key)
+				}
+			}
+			return nil
+		},
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			unavailable(w, err)
+		},
+	}
+}
+
+// Logs returns the last n lines of process output
+func (l *LLMManager) Logs(n int) []string {
+	return l.logs.Lines(n)
+}
+
+func (l *LLMManager) healthLoop() {
+	ticker := time.NewTicker(llmHealthInterval)
+	defer ticker.Stop()
+
+	l.checkHealth()
+	for {
+		select {
+		case <-ticker.C:
+			l.checkHealth()
+		case <-l.stopHealth:
+			return
+		}
+	}
+}
+
+func (l *LLMManager) checkHealth() {
+	settings := l.config.LLMSettings()
+	client := &http.Client{Timeout: 3 * time.Second}
+
+	healthy := false
+	resp, err := client.Get(settings.Endpoint + settings.HealthPath)
+	if err == nil {
+		resp.Body.Close()
+		healthy = resp.StatusCode < 500
+	}
+
+	l.mu.Lock()
+	if healthy != l.healthy {
+		if healthy {
+			log.Printf("[LLM] Endpoint healthy: %s", settings.Endpoint)
+		} else {
+			log.Printf("[LLM] Endpoint unavailable: %s", settings.Endpoint)
+		}
+	}
+	l.healthy = healthy
+	l.mu.Unlock()
+}
+
+// logBuffer keeps the most recent lines written to it
+type logBuffer struct {
+	mu    sync.Mutex
+	max   int
+	lines []string
+	w     *io.PipeWriter
+}
+
+func newLogBuffer(max int) *logBuffer {
+	b := &logBuffer{max: max}
+	r, w := io.Pipe()
+	b.w = w
+	go func() {
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+		for scanner.Scan() {
+			b.append(scanner.Text())
+		}
+	}()
+	return b
+}
+
+func (b *logBuffer) Write(p []byte) (int, error) {
+	return b.w.Write(p)
+}
+
+func (b *logBuffer) append(line string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.lines = append(b.lines, line)
+	if len(b.lines) > b.max {
+		b.lines = b.lines[len(b.lines)-b.max:]
+	}
+}
+
+// Lines returns up to n of the most recent lines (all if n <= 0)
+func (b *logBuffer) Lines(n int) []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if n <= 0 || n > len(b.lines) {
+		n = len(b.lines)
+	}
+	return append([]string{}, b.lines[len(b.lines)-n:]...)
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestLLMProxy(t *testing.T) {
+	var gotPath, gotOrigin string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath, gotOrigin = r.URL.Path, r.Header.Get("Origin")
+		w.Header().Set("Access-Control-Allow-Origin", "http://localhost:11434")
+		w.Write([]byte(`{"ok":true}`))
+	}))
+	defer upstream.Close()
+
+	// Base URLs work with or without the /v1 OpenAI clients are configured with
+	for _, endpoint := range []string{upstream.URL, upstream.URL + "/", upstream.URL + "/v1", upstream.URL + "/v1/"} {
+		llm := NewLLMManager(&Config{LLM: &LLMConfig{Endpoint: endpoint}})
+		req := httptest.NewRequest("POST", "/v1/chat/completions", strings.NewReader(`{}`))
+		req.Header.Set("Origin", "https://thymer.com")
+		rec := httptest.NewRecorder()
+		llm.Proxy().ServeHTTP(rec, req)
+
+		if gotPath != "/v1/chat/completions" {
+			t.Errorf("endpoint %s: upstream path = %q", endpoint, gotPath)
+		}
+		if gotOrigin != "" {
+			t.Errorf("endpoint %s: Origin forwarded: %q", endpoint, gotOrigin)
+		}
+		if rec.Header().Get("Access-Control-Allow-Origin") != "" {
+			t.Errorf("endpoint %s: upstream CORS header kept", endpoint)
+		}
+		if body, _ := io.ReadAll(rec.Body); string(body) != `{"ok":true}` {
+			t.Errorf("endpoint %s: body = %s", endpoint, body)
+		}
+	}
+}
+
+func TestLLMProxyUnavailable(t *testing.T) {
+	upstream := httptest.NewServer(http.NotFoundHandler())
+	upstream.Close()
+
+	llm := NewLLMManager(&Config{LLM: &LLMConfig{Endpoint: upstream.URL}})
+	rec := httptest.NewRecorder()
+	llm.Proxy().ServeHTTP(rec, httptest.NewRequest("POST", "/v1/chat/completions", strings.NewReader(`{}`)))
+
+	if rec.Code != http.StatusBadGateway || !strings.Contains(rec.Body.String(), "LLM endpoint unavailable") {
+		t.Errorf("got %d %s", rec.Code, rec.Body.String())
+	}
+}
@@ -18,12 +18,14 @@ type MCPServer struct {
 	bridge     *Bridge
 	server     *mcp.Server
 	httpServer *http.Server
+	completer  *Completer
 }
 
 func NewMCPServer(port int, bridge *Bridge) *MCPServer {
 	return &MCPServer{
-		port:   port,
-		bridge: bridge,
+		port:      port,
+		bridge:    bridge,
+		completer: NewCompleter(bridge),
 	}
 }
 
@@ -34,11 +36,14 @@ func (m *MCPServer) Start() error {
 			Name:    "thymer",
 			Version: "0.1.0",
 		},
-		nil,
+		&mcp.ServerOptions{
+			CompletionHandler: m.handleComplete,
+		},
 	)
 
 	// Register tools from bridge
 	m.registerTools()
+	m.registerPrompts()
 
 	// Create HTTP mux with both stateful and stateless endpoints
 	mux := http.NewServeMux()
@@ -98,7 +103,11 @@ func (m *MCPServer) handleStateless(w http.ResponseWriter, r *http.Request) {
 			"result": map[string]interface{}{
 				"protocolVersion": "2024-11-05",
 				"serverInfo":      map[string]string{"name": "thymer", "version": "0.1.0"},
-				"capabilities":    map[string]interface{}{"tools": map[string]bool{"listChanged": true}},
+				"capabilities": map[string]interface{}{
+					"tools":       map[string]bool{"listChanged": true},
+					"prompts":     map[string]bool{},
+					"completions": map[string]bool{},
+				},
 			},
 		})
 
@@ -147,6 +156,51 @@ func (m *MCPServer) handleStateless(w http.ResponseWriter, r *http.Request) {
 			},
 		})
 
+	case "prompts/list":
+		prompts := make([]*mcp.Prompt, 0, len(workspacePrompts))
+		for _, p := range workspacePrompts {
+			prompts = append(prompts, p.prompt)
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"jsonrpc": "2.0",
+			"id":      req.ID,
+			"result":  map[string]interface{}{"prompts": prompts},
+		})
+
+	case "prompts/get":
+		name, _ := req.Params["name"].(string)
+		prompt := findPrompt(name)
+		if prompt == nil {
+			m.jsonRPCError(w, req.ID, -32602, "Unknown prompt: "+name)
+			return
+		}
+		args := make(map[string]string)
+		if raw, ok := req.Params["arguments"].(map[string]interface{}); ok {
+			for k, v := range raw {
+				args[k] = fmt.Sprint(v)
+			}
+		}
+		result, err := prompt.renderPrompt(args)
+		if err != nil {
+			m.jsonRPCError(w, req.ID, -32602, err.Error())
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"jsonrpc": "2.0",
+			"id":      req.ID,
+			"result":  result,
+		})
+
+	case "completion/complete":
+		argument, _ := req.Params["argument"].(map[string]interface{})
+		name, _ := argument["name"].(string)
+		value, _ := argument["value"].(string)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"jsonrpc": "2.0",
+			"id":      req.ID,
+			"result":  map[string]interface{}{"completion": m.completer.Complete(name, value)},
+		})
+
 	default:
 		m.jsonRPCError(w, req.ID, -32601, "Method not found: "+req.Method)
 	}
@@ -195,6 +249,22 @@ func (m *MCPServer) registerTools() {
 	}
 }
 
+func (m *MCPServer) registerPrompts() {
+	for _, p := range workspacePrompts {
+		prompt := p // capture for closure
+		m.server.AddPrompt(prompt.prompt, func(ctx context.Context, req *mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+			return prompt.renderPrompt(req.Params.Arguments)
+		})
+	}
+}
+
+// handleComplete serves argument completions for prompts and tools
+func (m *MCPServer) handleComplete(ctx context.Context, req *mcp.CompleteRequest) (*mcp.CompleteResult, error) {
+	return &mcp.CompleteResult{
+		Completion: m.completer.Complete(req.Params.Argument.Name, req.Params.Argument.Value),
+	}, nil
+}
+
 func (m *MCPServer) executeTool(name string, args map[string]interface{}) (map[string]interface{}, error) {
 	result, err := m.bridge.ExecuteTool(name, args)
 	if err != nil {
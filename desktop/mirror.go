@@ -0,0 +1,437 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"path/filepath"
+	"sort"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// Mirror keeps a local copy of the collection records thymer-bar sees, from
+// query results and periodic snapshots, so queries keep working while
+// SyncHub is disconnected.
+//
+// Layout: the "collections" bucket maps a collection name to its
+// MirrorCollection; "records" holds a bucket per collection of guid to
+// record, in get_collection_records format. Records are also kept in a
+// full-text index (see search.go).
+type Mirror struct {
+	db *bolt.DB
+
+	// OnChange is called after records were added, changed or removed
+	OnChange func()
+}
+
+// mirrorFullSnapshotInterval bounds how long a record missed by incremental
+// snapshots stays stale
+const mirrorFullSnapshotInterval = 24 * time.Hour
+
+// mirrorPageSize is how many records a snapshot asks for in one bridge
+// call, so no call grows with the collection
+const mirrorPageSize = 200
+
+var (
+	mirrorCollectionsBucket = []byte("collections")
+	mirrorRecordsBucket     = []byte("records")
+)
+
+// MirrorCollection describes one mirrored collection
+type MirrorCollection struct {
+	Name       string                 `json:"name"`
+	Schema     map[string]interface{} `json:"schema,omitempty"`
+	Fields     []CollectionField      `json:"fields,omitempty"`
+	SnapshotAt time.Time              `json:"snapshot_at"` // When records were last known complete
+	Records    int                    `json:"records"`
+}
+
+// MirrorStatus is reported in /api/status
+type MirrorStatus struct {
+	Collections  int        `json:"collections"`
+	Records      int        `json:"records"`
+	LastSnapshot *time.Time `json:"last_snapshot,omitempty"`
+	Indexed      int        `json:"indexed"` // Records in the search index
+}
+
+// mirrorRecord is a record as get_collection_records returns it, plus its
+// text when a snapshot included it
+type mirrorRecord struct {
+	GUID   string                 `json:"guid"`
+	Title  string                 `json:"title"`
+	Fields map[string]interface{} `json:"fields"`
+	Body   *string                `json:"body,omitempty"` // nil when unknown
+}
+
+func mirrorPath() string {
+	return filepath.Join(configDir(), "mirror.db")
+}
+
+// OpenMirror opens (or creates) the mirror database
+func OpenMirror(path string) (*Mirror, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, err
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{mirrorCollectionsBucket, mirrorRecordsBucket, searchTermsBucket, searchDocsBucket, searchMetaBucket, vectorsBucket, vectorsPendingBucket} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	m := &Mirror{db: db}
+	if err := m.reindex(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("reindex: %w", err)
+	}
+	return m, nil
+}
+
+func (m *Mirror) Close() error {
+	return m.db.Close()
+}
+
+// updatedAt is a record's modification time, if SyncHub reported one
+func (r *mirrorRecord) updatedAt() (time.Time, bool) {
+	for key, v := range r.Fields {
+		if normalizeKey(key) == "updated_at" {
+			if s, ok := v.(string); ok {
+				return parseQueryTime(s)
+			}
+		}
+	}
+	return time.Time{}, false
+}
+
+// PutSchemas stores collection schemas from a list_collections result
+func (m *Mirror) PutSchemas(listResult json.RawMessage) error {
+	var list struct {
+		Collections map[string]struct {
+			Schema map[string]interface{} `json:"schema"`
+			Fields []CollectionField      `json:"fields"`
+		} `json:"collections"`
+	}
+	if err := json.Unmarshal(listResult, &list); err != nil {
+		return err
+	}
+
+	return m.db.Update(func(tx *bolt.Tx) error {
+		for name, col := range list.Collections {
+			meta := getMirrorCollection(tx, name)
+			meta.Schema = col.Schema
+			meta.Fields = col.Fields
+			if err := putMirrorCollection(tx, meta); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// PutRecords stores and indexes records of a collection. A record only
+// replaces the stored one if it isn't older by updated_at, and keeps the
+// stored text if it has none. When complete is set, guids lists every
+// record the collection has, and the rest are removed.
+func (m *Mirror) PutRecords(collection string, records []mirrorRecord, guids []string, complete bool, at time.Time) error {
+	changed := false
+	err := m.db.Update(func(tx *bolt.Tx) error {
+		bucket, err := tx.Bucket(mirrorRecordsBucket).CreateBucketIfNotExists([]byte(collection))
+		if err != nil {
+			return err
+		}
+
+		for _, r := range records {
+			existing := bucket.Get([]byte(r.GUID))
+			if existing != nil {
+				var old mirrorRecord
+				if json.Unmarshal(existing, &old) == nil {
+					oldAt, ok1 := old.updatedAt()
+					newAt, ok2 := r.updatedAt()
+					if ok1 && ok2 && newAt.Before(oldAt) {
+						continue
+					}
+					if r.Body == nil {
+						r.Body = old.Body
+					}
+				}
+			}
+			data, err := json.Marshal(r)
+			if err != nil {
+				return err
+			}
+			if bytes.Equal(data, existing) {
+				continue
+			}
+			if err := bucket.Put([]byte(r.GUID), data); err != nil {
+				return err
+			}
+			changed = true
+			if err := indexRecord(tx, collection, r); err != nil {
+				return err
+			}
+		}
+
+		if complete {
+			keep := make(map[string]bool, len(guids))
+			for _, guid := range guids {
+				keep[guid] = true
+			}
+			var stale [][]byte
+			bucket.ForEach(func(k, v []byte) error {
+				if !keep[string(k)] {
+					stale = append(stale, append([]byte(nil), k...))
+				}
+				return nil
+			})
+			for _, k := range stale {
+				if err := bucket.Delete(k); err != nil {
+					return err
+				}
+				changed = true
+				if err := unindexRecord(tx, searchDocKey(collection, string(k))); err != nil {
+					return err
+				}
+			}
+		}
+
+		meta := getMirrorCollection(tx, collection)
+		if complete {
+			meta.SnapshotAt = at
+		}
+		meta.Records = countKeys(bucket)
+		return putMirrorCollection(tx, meta)
+	})
+	if err == nil && changed && m.OnChange != nil {
+		m.OnChange()
+	}
+	return err
+}
+
+// PutQueryResult mirrors a full get_collection_records result
+func (m *Mirror) PutQueryResult(collection string, recordsResult json.RawMessage, at time.Time) error {
+	var result struct {
+		Records []mirrorRecord `json:"records"`
+	}
+	if err := json.Unmarshal(recordsResult, &result); err != nil {
+		return err
+	}
+	guids := make([]string, len(result.Records))
+	for i, r := range result.Records {
+		guids[i] = r.GUID
+	}
+	return m.PutRecords(collection, result.Records, guids, true, at)
+}
+
+// Collections returns the mirrored collections in list_collections format,
+// for collectionSchema and collectionFields
+func (m *Mirror) Collections() (json.RawMessage, error) {
+	list := map[string]interface{}{}
+	err := m.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(mirrorCollectionsBucket).ForEach(func(k, v []byte) error {
+			var meta MirrorCollection
+			if err := json.Unmarshal(v, &meta); err != nil {
+				return nil
+			}
+			list[meta.Name] = map[string]interface{}{"schema": meta.Schema, "fields": meta.Fields}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(map[string]interface{}{"collections": list})
+}
+
+// Records returns a collection's mirrored records in get_collection_records
+// format, and when they were last known complete
+func (m *Mirror) Records(collection string) (json.RawMessage, time.Time, error) {
+	var records []json.RawMessage
+	var snapshotAt time.Time
+	err := m.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(mirrorRecordsBucket).Bucket([]byte(collection))
+		if bucket == nil {
+			return fmt.Errorf("%s has not been mirrored yet", collection)
+		}
+		snapshotAt = getMirrorCollection(tx, collection).SnapshotAt
+		return bucket.ForEach(func(k, v []byte) error {
+			records = append(records, append(json.RawMessage(nil), v...))
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	if records == nil {
+		records = []json.RawMessage{}
+	}
+	data, err := json.Marshal(map[string]interface{}{
+		"collection": collection,
+		"records":    records,
+	})
+	return data, snapshotAt, err
+}
+
+func (m *Mirror) Status() MirrorStatus {
+	var status MirrorStatus
+	m.db.View(func(tx *bolt.Tx) error {
+		status.Indexed = getSearchStats(tx).Docs
+		return tx.Bucket(mirrorCollectionsBucket).ForEach(func(k, v []byte) error {
+			var meta MirrorCollection
+			if json.Unmarshal(v, &meta) != nil || meta.SnapshotAt.IsZero() {
+				return nil
+			}
+			status.Collections++
+			status.Records += meta.Records
+			if status.LastSnapshot == nil || meta.SnapshotAt.After(*status.LastSnapshot) {
+				at := meta.SnapshotAt
+				status.LastSnapshot = &at
+			}
+			return nil
+		})
+	})
+	return status
+}
+
+// Snapshot pulls records through the snapshot_collection tool, one
+// collection at a time: all of them when full is set, otherwise those
+// changed since the collection's last snapshot. updated_at often comes
+// from the source system rather than Thymer, so a record synced late can
+// be missed by an incremental snapshot; callers take a full one now and
+// then. A collection that fails doesn't hold up the others.
+func (m *Mirror) Snapshot(bridge *Bridge, full bool) error {
+	list, err := bridge.ExecuteTool("list_collections", map[string]interface{}{})
+	if err != nil {
+		return err
+	}
+	if err := m.PutSchemas(list); err != nil {
+		return fmt.Errorf("invalid collection list: %w", err)
+	}
+	var listed struct {
+		Collections map[string]json.RawMessage `json:"collections"`
+	}
+	json.Unmarshal(list, &listed)
+	names := make([]string, 0, len(listed.Collections))
+	for name := range listed.Collections {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var errs []error
+	changed := 0
+	for _, name := range names {
+		n, err := m.snapshotCollection(bridge, name, full)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+		}
+		changed += n
+	}
+	log.Printf("[Mirror] Snapshot of %d collection(s), %d changed record(s)", len(names)-len(errs), changed)
+	return errors.Join(errs...)
+}
+
+// snapshotCollection pages through a snapshot of one collection and
+// returns how many records changed. Records missing from the collection
+// are dropped, and its snapshot time moves on, only once every page
+// arrived.
+func (m *Mirror) snapshotCollection(bridge *Bridge, name string, full bool) (int, error) {
+	args := map[string]interface{}{
+		"collection": name,
+		"bodies":     true,
+		"limit":      mirrorPageSize,
+	}
+	var since time.Time
+	m.db.View(func(tx *bolt.Tx) error {
+		since = getMirrorCollection(tx, name).SnapshotAt
+		return nil
+	})
+	if !full && !since.IsZero() {
+		args["since"] = since.Format(time.RFC3339Nano)
+	}
+
+	var guids []string
+	var snapshotAt time.Time
+	changed := 0
+	for offset := 0; ; offset += mirrorPageSize {
+		args["offset"] = offset
+		result, err := bridge.ExecuteTool("snapshot_collection", args)
+		if err != nil {
+			return changed, err
+		}
+		var page struct {
+			SnapshotAt time.Time      `json:"snapshot_at"`
+			SnapshotID string         `json:"snapshot_id"`
+			Total      int            `json:"total"`
+			GUIDs      []string       `json:"guids"`
+			Records    []mirrorRecord `json:"records"`
+			Error      string         `json:"error"`
+		}
+		if err := json.Unmarshal(result, &page); err != nil {
+			return changed, fmt.Errorf("invalid snapshot: %w", err)
+		}
+		if page.Error != "" {
+			return changed, fmt.Errorf("%s", page.Error)
+		}
+		if offset == 0 {
+			snapshotAt = page.SnapshotAt
+			args["snapshot_id"] = page.SnapshotID
+		}
+
+		guids = append(guids, page.GUIDs...)
+		if len(page.Records) > 0 {
+			if err := m.PutRecords(name, page.Records, nil, false, snapshotAt); err != nil {
+				return changed, err
+			}
+			changed += len(page.Records)
+		}
+		if offset+mirrorPageSize >= page.Total {
+			break
+		}
+	}
+	return changed, m.PutRecords(name, nil, guids, true, snapshotAt)
+}
+
+// countKeys counts a bucket's keys; Stats is stale within a write
+// transaction
+func countKeys(bucket *bolt.Bucket) int {
+	n := 0
+	c := bucket.Cursor()
+	for k, _ := c.First(); k != nil; k, _ = c.Next() {
+		n++
+	}
+	return n
+}
+
+func getMirrorCollection(tx *bolt.Tx, name string) MirrorCollection {
+	meta := MirrorCollection{Name: name}
+	if data := tx.Bucket(mirrorCollectionsBucket).Get([]byte(name)); data != nil {
+		json.Unmarshal(data, &meta)
+	}
+	return meta
+}
+
+func putMirrorCollection(tx *bolt.Tx, meta MirrorCollection) error {
+	data, err := json.Marshal(meta)
+	if err != nil {
+		return err
+	}
+	return tx.Bucket(mirrorCollectionsBucket).Put([]byte(meta.Name), data)
+}
+
+// logMirrorError keeps mirror failures from affecting the request that
+// triggered them
+func logMirrorError(what string, err error) {
+	if err != nil {
+		log.Printf("[Mirror] Failed to %s: %v", what, err)
+	}
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"path/filepath"
+	"testing"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// snapshotStub answers list_collections and snapshot_collection like
+// SyncHub, from records per collection
+type snapshotStub struct {
+	records map[string][]string // collection -> guids
+	failing map[string]bool
+	at      time.Time
+	calls   map[string][]map[string]interface{}
+}
+
+func (s *snapshotStub) bridge() *Bridge {
+	b := NewBridge(0)
+	b.AddLocalTool(Tool{Name: "list_collections"}, func(args map[string]interface{}) (interface{}, error) {
+		list := map[string]interface{}{}
+		for name := range s.records {
+			list[name] = map[string]interface{}{"fields": []CollectionField{{ID: "status", Label: "Status", Type: "text"}}}
+		}
+		return map[string]interface{}{"collections": list}, nil
+	})
+	b.AddLocalTool(Tool{Name: "snapshot_collection"}, func(args map[string]interface{}) (interface{}, error) {
+		name := args["collection"].(string)
+		s.calls[name] = append(s.calls[name], maps.Clone(args))
+		if s.failing[name] {
+			return map[string]interface{}{"error": "Snapshot expired; start again"}, nil
+		}
+		guids := s.records[name]
+		offset, limit := args["offset"].(int), args["limit"].(int)
+		page := guids[offset:min(offset+limit, len(guids))]
+		var records []mirrorRecord
+		for _, guid := range page {
+			records = append(records, mirrorRecord{GUID: guid, Title: guid, Fields: map[string]interface{}{"status": "open"}})
+		}
+		return map[string]interface{}{
+			"collection":  name,
+			"total":       len(guids),
+			"snapshot_id": name + "-1",
+			"snapshot_at": s.at,
+			"guids":       page,
+			"records":     records,
+		}, nil
+	})
+	return b
+}
+
+func guidList(prefix string, n int) []string {
+	guids := make([]string, n)
+	for i := range guids {
+		guids[i] = fmt.Sprintf("%s%04d", prefix, i)
+	}
+	return guids
+}
+
+func TestMirrorSnapshot(t *testing.T) {
+	m, err := OpenMirror(filepath.Join(t.TempDir(), "mirror.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+
+	first := time.Date(2026, 10, 18, 9, 0, 0, 0, time.UTC)
+	stub := &snapshotStub{
+		records: map[string][]string{"Issues": guidList("I", 450), "People": guidList("P", 3)},
+		failing: map[string]bool{},
+		at:      first,
+		calls:   map[string][]map[string]interface{}{},
+	}
+	bridge := stub.bridge()
+	if err := m.Snapshot(bridge, true); err != nil {
+		t.Fatalf("Snapshot: %v", err)
+	}
+
+	// Each collection is paged on its own, later pages by the first's id
+	calls := stub.calls["Issues"]
+	if len(calls) != 3 {
+		t.Fatalf("Issues took %d calls, want 3", len(calls))
+	}
+	for i, args := range calls {
+		if args["offset"] != i*mirrorPageSize || args["since"] != nil || (i > 0) != (args["snapshot_id"] == "Issues-1") {
+			t.Errorf("call %d: %v", i, args)
+		}
+	}
+	if status := m.Status(); status.Records != 453 || status.Collections != 2 {
+		t.Errorf("status = %+v", status)
+	}
+
+	// Next time each collection is asked for what changed since its own
+	// snapshot; one failing leaves the other's progress alone
+	second := first.Add(time.Hour)
+	stub.at = second
+	stub.records["Issues"] = stub.records["Issues"][:400]
+	stub.failing["People"] = true
+	stub.calls = map[string][]map[string]interface{}{}
+	if err := m.Snapshot(bridge, false); err == nil {
+		t.Error("failed collection not reported")
+	}
+	if since := stub.calls["Issues"][0]["since"]; since != first.Format(time.RFC3339Nano) {
+		t.Errorf("Issues since = %v", since)
+	}
+
+	snapshotAt := func(name string) time.Time {
+		var at time.Time
+		m.db.View(func(tx *bolt.Tx) error {
+			at = getMirrorCollection(tx, name).SnapshotAt
+			return nil
+		})
+		return at
+	}
+	if !snapshotAt("Issues").Equal(second) || !snapshotAt("People").Equal(first) {
+		t.Errorf("snapshot times: Issues %v, People %v", snapshotAt("Issues"), snapshotAt("People"))
+	}
+
+	// Records gone from the collection are dropped once all pages arrived
+	raw, _, err := m.Records("Issues")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var result struct {
+		Records []mirrorRecord `json:"records"`
+	}
+	json.Unmarshal(raw, &result)
+	if len(result.Records) != 400 {
+		t.Errorf("Issues has %d records, want 400", len(result.Records))
+	}
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// workspacePrompt is a canned request whose arguments are completed from
+// live workspace data (see Completer)
+type workspacePrompt struct {
+	prompt *mcp.Prompt
+	render func(args map[string]string) string
+}
+
+var workspacePrompts = []workspacePrompt{
+	{
+		prompt: &mcp.Prompt{
+			Name:        "open_issues",
+			Description: "Summarize open issues for a repository",
+			Arguments: []*mcp.PromptArgument{
+				{Name: "repo", Description: "Repository (owner/repo)", Required: true},
+				{Name: "assignee", Description: "Only issues assigned to this user"},
+			},
+		},
+		render: func(args map[string]string) string {
+			text := fmt.Sprintf("Summarize the open issues in %s using issues_summarize_open", args["repo"])
+			if args["assignee"] != "" {
+				text += fmt.Sprintf(", focusing on those assigned to %s", args["assignee"])
+			}
+			return text + ". Link each issue with [[GUID]]."
+		},
+	},
+	{
+		prompt: &mcp.Prompt{
+			Name:        "person_brief",
+			Description: "Brief me on a person before a meeting",
+			Arguments: []*mcp.PromptArgument{
+				{Name: "person", Description: "Name from the People collection", Required: true},
+			},
+		},
+		render: func(args map[string]string) string {
+			return fmt.Sprintf("Give me a short brief on %s: who they are, when we were last in touch, "+
+				"and any recent notes, events or captures mentioning them.", args["person"])
+		},
+	},
+	{
+		prompt: &mcp.Prompt{
+			Name:        "habit_progress",
+			Description: "Review progress on a habit",
+			Arguments: []*mcp.PromptArgument{
+				{Name: "habit", Description: "Habit name from HabitHub", Required: true},
+			},
+		},
+		render: func(args map[string]string) string {
+			return fmt.Sprintf("How am I doing with %s? Look at recent journal entries and the habit page.", args["habit"])
+		},
+	},
+	{
+		prompt: &mcp.Prompt{
+			Name:        "explore_collection",
+			Description: "Describe what's in a collection",
+			Arguments: []*mcp.PromptArgument{
+				{Name: "collection", Description: "Collection name", Required: true},
+			},
+		},
+		render: func(args map[string]string) string {
+			return fmt.Sprintf("Describe the %s collection: its fields, how many records it has, "+
+				"and a few representative examples.", args["collection"])
+		},
+	},
+}
+
+// findPrompt looks up a workspace prompt by name
+func findPrompt(name string) *workspacePrompt {
+	for i := range workspacePrompts {
+		if workspacePrompts[i].prompt.Name == name {
+			return &workspacePrompts[i]
+		}
+	}
+	return nil
+}
+
+// renderPrompt checks required arguments and builds the prompt result
+func (p *workspacePrompt) renderPrompt(args map[string]string) (*mcp.GetPromptResult, error) {
+	var missing []string
+	for _, arg := range p.prompt.Arguments {
+		if arg.Required && args[arg.Name] == "" {
+			missing = append(missing, arg.Name)
+		}
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("missing required arguments: %s", strings.Join(missing, ", "))
+	}
+
+	return &mcp.GetPromptResult{
+		Description: p.prompt.Description,
+		Messages: []*mcp.PromptMessage{
+			{Role: "user", Content: &mcp.TextContent{Text: p.render(args)}},
+		},
+	}, nil
+}
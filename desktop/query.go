@@ -0,0 +1,423 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const defaultQueryLimit = 20
+
+// queryOps are the --where operators, longest first so ">=" wins over ">"
+var queryOps = []string{"!=", ">=", "<=", "=", ">", "<", "~"}
+
+// QueryFilter is a single field predicate like "status=Open" or
+// "updated_at>2026-01-01". "~" is a case-insensitive substring match.
+type QueryFilter struct {
+	Field string
+	Op    string
+	Value string
+}
+
+// QuerySort orders results by a field, "-field" for descending
+type QuerySort struct {
+	Field string
+	Desc  bool
+}
+
+// Query is a generic collection query parsed from /api/query parameters
+type Query struct {
+	Collection string
+	Where      []QueryFilter
+	Sort       []QuerySort
+	Fields     []string
+	Limit      int
+	Offset     int
+}
+
+// QueryPage is one page of query results
+type QueryPage struct {
+	Records    []map[string]interface{}
+	Total      int
+	NextCursor string
+}
+
+// ParseFilter parses "field<op>value"
+func ParseFilter(expr string) (QueryFilter, error) {
+	best := -1
+	var op string
+	for _, candidate := range queryOps {
+		if i := strings.Index(expr, candidate); i > 0 && (best < 0 || i < best) {
+			best, op = i, candidate
+		}
+	}
+	if best < 0 {
+		return QueryFilter{}, fmt.Errorf("invalid filter %q (use field=value, field>value, field~text...)", expr)
+	}
+	return QueryFilter{
+		Field: strings.TrimSpace(expr[:best]),
+		Op:    op,
+		Value: strings.TrimSpace(expr[best+len(op):]),
+	}, nil
+}
+
+// ParseSort parses "field", "-field" or "field:desc"
+func ParseSort(expr string) QuerySort {
+	expr = strings.TrimSpace(expr)
+	if strings.HasPrefix(expr, "-") {
+		return QuerySort{Field: expr[1:], Desc: true}
+	}
+	if field, dir, ok := strings.Cut(expr, ":"); ok {
+		return QuerySort{Field: field, Desc: strings.EqualFold(dir, "desc")}
+	}
+	return QuerySort{Field: expr}
+}
+
+// EncodeCursor and DecodeCursor keep the pagination cursor opaque to clients
+func EncodeCursor(offset int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte("offset:" + strconv.Itoa(offset)))
+}
+
+func DecodeCursor(cursor string) (int, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil || !strings.HasPrefix(string(data), "offset:") {
+		return 0, fmt.Errorf("invalid cursor")
+	}
+	n, err := strconv.Atoi(strings.TrimPrefix(string(data), "offset:"))
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid cursor")
+	}
+	return n, nil
+}
+
+// normalizeKey makes "In Progress", "in-progress" and "in_progress" equal,
+// which also lines up choice labels with choice ids
+func normalizeKey(s string) string {
+	s = strings.ToLower(strings.TrimSpace(s))
+	return strings.NewReplacer(" ", "_", "-", "_").Replace(s)
+}
+
+// collectionSchema finds a collection in a list_collections result by
+// case-insensitive name and returns its canonical name and field names:
+// those its schema describes and any other field it is configured with
+func collectionSchema(listResult json.RawMessage, name string) (string, []string, error) {
+	var list struct {
+		Collections map[string]struct {
+			Schema map[string]interface{} `json:"schema"`
+			Fields []CollectionField      `json:"fields"`
+		} `json:"collections"`
+	}
+	if err := json.Unmarshal(listResult, &list); err != nil {
+		return "", nil, fmt.Errorf("invalid list_collections result: %w", err)
+	}
+
+	var available []string
+	for colName, col := range list.Collections {
+		if strings.EqualFold(colName, name) {
+			fields := make([]string, 0, len(col.Schema)+len(col.Fields))
+			for field := range col.Schema {
+				fields = append(fields, field)
+			}
+			for _, f := range col.Fields {
+				if _, ok := col.Schema[f.ID]; !ok {
+					fields = append(fields, f.ID)
+				}
+			}
+			sort.Strings(fields)
+			return colName, fields, nil
+		}
+		available = append(available, colName)
+	}
+	sort.Strings(available)
+	return "", nil, fmt.Errorf("unknown collection %q (available: %s)", name, strings.Join(available, ", "))
+}
+
+// Validate checks every field the query references against the schema.
+// Collections without a known schema accept any field.
+func (q *Query) Validate(schema []string) error {
+	check := q.fieldCheck(schema)
+	for _, f := range q.Where {
+		if err := check(f.Field); err != nil {
+			return err
+		}
+	}
+	for _, s := range q.Sort {
+		if err := check(s.Field); err != nil {
+			return err
+		}
+	}
+	for _, field := range q.Fields {
+		if err := check(field); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fieldCheck returns a check that a field is in the schema, or anything
+// goes when the schema is unknown
+func (q *Query) fieldCheck(schema []string) func(field string) error {
+	if len(schema) == 0 {
+		return func(string) error { return nil }
+	}
+
+	known := map[string]bool{"guid": true, "title": true}
+	names := []string{"guid", "title"}
+	for _, field := range schema {
+		if !known[normalizeKey(field)] {
+			known[normalizeKey(field)] = true
+			names = append(names, field)
+		}
+	}
+	return func(field string) error {
+		if !known[normalizeKey(field)] {
+			return fmt.Errorf("unknown field %q for %s (fields: %s)", field, q.Collection, strings.Join(names, ", "))
+		}
+		return nil
+	}
+}
+
+// findTool looks up a collection's _find tool
+func findTool(bridge *Bridge, collection string) (Tool, bool) {
+	name := strings.ToLower(collection) + "_find"
+	tools := bridge.GetTools()
+	i := slices.IndexFunc(tools, func(t Tool) bool { return t.Name == name })
+	if i < 0 {
+		return Tool{}, false
+	}
+	return tools[i], true
+}
+
+// toolParam reports whether a tool takes a parameter
+func toolParam(tool Tool, name string) bool {
+	params, _ := tool.Parameters["properties"].(map[string]interface{})
+	_, ok := params[name]
+	return ok
+}
+
+// findArgs are arguments for a collection's _find tool that select at least
+// the query's matches, or false when the tool can't: only "=" filters on
+// its parameters are pushed down, and only without a sort, since _find has
+// an order of its own. The limit asks for one record past the page, to
+// tell whether there is a next one.
+func (q *Query) findArgs(tool Tool) (map[string]interface{}, bool) {
+	params, _ := tool.Parameters["properties"].(map[string]interface{})
+	if params == nil || len(q.Sort) > 0 || q.Limit == 0 {
+		return nil, false
+	}
+
+	args := map[string]interface{}{"limit": q.Offset + q.Limit + 1}
+	for _, f := range q.Where {
+		key := normalizeKey(f.Field)
+		param, ok := params[key].(map[string]interface{})
+		if !ok || f.Op != "=" || key == "sort" || key == "offset" || args[key] != nil {
+			return nil, false
+		}
+		value := f.Value
+		if enum, ok := param["enum"].([]interface{}); ok {
+			// _find takes the labels it lists
+			i := slices.IndexFunc(enum, func(e interface{}) bool {
+				return normalizeKey(fmt.Sprint(e)) == normalizeKey(value)
+			})
+			if i < 0 {
+				return nil, false
+			}
+			value = fmt.Sprint(enum[i])
+		}
+		args[key] = value
+	}
+	return args, true
+}
+
+// findPage picks the page's guids from a _find result, checking the filters
+// again since _find may match loosely, e.g. by substring. total is -1 when
+// _find stopped at the limit. It returns false when the result can't
+// answer the query: its records lack a filtered field, or the check threw
+// out matches of a result that stopped at the limit.
+func (q *Query) findPage(findResult json.RawMessage, limit int) (guids []string, total int, next string, ok bool) {
+	var found []map[string]interface{}
+	if err := json.Unmarshal(findResult, &found); err != nil {
+		return nil, 0, "", false
+	}
+
+	var matched []string
+	for _, r := range found {
+		record := make(map[string]interface{}, len(r))
+		for k, v := range r {
+			record[normalizeKey(k)] = v
+		}
+		for _, f := range q.Where {
+			if _, ok := record[normalizeKey(f.Field)]; !ok {
+				return nil, 0, "", false
+			}
+		}
+		guid, _ := record["guid"].(string)
+		if guid == "" {
+			return nil, 0, "", false
+		}
+		if q.matches(record) {
+			matched = append(matched, guid)
+		}
+	}
+
+	total = len(matched)
+	if len(found) >= limit {
+		if len(matched) < limit {
+			return nil, 0, "", false
+		}
+		total = -1
+	}
+	start := min(q.Offset, len(matched))
+	end := min(start+q.Limit, len(matched))
+	if end < len(matched) {
+		next = EncodeCursor(end)
+	}
+	return matched[start:end], total, next, true
+}
+
+// Apply filters, sorts, paginates and projects records from
+// get_collection_records
+func (q *Query) Apply(recordsResult json.RawMessage) (*QueryPage, error) {
+	var result struct {
+		Records []struct {
+			GUID   string                 `json:"guid"`
+			Title  string                 `json:"title"`
+			Fields map[string]interface{} `json:"fields"`
+		} `json:"records"`
+		Error string `json:"error"`
+	}
+	if err := json.Unmarshal(recordsResult, &result); err != nil {
+		return nil, fmt.Errorf("invalid records result: %w", err)
+	}
+	if result.Error != "" {
+		return nil, fmt.Errorf("%s", result.Error)
+	}
+
+	// Flatten to {guid, title, field...} keyed by normalized name
+	var matched []map[string]interface{}
+	for _, r := range result.Records {
+		record := map[string]interface{}{"guid": r.GUID, "title": r.Title}
+		for k, v := range r.Fields {
+			record[normalizeKey(k)] = v
+		}
+		if q.matches(record) {
+			matched = append(matched, record)
+		}
+	}
+
+	if len(q.Sort) > 0 {
+		sort.SliceStable(matched, func(i, j int) bool {
+			for _, s := range q.Sort {
+				key := normalizeKey(s.Field)
+				a, aok := matched[i][key]
+				b, bok := matched[j][key]
+				if aok != bok {
+					return aok // missing values last
+				}
+				if c := compareValues(a, b); c != 0 {
+					if s.Desc {
+						return c > 0
+					}
+					return c < 0
+				}
+			}
+			return false
+		})
+	}
+
+	page := &QueryPage{Total: len(matched), Records: []map[string]interface{}{}}
+	start := min(max(q.Offset, 0), len(matched))
+	end := len(matched)
+	if q.Limit > 0 && start+q.Limit < end {
+		end = start + q.Limit
+		page.NextCursor = EncodeCursor(end)
+	}
+
+	for _, record := range matched[start:end] {
+		if len(q.Fields) > 0 {
+			projected := map[string]interface{}{"guid": record["guid"]}
+			for _, field := range q.Fields {
+				if v, ok := record[normalizeKey(field)]; ok {
+					projected[field] = v
+				}
+			}
+			record = projected
+		}
+		page.Records = append(page.Records, record)
+	}
+	return page, nil
+}
+
+func (q *Query) matches(record map[string]interface{}) bool {
+	for _, f := range q.Where {
+		value, ok := record[normalizeKey(f.Field)]
+		if !ok {
+			// Only "!=" can match a field the record doesn't have
+			if f.Op != "!=" {
+				return false
+			}
+			continue
+		}
+
+		var match bool
+		switch f.Op {
+		case "=":
+			match = compareValues(value, f.Value) == 0
+		case "!=":
+			match = compareValues(value, f.Value) != 0
+		case ">":
+			match = compareValues(value, f.Value) > 0
+		case ">=":
+			match = compareValues(value, f.Value) >= 0
+		case "<":
+			match = compareValues(value, f.Value) < 0
+		case "<=":
+			match = compareValues(value, f.Value) <= 0
+		case "~":
+			match = strings.Contains(strings.ToLower(fmt.Sprint(value)), strings.ToLower(f.Value))
+		}
+		if !match {
+			return false
+		}
+	}
+	return true
+}
+
+// compareValues compares as numbers, then dates, then normalized strings
+func compareValues(a, b interface{}) int {
+	as, bs := fmt.Sprint(a), fmt.Sprint(b)
+
+	if af, err := strconv.ParseFloat(as, 64); err == nil {
+		if bf, err := strconv.ParseFloat(bs, 64); err == nil {
+			switch {
+			case af < bf:
+				return -1
+			case af > bf:
+				return 1
+			}
+			return 0
+		}
+	}
+
+	if at, ok := parseQueryTime(as); ok {
+		if bt, ok := parseQueryTime(bs); ok {
+			return at.Compare(bt)
+		}
+	}
+
+	return strings.Compare(normalizeKey(as), normalizeKey(bs))
+}
+
+func parseQueryTime(s string) (time.Time, bool) {
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02T15:04", "2006-01-02"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var issuesFindTool = Tool{
+	Name: "issues_find",
+	Parameters: map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"state": map[string]interface{}{"type": "string", "enum": []interface{}{"Open", "Closed"}},
+			"repo":  map[string]interface{}{"type": "string"},
+			"limit": map[string]interface{}{"type": "number"},
+		},
+	},
+}
+
+// findStub answers issues_find and get_collection_records from issues
+// in repos acme/web, acme/web-old and acme/api in turn, and records each
+// call
+func findStub(n int, calls *[]map[string]interface{}) *Bridge {
+	repos := []string{"acme/web", "acme/web-old", "acme/api"}
+	issues := make([]map[string]interface{}, n)
+	for i := range issues {
+		issues[i] = map[string]interface{}{"guid": fmt.Sprintf("I%d", i), "title": fmt.Sprintf("Issue %d", i), "state": "Open", "repo": repos[i%len(repos)]}
+	}
+
+	b := NewBridge(0)
+	b.AddLocalTool(issuesFindTool, func(args map[string]interface{}) (interface{}, error) {
+		*calls = append(*calls, maps.Clone(args))
+		// Matches repo by substring, like the real one
+		var found []map[string]interface{}
+		for _, issue := range issues {
+			if len(found) < args["limit"].(int) && strings.Contains(issue["repo"].(string), fmt.Sprint(args["repo"])) {
+				found = append(found, issue)
+			}
+		}
+		return found, nil
+	})
+	b.AddLocalTool(Tool{Name: "get_collection_records"}, func(args map[string]interface{}) (interface{}, error) {
+		*calls = append(*calls, maps.Clone(args))
+		var records []mirrorRecord
+		for _, guid := range args["guids"].([]string) {
+			records = append(records, mirrorRecord{GUID: guid, Title: guid, Fields: map[string]interface{}{"state": "open"}})
+		}
+		return map[string]interface{}{"collection": "Issues", "records": records}, nil
+	})
+	return b
+}
+
+func TestFindArgs(t *testing.T) {
+	tests := []struct {
+		query *Query
+		args  map[string]interface{}
+	}{
+		{&Query{Limit: 20, Where: []QueryFilter{{Field: "state", Op: "=", Value: "open"}}}, map[string]interface{}{"state": "Open", "limit": 21}},
+		{&Query{Limit: 5, Offset: 10, Where: []QueryFilter{{Field: "Repo", Op: "=", Value: "acme/web"}}}, map[string]interface{}{"repo": "acme/web", "limit": 16}},
+		{&Query{Limit: 20, Where: []QueryFilter{{Field: "state", Op: "=", Value: "stale"}}}, nil},
+		{&Query{Limit: 20, Where: []QueryFilter{{Field: "state", Op: "!=", Value: "open"}}}, nil},
+		{&Query{Limit: 20, Where: []QueryFilter{{Field: "title", Op: "=", Value: "Fix"}}}, nil},
+		{&Query{Limit: 20, Sort: []QuerySort{{Field: "title"}}}, nil},
+		{&Query{Limit: 0}, nil},
+	}
+	for _, tt := range tests {
+		args, ok := tt.query.findArgs(issuesFindTool)
+		if ok != (tt.args != nil) || (ok && !maps.Equal(args, tt.args)) {
+			t.Errorf("%+v: %v, %v; want %v", tt.query, args, ok, tt.args)
+		}
+	}
+}
+
+func TestFindQuery(t *testing.T) {
+	var calls []map[string]interface{}
+	a := &App{bridge: findStub(9, &calls)}
+	query := func(repo string, offset, limit int) *Query {
+		return &Query{Collection: "Issues", Offset: offset, Limit: limit, Where: []QueryFilter{{Field: "repo", Op: "=", Value: repo}}}
+	}
+
+	// _find's substring match is checked again, and only the page is fetched
+	page, err := a.findQuery(query("acme/web", 1, 5))
+	if err != nil || page == nil {
+		t.Fatalf("findQuery: %v, %v", page, err)
+	}
+	if len(page.Records) != 2 || page.Records[0]["guid"] != "I3" || page.Records[1]["guid"] != "I6" {
+		t.Errorf("records = %v", page.Records)
+	}
+	if page.Total != 3 || page.NextCursor != "" {
+		t.Errorf("total %d, next %q", page.Total, page.NextCursor)
+	}
+	if fetched := calls[len(calls)-1]["guids"].([]string); len(fetched) != 2 {
+		t.Errorf("fetched %v", fetched)
+	}
+
+	// Stopped at the limit, the total is unknown
+	page, err = a.findQuery(query("acme/api", 0, 2))
+	if err != nil || page == nil || len(page.Records) != 2 || page.Total != -1 || page.NextCursor != EncodeCursor(2) {
+		t.Errorf("truncated page = %+v, %v", page, err)
+	}
+
+	// Stopped at the limit with matches thrown out, it falls back
+	if page, err := a.findQuery(query("acme/web", 1, 1)); page != nil || err != nil {
+		t.Errorf("lossy page = %+v, %v", page, err)
+	}
+}
+
+func TestQueryParams(t *testing.T) {
+	m, err := OpenMirror(filepath.Join(t.TempDir(), "mirror.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+	list, _ := json.Marshal(map[string]interface{}{"collections": map[string]interface{}{
+		"Issues": map[string]interface{}{
+			"schema": map[string]interface{}{"state": "Open | Closed"},
+			"fields": []CollectionField{{ID: "state", Label: "State", Type: "choice"}, {ID: "updated_at", Label: "Updated", Type: "datetime"}},
+		},
+	}})
+	if err := m.PutSchemas(list); err != nil {
+		t.Fatal(err)
+	}
+	a := &App{mirror: m}
+
+	tests := []struct {
+		query string
+		code  int
+		err   string
+	}{
+		{"", http.StatusBadRequest, "collection parameter required"},
+		{"collection=Issues&stat=open", http.StatusBadRequest, `unknown query parameter "stat"`},
+		{"collection=Issues&limt=5", http.StatusBadRequest, `unknown query parameter "limt"`},
+		{"collection=Issues&where=stat=open", http.StatusBadRequest, `unknown field "stat"`},
+		// Fields the schema doesn't describe are still fields; the mirror
+		// just has no records yet
+		{"collection=Issues&updated_at=2026-10-18", http.StatusServiceUnavailable, ""},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		a.handleQuery(rec, httptest.NewRequest("GET", "/api/query?"+tt.query, nil))
+		var body struct {
+			Error string `json:"error"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+			t.Errorf("%s: not JSON: %s", tt.query, rec.Body.String())
+		}
+		if rec.Code != tt.code || !strings.Contains(body.Error, tt.err) {
+			t.Errorf("%s: %d %s", tt.query, rec.Code, rec.Body.String())
+		}
+	}
+}
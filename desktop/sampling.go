@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+const (
+	// sampleTimeout is generous because clients may ask the user to approve
+	// each sampling request before running it.
+	sampleTimeout = 2 * time.Minute
+
+	defaultSampleMaxTokens = 1024
+)
+
+// SampleRequest is a completion request sent by SyncHub over the bridge.
+// Plugins use it to borrow the LLM of a connected MCP client.
+type SampleRequest struct {
+	Messages      []SampleMessage `json:"messages"`
+	SystemPrompt  string          `json:"systemPrompt,omitempty"`
+	MaxTokens     int64           `json:"maxTokens,omitempty"`
+	Temperature   float64         `json:"temperature,omitempty"`
+	StopSequences []string        `json:"stopSequences,omitempty"`
+	ModelHints    []string        `json:"modelHints,omitempty"`
+}
+
+// SampleMessage is a single text message in a sampling conversation
+type SampleMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// SampleResult is the completion returned to SyncHub
+type SampleResult struct {
+	Role       string `json:"role"`
+	Content    string `json:"content"`
+	Model      string `json:"model"`
+	StopReason string `json:"stopReason,omitempty"`
+}
+
+// samplingSession returns a connected client session that supports sampling.
+// Only sessions on the stateful /mcp endpoint qualify - the stateless
+// endpoint has no channel back to the client.
+func (m *MCPServer) samplingSession() *mcp.ServerSession {
+	if m.server == nil {
+		return nil
+	}
+	for session := range m.server.Sessions() {
+		params := session.InitializeParams()
+		if params != nil && params.Capabilities != nil && params.Capabilities.Sampling != nil {
+			return session
+		}
+	}
+	return nil
+}
+
+// SamplingAvailable reports whether a sampling-capable client is connected
+func (m *MCPServer) SamplingAvailable() bool {
+	return m.samplingSession() != nil
+}
+
+// Sample forwards a request as sampling/createMessage to a connected client
+func (m *MCPServer) Sample(ctx context.Context, req SampleRequest) (*SampleResult, error) {
+	session := m.samplingSession()
+	if session == nil {
+		return nil, fmt.Errorf("no connected MCP client supports sampling")
+	}
+	if len(req.Messages) == 0 {
+		return nil, fmt.Errorf("messages required")
+	}
+
+	params := &mcp.CreateMessageParams{
+		MaxTokens:     req.MaxTokens,
+		SystemPrompt:  req.SystemPrompt,
+		Temperature:   req.Temperature,
+		StopSequences: req.StopSequences,
+	}
+	if params.MaxTokens <= 0 {
+		params.MaxTokens = defaultSampleMaxTokens
+	}
+	for _, msg := range req.Messages {
+		role := mcp.Role(msg.Role)
+		if role != "assistant" {
+			role = "user"
+		}
+		params.Messages = append(params.Messages, &mcp.SamplingMessage{
+			Role:    role,
+			Content: &mcp.TextContent{Text: msg.Content},
+		})
+	}
+	if len(req.ModelHints) > 0 {
+		params.ModelPreferences = &mcp.ModelPreferences{}
+		for _, hint := range req.ModelHints {
+			params.ModelPreferences.Hints = append(params.ModelPreferences.Hints, &mcp.ModelHint{Name: hint})
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, sampleTimeout)
+	defer cancel()
+
+	log.Printf("[MCP] Forwarding sampling request (%d messages) to client", len(params.Messages))
+	result, err := session.CreateMessage(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+
+	text, ok := result.Content.(*mcp.TextContent)
+	if !ok {
+		return nil, fmt.Errorf("client returned non-text content")
+	}
+	return &SampleResult{
+		Role:       string(result.Role),
+		Content:    text.Text,
+		Model:      result.Model,
+		StopReason: result.StopReason,
+	}, nil
+}
@@ -0,0 +1,287 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// scheduleCheckInterval is how often the scheduler looks for due syncs.
+// Checking against each entry's next run time, rather than matching the
+// current minute, means a sleeping laptop still syncs once on wake.
+const scheduleCheckInterval = 15 * time.Second
+
+// ScheduleEntryStatus describes one scheduled sync for /api/status
+type ScheduleEntryStatus struct {
+	Plugin  string     `json:"plugin"` // "all" syncs every plugin
+	Cron    string     `json:"cron"`
+	NextRun *time.Time `json:"next_run,omitempty"`
+	Pending string     `json:"pending,omitempty"` // Why a due run is waiting
+	LastRun *time.Time `json:"last_run,omitempty"`
+	LastJob string     `json:"last_job,omitempty"`
+	Error   string     `json:"error,omitempty"` // Invalid cron expression
+}
+
+// ScheduleStatus is the scheduler's state for /api/status
+type ScheduleStatus struct {
+	QuietHours string                `json:"quiet_hours,omitempty"`
+	Quiet      bool                  `json:"quiet"` // Inside quiet hours now
+	Entries    []ScheduleEntryStatus `json:"entries"`
+}
+
+type scheduleEntry struct {
+	plugin  string
+	expr    string
+	cron    *cronSchedule
+	err     string
+	next    time.Time
+	pending bool
+	lastRun *time.Time
+	lastJob string
+}
+
+// quietHours is a daily window, in minutes since midnight, when scheduled
+// syncs wait. start > end wraps past midnight.
+type quietHours struct {
+	start, end int
+}
+
+// Scheduler triggers syncs on the cron schedule from the config. Runs that
+// come due while SyncHub is disconnected or during quiet hours are queued
+// and run once when possible.
+type Scheduler struct {
+	startSync func(plugin string) (SyncJob, error)
+	connected func() bool
+	running   func(plugin string) bool
+
+	mu      sync.Mutex
+	entries []*scheduleEntry
+	quiet   *quietHours
+	quietV  string
+
+	wake chan struct{}
+	stop chan struct{}
+}
+
+// NewScheduler builds a scheduler from the config. Invalid entries are
+// logged and reported in the status rather than stopping thymer-bar.
+func NewScheduler(cfg *ScheduleConfig, startSync func(string) (SyncJob, error), connected func() bool, running func(string) bool) *Scheduler {
+	s := &Scheduler{
+		startSync: startSync,
+		connected: connected,
+		running:   running,
+		wake:      make(chan struct{}, 1),
+		stop:      make(chan struct{}),
+	}
+	if cfg == nil {
+		return s
+	}
+
+	if cfg.QuietHours != "" {
+		quiet, err := parseQuietHours(cfg.QuietHours)
+		if err != nil {
+			log.Printf("[Schedule] Ignoring quietHours: %v", err)
+		} else {
+			s.quiet = quiet
+			s.quietV = cfg.QuietHours
+		}
+	}
+
+	now := time.Now()
+	for plugin, expr := range cfg.Plugins {
+		e := &scheduleEntry{plugin: plugin, expr: expr}
+		cron, err := parseCron(expr)
+		if err != nil {
+			log.Printf("[Schedule] Ignoring %s: %v", plugin, err)
+			e.err = err.Error()
+		} else {
+			e.cron = cron
+			e.next = cron.Next(now)
+		}
+		s.entries = append(s.entries, e)
+	}
+	sort.Slice(s.entries, func(i, j int) bool {
+		return s.entries[i].plugin < s.entries[j].plugin
+	})
+	return s
+}
+
+// Run checks for due syncs until Stop is called
+func (s *Scheduler) Run() {
+	if len(s.entries) == 0 {
+		return
+	}
+	log.Printf("[Schedule] %d scheduled sync(s)", len(s.entries))
+
+	go func() {
+		ticker := time.NewTicker(scheduleCheckInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+			case <-s.wake:
+			case <-s.stop:
+				return
+			}
+			s.check(time.Now())
+		}
+	}()
+}
+
+func (s *Scheduler) Stop() {
+	close(s.stop)
+}
+
+// Kick runs queued syncs now, e.g. when SyncHub reconnects
+func (s *Scheduler) Kick() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (s *Scheduler) check(now time.Time) {
+	s.mu.Lock()
+	for _, e := range s.entries {
+		if e.cron == nil || e.next.IsZero() || now.Before(e.next) {
+			continue
+		}
+		// Several missed runs collapse into one
+		e.pending = true
+		e.next = e.cron.Next(now)
+	}
+
+	if s.quiet.contains(now) || !s.connected() {
+		s.mu.Unlock()
+		return
+	}
+
+	var due []*scheduleEntry
+	for _, e := range s.entries {
+		if e.pending {
+			e.pending = false
+			due = append(due, e)
+		}
+	}
+	s.mu.Unlock()
+
+	for _, e := range due {
+		plugin := e.plugin
+		if plugin == "all" {
+			plugin = ""
+		}
+		if s.running(plugin) {
+			log.Printf("[Schedule] %s is already syncing, skipping", e.plugin)
+			continue
+		}
+		log.Printf("[Schedule] Syncing %s", e.plugin)
+		go func(e *scheduleEntry) {
+			job, err := s.startSync(plugin)
+			if err != nil {
+				log.Printf("[Schedule] Sync %s failed: %v", e.plugin, err)
+			}
+			s.mu.Lock()
+			ran := job.StartedAt
+			e.lastRun = &ran
+			e.lastJob = job.ID
+			s.mu.Unlock()
+		}(e)
+	}
+}
+
+// Status reports each entry's next run, accounting for quiet hours
+func (s *Scheduler) Status() ScheduleStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	status := ScheduleStatus{
+		QuietHours: s.quietV,
+		Quiet:      s.quiet.contains(now),
+		Entries:    []ScheduleEntryStatus{},
+	}
+	connected := s.connected()
+	for _, e := range s.entries {
+		es := ScheduleEntryStatus{
+			Plugin:  e.plugin,
+			Cron:    e.expr,
+			LastRun: e.lastRun,
+			LastJob: e.lastJob,
+			Error:   e.err,
+		}
+		if e.pending {
+			switch {
+			case !connected:
+				es.Pending = "SyncHub disconnected"
+			case status.Quiet:
+				es.Pending = "quiet hours"
+			default:
+				es.Pending = "starting"
+			}
+		}
+		if !e.next.IsZero() {
+			next := e.next
+			if s.quiet.contains(next) {
+				next = s.quiet.endAfter(next)
+			}
+			es.NextRun = &next
+		}
+		status.Entries = append(status.Entries, es)
+	}
+	return status
+}
+
+// parseQuietHours parses "22:00-07:00"
+func parseQuietHours(v string) (*quietHours, error) {
+	parts := strings.Split(v, "-")
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("%q is not HH:MM-HH:MM", v)
+	}
+	start, err := parseClock(parts[0])
+	if err != nil {
+		return nil, err
+	}
+	end, err := parseClock(parts[1])
+	if err != nil {
+		return nil, err
+	}
+	return &quietHours{start: start, end: end}, nil
+}
+
+func parseClock(v string) (int, error) {
+	v = strings.TrimSpace(v)
+	hm := strings.Split(v, ":")
+	if len(hm) != 2 {
+		return 0, fmt.Errorf("%q is not HH:MM", v)
+	}
+	h, err1 := strconv.Atoi(hm[0])
+	m, err2 := strconv.Atoi(hm[1])
+	if err1 != nil || err2 != nil || h < 0 || h > 23 || m < 0 || m > 59 {
+		return 0, fmt.Errorf("%q is not HH:MM", v)
+	}
+	return h*60 + m, nil
+}
+
+func (q *quietHours) contains(t time.Time) bool {
+	if q == nil {
+		return false
+	}
+	m := t.Hour()*60 + t.Minute()
+	if q.start <= q.end {
+		return m >= q.start && m < q.end
+	}
+	return m >= q.start || m < q.end
+}
+
+// endAfter returns when the quiet hours containing t end
+func (q *quietHours) endAfter(t time.Time) time.Time {
+	end := time.Date(t.Year(), t.Month(), t.Day(), q.end/60, q.end%60, 0, 0, t.Location())
+	if !end.After(t) {
+		end = end.AddDate(0, 0, 1)
+	}
+	return end
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestQuietHours(t *testing.T) {
+	at := func(s string) time.Time {
+		v, err := time.Parse("2006-01-02 15:04", s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return v
+	}
+	tests := []struct {
+		window, at string
+		quiet      bool
+		end        string
+	}{
+		{"22:00-07:00", "2026-10-16 23:30", true, "2026-10-17 07:00"},
+		{"22:00-07:00", "2026-10-17 06:59", true, "2026-10-17 07:00"},
+		{"22:00-07:00", "2026-10-17 07:00", false, ""},
+		{"22:00-07:00", "2026-10-16 21:59", false, ""},
+		{"12:00-13:30", "2026-10-16 12:00", true, "2026-10-16 13:30"},
+		{"12:00-13:30", "2026-10-16 13:30", false, ""},
+	}
+	for _, tt := range tests {
+		q, err := parseQuietHours(tt.window)
+		if err != nil {
+			t.Fatalf("parseQuietHours(%q): %v", tt.window, err)
+		}
+		now := at(tt.at)
+		if got := q.contains(now); got != tt.quiet {
+			t.Errorf("%s contains %s = %v", tt.window, tt.at, got)
+		}
+		if tt.quiet {
+			if end := q.endAfter(now); !end.Equal(at(tt.end)) {
+				t.Errorf("%s at %s ends %s, want %s", tt.window, tt.at, end, tt.end)
+			}
+		}
+	}
+
+	for _, v := range []string{"", "22:00", "22-07", "24:00-07:00", "22:00-07:60"} {
+		if _, err := parseQuietHours(v); err == nil {
+			t.Errorf("parseQuietHours(%q) succeeded", v)
+		}
+	}
+}
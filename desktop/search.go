@@ -0,0 +1,639 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// The search index lives in the mirror database next to the records it
+// covers and is updated in the same transaction:
+//
+//	"search_terms": term -> bucket of doc key -> searchPostings
+//	"search_docs":  doc key -> searchDoc, to unindex a record
+//	"search_meta":  "stats" -> searchStats, "version" -> searchIndexVersion
+//
+// A doc key is "collection\x00guid".
+var (
+	searchTermsBucket = []byte("search_terms")
+	searchDocsBucket  = []byte("search_docs")
+	searchMetaBucket  = []byte("search_meta")
+)
+
+// searchIndexVersion is bumped when the index format changes, so existing
+// mirrors are reindexed on start
+const searchIndexVersion = "2"
+
+// Field weights for ranking; other fields weigh searchFieldBoost
+const (
+	searchTitleBoost = 3.0
+	searchFieldBoost = 1.5
+	searchBodyBoost  = 1.0
+)
+
+// BM25 parameters
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+)
+
+const defaultSearchLimit = 10
+
+// searchPostings maps a field ("title", "body" or a field id) to the
+// positions of a term in it
+type searchPostings map[string][]int
+
+type searchDoc struct {
+	Collection string   `json:"collection"`
+	GUID       string   `json:"guid"`
+	Length     int      `json:"length"`
+	Terms      []string `json:"terms"`
+}
+
+type searchStats struct {
+	Docs   int `json:"docs"`
+	Tokens int `json:"tokens"`
+}
+
+// SearchResult is one match from the local index
+type SearchResult struct {
+	GUID       string  `json:"guid"`
+	Title      string  `json:"title"`
+	Collection string  `json:"collection"`
+	Snippet    string  `json:"snippet"`
+	Score      float64 `json:"score"`
+	Similarity float64 `json:"similarity,omitempty"` // Semantic search only
+}
+
+// searchClause is one part of a query: a word, a "quoted phrase", either
+// optionally scoped to a field (repo:foo, title:"release notes") or
+// excluded with a leading -
+type searchClause struct {
+	field  string // normalized field, "" for any
+	terms  []string
+	negate bool
+}
+
+func searchDocKey(collection, guid string) []byte {
+	return []byte(collection + "\x00" + guid)
+}
+
+// tokenize lowercases text and splits it into words of letters and digits
+func tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// searchText flattens a field value into text
+func searchText(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case []interface{}:
+		parts := make([]string, 0, len(v))
+		for _, item := range v {
+			parts = append(parts, searchText(item))
+		}
+		return strings.Join(parts, " ")
+	case map[string]interface{}:
+		data, _ := json.Marshal(v)
+		return string(data)
+	}
+	return fmt.Sprint(v)
+}
+
+// dataURL matches a base64 data: URL, the way files were once attached to
+// captures. Their text would only fill the index with noise.
+var dataURL = regexp.MustCompile(`data:[\w.+-]+/[\w.+-]+(;[\w.+-]+=[\w.+-]+)*;base64,[A-Za-z0-9+/=]*`)
+
+// bodyText is a record's body without data: URLs
+func bodyText(r mirrorRecord) string {
+	if r.Body == nil {
+		return ""
+	}
+	return dataURL.ReplaceAllString(*r.Body, "")
+}
+
+// recordTexts is the searchable text of a record by field
+func recordTexts(r mirrorRecord) map[string]string {
+	texts := map[string]string{"title": r.Title}
+	if r.Body != nil {
+		texts["body"] = bodyText(r)
+	}
+	for k, v := range r.Fields {
+		if key := normalizeKey(k); key != "title" && key != "body" {
+			texts[key] = searchText(v)
+		}
+	}
+	return texts
+}
+
+// indexRecord replaces a record's entries in the index
+func indexRecord(tx *bolt.Tx, collection string, r mirrorRecord) error {
+	key := searchDocKey(collection, r.GUID)
+	if err := unindexRecord(tx, key); err != nil {
+		return err
+	}
+
+	postings := map[string]searchPostings{}
+	length := 0
+	for field, text := range recordTexts(r) {
+		for pos, term := range tokenize(text) {
+			if postings[term] == nil {
+				postings[term] = searchPostings{}
+			}
+			postings[term][field] = append(postings[term][field], pos)
+			length++
+		}
+	}
+
+	terms := tx.Bucket(searchTermsBucket)
+	doc := searchDoc{Collection: collection, GUID: r.GUID, Length: length}
+	for term, p := range postings {
+		bucket, err := terms.CreateBucketIfNotExists([]byte(term))
+		if err != nil {
+			return err
+		}
+		data, err := json.Marshal(p)
+		if err != nil {
+			return err
+		}
+		if err := bucket.Put(key, data); err != nil {
+			return err
+		}
+		doc.Terms = append(doc.Terms, term)
+	}
+
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	if err := tx.Bucket(searchDocsBucket).Put(key, data); err != nil {
+		return err
+	}
+	if err := queueEmbedding(tx, key); err != nil {
+		return err
+	}
+	return updateSearchStats(tx, 1, length)
+}
+
+// unindexRecord removes a record from the index, if it is there
+func unindexRecord(tx *bolt.Tx, key []byte) error {
+	if err := dropEmbedding(tx, key); err != nil {
+		return err
+	}
+	docs := tx.Bucket(searchDocsBucket)
+	data := docs.Get(key)
+	if data == nil {
+		return nil
+	}
+	var doc searchDoc
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return docs.Delete(key)
+	}
+
+	terms := tx.Bucket(searchTermsBucket)
+	for _, term := range doc.Terms {
+		bucket := terms.Bucket([]byte(term))
+		if bucket == nil {
+			continue
+		}
+		if err := bucket.Delete(key); err != nil {
+			return err
+		}
+		if k, _ := bucket.Cursor().First(); k == nil {
+			if err := terms.DeleteBucket([]byte(term)); err != nil {
+				return err
+			}
+		}
+	}
+	if err := docs.Delete(key); err != nil {
+		return err
+	}
+	return updateSearchStats(tx, -1, -doc.Length)
+}
+
+func getSearchStats(tx *bolt.Tx) searchStats {
+	var stats searchStats
+	if data := tx.Bucket(searchMetaBucket).Get([]byte("stats")); data != nil {
+		json.Unmarshal(data, &stats)
+	}
+	return stats
+}
+
+func updateSearchStats(tx *bolt.Tx, docs, tokens int) error {
+	stats := getSearchStats(tx)
+	stats.Docs += docs
+	stats.Tokens += tokens
+	data, err := json.Marshal(stats)
+	if err != nil {
+		return err
+	}
+	return tx.Bucket(searchMetaBucket).Put([]byte("stats"), data)
+}
+
+// reindex rebuilds the index from the mirrored records when it was built
+// by another version, or not at all
+func (m *Mirror) reindex() error {
+	return m.db.Update(func(tx *bolt.Tx) error {
+		meta := tx.Bucket(searchMetaBucket)
+		if string(meta.Get([]byte("version"))) == searchIndexVersion {
+			return nil
+		}
+
+		for _, name := range [][]byte{searchTermsBucket, searchDocsBucket} {
+			if err := tx.DeleteBucket(name); err != nil {
+				return err
+			}
+			if _, err := tx.CreateBucket(name); err != nil {
+				return err
+			}
+		}
+		if err := meta.Delete([]byte("stats")); err != nil {
+			return err
+		}
+
+		err := tx.Bucket(mirrorRecordsBucket).ForEach(func(name, _ []byte) error {
+			collection := string(name)
+			return tx.Bucket(mirrorRecordsBucket).Bucket(name).ForEach(func(_, v []byte) error {
+				var r mirrorRecord
+				if json.Unmarshal(v, &r) != nil {
+					return nil
+				}
+				return indexRecord(tx, collection, r)
+			})
+		})
+		if err != nil {
+			return err
+		}
+		return meta.Put([]byte("version"), []byte(searchIndexVersion))
+	})
+}
+
+// parseSearchQuery splits a query into clauses. Words and phrases must all
+// match; -word excludes records that contain it.
+func parseSearchQuery(query string) ([]searchClause, error) {
+	var clauses []searchClause
+	rest := strings.TrimSpace(query)
+	for rest != "" {
+		var c searchClause
+		if rest[0] == '-' {
+			c.negate = true
+			rest = rest[1:]
+		}
+
+		// field: prefix, when what precedes the colon is a plain word
+		if i := strings.IndexAny(rest, ": \""); i > 0 && rest[i] == ':' {
+			c.field = normalizeKey(rest[:i])
+			rest = rest[i+1:]
+		}
+
+		var text string
+		if strings.HasPrefix(rest, "\"") {
+			end := strings.Index(rest[1:], "\"")
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated quote in %q", query)
+			}
+			text, rest = rest[1:end+1], rest[end+2:]
+		} else if i := strings.IndexByte(rest, ' '); i >= 0 {
+			text, rest = rest[:i], rest[i+1:]
+		} else {
+			text, rest = rest, ""
+		}
+		rest = strings.TrimSpace(rest)
+
+		// field:a/b is the phrase "a b" within field
+		c.terms = tokenize(text)
+		if len(c.terms) > 0 {
+			clauses = append(clauses, c)
+		}
+	}
+
+	for _, c := range clauses {
+		if !c.negate {
+			return clauses, nil
+		}
+	}
+	return nil, fmt.Errorf("query needs at least one word to match")
+}
+
+// Search ranks mirrored records against a query with BM25, weighting
+// title matches over fields over body text. collection, if set, limits the
+// search to one collection (case-insensitive).
+func (m *Mirror) Search(query, collection string, limit int) ([]SearchResult, error) {
+	clauses, err := parseSearchQuery(query)
+	if err != nil {
+		return nil, err
+	}
+	if limit <= 0 {
+		limit = defaultSearchLimit
+	}
+
+	results, err := m.rank(clauses, collection, limit, false)
+	if err != nil {
+		return nil, err
+	}
+	return results, m.describe(results, clauses)
+}
+
+// rank scores records against clauses, best first. Normally every clause
+// must match; with anyClause, records matching some of them are kept too.
+func (m *Mirror) rank(clauses []searchClause, collection string, limit int, anyClause bool) ([]SearchResult, error) {
+	results := []SearchResult{}
+	err := m.db.View(func(tx *bolt.Tx) error {
+		stats := getSearchStats(tx)
+		if stats.Docs == 0 {
+			return nil
+		}
+		avgLength := float64(stats.Tokens) / float64(stats.Docs)
+		docs := tx.Bucket(searchDocsBucket)
+		terms := tx.Bucket(searchTermsBucket)
+
+		var scores map[string]float64
+		excluded := map[string]bool{}
+		for _, c := range clauses {
+			matches := matchClause(terms, c)
+			if c.negate {
+				for key := range matches {
+					excluded[key] = true
+				}
+				continue
+			}
+
+			idf := 0.0
+			for _, term := range c.terms {
+				idf += bm25IDF(stats.Docs, termDocCount(terms, term))
+			}
+			next := map[string]float64{}
+			if anyClause {
+				for key, score := range scores {
+					next[key] = score
+				}
+			}
+			for key, tf := range matches {
+				if scores != nil && !anyClause {
+					if _, ok := scores[key]; !ok {
+						continue
+					}
+				}
+				var doc searchDoc
+				if json.Unmarshal(docs.Get([]byte(key)), &doc) != nil {
+					continue
+				}
+				norm := 1 - bm25B + bm25B*float64(doc.Length)/avgLength
+				next[key] = scores[key] + idf*tf*(bm25K1+1)/(tf+bm25K1*norm)
+			}
+			scores = next
+			if len(scores) == 0 && !anyClause {
+				return nil
+			}
+		}
+
+		for key, score := range scores {
+			if excluded[key] {
+				continue
+			}
+			name, guid, _ := strings.Cut(key, "\x00")
+			if collection != "" && !strings.EqualFold(name, collection) {
+				continue
+			}
+			results = append(results, SearchResult{
+				GUID:       guid,
+				Collection: name,
+				Score:      math.Round(score*1000) / 1000,
+			})
+		}
+		sort.Slice(results, func(i, j int) bool {
+			if results[i].Score != results[j].Score {
+				return results[i].Score > results[j].Score
+			}
+			return results[i].GUID < results[j].GUID
+		})
+		if len(results) > limit {
+			results = results[:limit]
+		}
+		return nil
+	})
+	return results, err
+}
+
+// describe fills in titles and snippets
+func (m *Mirror) describe(results []SearchResult, clauses []searchClause) error {
+	return m.db.View(func(tx *bolt.Tx) error {
+		for i := range results {
+			r := &results[i]
+			bucket := tx.Bucket(mirrorRecordsBucket).Bucket([]byte(r.Collection))
+			if bucket == nil {
+				continue
+			}
+			var record mirrorRecord
+			if json.Unmarshal(bucket.Get([]byte(r.GUID)), &record) == nil {
+				r.Title = record.Title
+				r.Snippet = searchSnippet(record, clauses)
+			}
+		}
+		return nil
+	})
+}
+
+// matchClause returns the docs matching a clause with the clause's
+// weighted term frequency in each
+func matchClause(terms *bolt.Bucket, c searchClause) map[string]float64 {
+	first := terms.Bucket([]byte(c.terms[0]))
+	if first == nil {
+		return nil
+	}
+
+	matches := map[string]float64{}
+	first.ForEach(func(key, data []byte) error {
+		var postings []searchPostings
+		for _, term := range c.terms {
+			bucket := terms.Bucket([]byte(term))
+			if bucket == nil {
+				return nil
+			}
+			var p searchPostings
+			if json.Unmarshal(bucket.Get(key), &p) != nil {
+				return nil
+			}
+			postings = append(postings, p)
+		}
+
+		tf := 0.0
+		for field, positions := range postings[0] {
+			if c.field != "" && field != c.field {
+				continue
+			}
+			n := 0
+			for _, pos := range positions {
+				if phraseAt(postings, field, pos) {
+					n++
+				}
+			}
+			tf += float64(n) * searchFieldWeight(field)
+		}
+		if tf > 0 {
+			matches[string(key)] = tf
+		}
+		return nil
+	})
+	return matches
+}
+
+// phraseAt reports whether the terms follow each other from pos in field
+func phraseAt(postings []searchPostings, field string, pos int) bool {
+	for i := 1; i < len(postings); i++ {
+		found := false
+		for _, p := range postings[i][field] {
+			if p == pos+i {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+func searchFieldWeight(field string) float64 {
+	switch field {
+	case "title":
+		return searchTitleBoost
+	case "body":
+		return searchBodyBoost
+	}
+	return searchFieldBoost
+}
+
+func termDocCount(terms *bolt.Bucket, term string) int {
+	bucket := terms.Bucket([]byte(term))
+	if bucket == nil {
+		return 0
+	}
+	return countKeys(bucket)
+}
+
+func bm25IDF(docs, docCount int) float64 {
+	return math.Log(1 + (float64(docs)-float64(docCount)+0.5)/(float64(docCount)+0.5))
+}
+
+// searchSnippet shows text around the first match, preferring the body,
+// or the start of the body when nothing matches
+func searchSnippet(r mirrorRecord, clauses []searchClause) string {
+	texts := recordTexts(r)
+	fields := []string{"body"}
+	for field := range texts {
+		if field != "body" && field != "title" {
+			fields = append(fields, field)
+		}
+	}
+	sort.Strings(fields[1:])
+
+	for _, field := range fields {
+		text := texts[field]
+		lower, offsets := lowerWithOffsets(text)
+		for _, c := range clauses {
+			if c.negate || (c.field != "" && c.field != field) {
+				continue
+			}
+			if i := strings.Index(lower, c.terms[0]); i >= 0 {
+				return snippetAround(text, offsets[i])
+			}
+		}
+	}
+	return snippetAround(texts["body"], 0)
+}
+
+// lowerWithOffsets lowercases text like strings.ToLower, and maps each byte
+// of the result to where its rune starts in text. Lowercase runes can be
+// longer than their capitals (Ⱥ is 2 bytes, ⱥ 3), so offsets differ.
+func lowerWithOffsets(text string) (string, []int) {
+	var b strings.Builder
+	offsets := make([]int, 0, len(text))
+	for i, r := range text {
+		n := b.Len()
+		b.WriteRune(unicode.ToLower(r))
+		for range b.Len() - n {
+			offsets = append(offsets, i)
+		}
+	}
+	return b.String(), offsets
+}
+
+// snippetAround cuts about 160 characters of text around byte offset i,
+// on word boundaries
+func snippetAround(text string, i int) string {
+	const before, after = 60, 100
+	start, end := i-before, i+after
+	prefix, suffix := "…", "…"
+	if start <= 0 {
+		start, prefix = 0, ""
+	} else if j := strings.IndexByte(text[start:i], ' '); j >= 0 {
+		start += j + 1
+	}
+	if end >= len(text) {
+		end, suffix = len(text), ""
+	} else if j := strings.LastIndexByte(text[i:end], ' '); j > 0 {
+		end = i + j
+	}
+	// Don't cut a multi-byte character
+	for start > 0 && start < len(text) && !isRuneStart(text[start]) {
+		start--
+	}
+	for end < len(text) && !isRuneStart(text[end]) {
+		end++
+	}
+	return prefix + strings.Join(strings.Fields(text[start:end]), " ") + suffix
+}
+
+func isRuneStart(b byte) bool {
+	return b&0xC0 != 0x80
+}
+
+var searchLocalTool = Tool{
+	Name:        "search_local",
+	Description: "Search records in Thymer Desktop's local index of the workspace. Fast, ranked, and works while Thymer is closed. Supports \"exact phrases\", field:value (e.g. repo:thymer state:open) and -word to exclude.",
+	Parameters: map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"query":      map[string]interface{}{"type": "string", "description": "Words, \"phrases\", field:value and -word"},
+			"collection": map[string]interface{}{"type": "string", "description": "Only search this collection"},
+			"limit":      map[string]interface{}{"type": "number", "description": "Maximum results (default 10)"},
+		},
+		"required": []string{"query"},
+	},
+}
+
+// runSearchLocal is the search_local tool
+func (m *Mirror) runSearchLocal(args map[string]interface{}) (interface{}, error) {
+	query, _ := args["query"].(string)
+	collection, _ := args["collection"].(string)
+	limit := defaultSearchLimit
+	if n, ok := args["limit"].(float64); ok && n > 0 {
+		limit = int(n)
+	}
+
+	results, err := m.Search(query, collection, limit)
+	if err != nil {
+		return map[string]interface{}{"error": err.Error()}, nil
+	}
+	result := map[string]interface{}{
+		"query":   query,
+		"results": results,
+	}
+	if collection != "" {
+		result["collection"] = collection
+	}
+	return result, nil
+}
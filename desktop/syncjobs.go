@@ -0,0 +1,387 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// maxSyncJobs is how many jobs the history keeps
+const maxSyncJobs = 200
+
+// Sync job and plugin result states
+const (
+	SyncRunning = "running"
+	SyncSuccess = "success"
+	SyncError   = "error"
+	SyncSkipped = "skipped"
+)
+
+// Where a sync job came from
+const (
+	TriggerAPI      = "api"
+	TriggerCLI      = "cli"
+	TriggerTray     = "tray"
+	TriggerSchedule = "schedule"
+)
+
+// SyncPluginResult is one plugin's outcome within a sync job
+type SyncPluginResult struct {
+	Plugin     string     `json:"plugin"`
+	Status     string     `json:"status"`
+	Created    int        `json:"created"`
+	Updated    int        `json:"updated"`
+	Skipped    int        `json:"skipped"`
+	Summary    string     `json:"summary,omitempty"`
+	Error      string     `json:"error,omitempty"`
+	StartedAt  *time.Time `json:"started_at,omitempty"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+	DurationMs int64      `json:"duration_ms,omitempty"`
+}
+
+// SyncJob is a sync of one plugin, or all of them, triggered through the bridge
+type SyncJob struct {
+	ID         string             `json:"id"`
+	Plugin     string             `json:"plugin,omitempty"` // Empty for sync-all
+	Trigger    string             `json:"trigger,omitempty"`
+	Status     string             `json:"status"`
+	StartedAt  time.Time          `json:"started_at"`
+	FinishedAt *time.Time         `json:"finished_at,omitempty"`
+	Results    []SyncPluginResult `json:"results"`
+	Error      string             `json:"error,omitempty"`
+}
+
+// SyncEvent is sent to watchers of a job: "started" and "finished" for each
+// plugin, then "done" with the final job
+type SyncEvent struct {
+	Type   string            `json:"type"`
+	Plugin string            `json:"plugin,omitempty"`
+	Result *SyncPluginResult `json:"result,omitempty"`
+	Job    *SyncJob          `json:"job,omitempty"`
+}
+
+// SyncJobs tracks sync jobs and their progress, and keeps a history of
+// finished jobs on disk
+type SyncJobs struct {
+	path string
+
+	mu       sync.Mutex
+	jobs     map[string]*SyncJob
+	order    []string
+	watchers map[string][]chan SyncEvent
+	counter  int
+}
+
+// NewSyncJobs loads the history from path, if it exists
+func NewSyncJobs(path string) *SyncJobs {
+	s := &SyncJobs{
+		path:     path,
+		jobs:     make(map[string]*SyncJob),
+		watchers: make(map[string][]chan SyncEvent),
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return s
+	}
+	var history []*SyncJob
+	if err := json.Unmarshal(data, &history); err != nil {
+		log.Printf("[Sync] Failed to parse history: %v", err)
+		return s
+	}
+	for _, job := range history {
+		// Jobs still running when thymer-bar stopped will never finish
+		if job.Status == SyncRunning {
+			job.Status = SyncError
+			job.Error = "thymer-bar stopped during sync"
+		}
+		s.jobs[job.ID] = job
+		s.order = append(s.order, job.ID)
+	}
+	s.counter = len(history)
+	return s
+}
+
+func syncHistoryPath() string {
+	return filepath.Join(configDir(), "sync_history.json")
+}
+
+// Start records a new running job. An empty plugin means all plugins.
+func (s *SyncJobs) Start(plugin, trigger string) *SyncJob {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.counter++
+	job := &SyncJob{
+		ID:        fmt.Sprintf("sync_%d_%d", time.Now().Unix(), s.counter),
+		Plugin:    plugin,
+		Trigger:   trigger,
+		Status:    SyncRunning,
+		StartedAt: time.Now(),
+		Results:   []SyncPluginResult{},
+	}
+	s.jobs[job.ID] = job
+	s.order = append(s.order, job.ID)
+
+	// Forget the oldest finished jobs
+	for len(s.order) > maxSyncJobs {
+		oldest := s.jobs[s.order[0]]
+		if oldest.Status == SyncRunning {
+			break
+		}
+		delete(s.jobs, oldest.ID)
+		s.order = s.order[1:]
+	}
+	return job
+}
+
+// Progress records a plugin starting or finishing within a job
+func (s *SyncJobs) Progress(id string, event string, result SyncPluginResult) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job, ok := s.jobs[id]
+	if !ok || job.Status != SyncRunning {
+		return
+	}
+
+	now := time.Now()
+	if event == "started" {
+		result.Status = SyncRunning
+		result.StartedAt = &now
+	} else {
+		result.FinishedAt = &now
+	}
+	replaced := false
+	for i := range job.Results {
+		if job.Results[i].Plugin == result.Plugin {
+			if result.StartedAt == nil {
+				result.StartedAt = job.Results[i].StartedAt
+			}
+			job.Results[i] = result
+			replaced = true
+		}
+	}
+	if !replaced {
+		job.Results = append(job.Results, result)
+	}
+
+	r := result
+	s.notify(id, SyncEvent{Type: event, Plugin: result.Plugin, Result: &r})
+}
+
+// Finish completes a job. Results, when given, replace what progress
+// reported. The job fails if errMsg is set or any plugin errored.
+func (s *SyncJobs) Finish(id string, results []SyncPluginResult, errMsg string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job, ok := s.jobs[id]
+	if !ok || job.Status != SyncRunning {
+		return
+	}
+
+	now := time.Now()
+	if results != nil {
+		// Keep the times progress recorded
+		for i := range results {
+			for _, prev := range job.Results {
+				if prev.Plugin == results[i].Plugin {
+					if results[i].StartedAt == nil {
+						results[i].StartedAt = prev.StartedAt
+					}
+					if results[i].FinishedAt == nil {
+						results[i].FinishedAt = prev.FinishedAt
+					}
+				}
+			}
+			if results[i].FinishedAt == nil {
+				results[i].FinishedAt = &now
+			}
+		}
+		job.Results = results
+	}
+	job.FinishedAt = &now
+	job.Error = errMsg
+	job.Status = SyncSuccess
+	if errMsg != "" {
+		job.Status = SyncError
+	}
+	for i, r := range job.Results {
+		// A plugin never reported finishing
+		if r.Status == SyncRunning {
+			job.Results[i].Status = SyncError
+			job.Results[i].Error = "interrupted"
+			if errMsg != "" {
+				job.Results[i].Error = errMsg
+			}
+			job.Results[i].FinishedAt = &now
+		}
+		if job.Results[i].Status == SyncError {
+			job.Status = SyncError
+		}
+	}
+
+	done := *job
+	s.notify(id, SyncEvent{Type: "done", Job: &done})
+	for _, ch := range s.watchers[id] {
+		close(ch)
+	}
+	delete(s.watchers, id)
+
+	if err := s.save(); err != nil {
+		log.Printf("[Sync] Failed to save history: %v", err)
+	}
+}
+
+// save writes the history. Callers hold s.mu.
+func (s *SyncJobs) save() error {
+	if s.path == "" {
+		return nil
+	}
+	history := make([]*SyncJob, 0, len(s.order))
+	for _, id := range s.order {
+		history = append(history, s.jobs[id])
+	}
+	data, err := json.MarshalIndent(history, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
+
+// List returns jobs newest first, optionally only those that synced plugin
+// or ended with status. limit <= 0 means all.
+func (s *SyncJobs) List(plugin, status string, limit int) []SyncJob {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	jobs := []SyncJob{}
+	for i := len(s.order) - 1; i >= 0; i-- {
+		job := s.jobs[s.order[i]]
+		if status != "" && job.Status != status {
+			continue
+		}
+		if plugin != "" && !job.hasPlugin(plugin) {
+			continue
+		}
+		copied := *job
+		copied.Results = append([]SyncPluginResult(nil), job.Results...)
+		jobs = append(jobs, copied)
+		if limit > 0 && len(jobs) >= limit {
+			break
+		}
+	}
+	return jobs
+}
+
+func (j *SyncJob) hasPlugin(plugin string) bool {
+	if j.Plugin == plugin {
+		return true
+	}
+	for _, r := range j.Results {
+		if r.Plugin == plugin {
+			return true
+		}
+	}
+	return false
+}
+
+// FailRunning fails every running job, e.g. when SyncHub disconnects
+func (s *SyncJobs) FailRunning(errMsg string) {
+	s.mu.Lock()
+	var running []string
+	for id, job := range s.jobs {
+		if job.Status == SyncRunning {
+			running = append(running, id)
+		}
+	}
+	s.mu.Unlock()
+
+	for _, id := range running {
+		s.Finish(id, nil, errMsg)
+	}
+}
+
+// Running reports whether plugin is being synced, by itself or as part of
+// a sync-all. An empty plugin asks about sync-all.
+func (s *SyncJobs) Running(plugin string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, job := range s.jobs {
+		if job.Status == SyncRunning && (job.Plugin == "" || job.Plugin == plugin) {
+			return true
+		}
+	}
+	return false
+}
+
+// Get returns a copy of a job
+func (s *SyncJobs) Get(id string) (SyncJob, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job, ok := s.jobs[id]
+	if !ok {
+		return SyncJob{}, false
+	}
+	copied := *job
+	copied.Results = append([]SyncPluginResult(nil), job.Results...)
+	return copied, true
+}
+
+// Watch returns the job as it is now and a channel of its further events,
+// closed after "done". The channel is nil if the job already finished.
+func (s *SyncJobs) Watch(id string) (SyncJob, <-chan SyncEvent, func(), bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job, ok := s.jobs[id]
+	if !ok {
+		return SyncJob{}, nil, func() {}, false
+	}
+	copied := *job
+	copied.Results = append([]SyncPluginResult(nil), job.Results...)
+	if job.Status != SyncRunning {
+		return copied, nil, func() {}, true
+	}
+
+	ch := make(chan SyncEvent, 16)
+	s.watchers[id] = append(s.watchers[id], ch)
+	cancel := func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		list := s.watchers[id]
+		for i, c := range list {
+			if c == ch {
+				s.watchers[id] = append(list[:i], list[i+1:]...)
+				close(ch)
+				break
+			}
+		}
+	}
+	return copied, ch, cancel, true
+}
+
+// notify sends an event to a job's watchers; slow watchers miss events
+// rather than blocking the bridge. Callers hold s.mu.
+func (s *SyncJobs) notify(id string, event SyncEvent) {
+	for _, ch := range s.watchers[id] {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
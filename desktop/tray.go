@@ -6,6 +6,7 @@ import (
 	"log"
 	"os/exec"
 	"runtime"
+	"strings"
 	"time"
 
 	"fyne.io/systray"
@@ -36,6 +37,18 @@ func (a *App) onTrayReady() {
 	// Sync All
 	mSyncAll := systray.AddMenuItem("Sync All", "Trigger sync for all plugins")
 
+	// Last sync summary (not clickable)
+	mLastSync := systray.AddMenuItem("No syncs yet", "Sync history")
+	mLastSync.Disable()
+
+	systray.AddSeparator()
+
+	// Local LLM submenu
+	mLLM := systray.AddMenuItem("Local LLM", "Local LLM status")
+	mLLMStart := mLLM.AddSubMenuItem("Start", "Start the local LLM")
+	mLLMStop := mLLM.AddSubMenuItem("Stop", "Stop the local LLM")
+	mLLMRestart := mLLM.AddSubMenuItem("Restart", "Restart the local LLM")
+
 	systray.AddSeparator()
 
 	// Settings submenu
@@ -73,6 +86,30 @@ func (a *App) onTrayReady() {
 					mStatus.SetTitle("○ Waiting for SyncHub...")
 					mStatus.SetTooltip("Open Thymer in browser to connect")
 				}
+
+				title, tooltip := a.syncSummary()
+				mLastSync.SetTitle(title)
+				mLastSync.SetTooltip(tooltip)
+
+				llm := a.llm.Status()
+				if llm.Running {
+					mLLM.SetTitle(fmt.Sprintf("● Local LLM (%s)", llm.Model))
+				} else {
+					mLLM.SetTitle("○ Local LLM")
+				}
+				mLLM.SetTooltip(llm.Endpoint)
+				if llm.Managed && !llm.Process {
+					mLLMStart.Enable()
+				} else {
+					mLLMStart.Disable()
+				}
+				if llm.Process {
+					mLLMStop.Enable()
+					mLLMRestart.Enable()
+				} else {
+					mLLMStop.Disable()
+					mLLMRestart.Disable()
+				}
 			case <-a.ctx.Done():
 				return
 			}
@@ -89,7 +126,7 @@ func (a *App) onTrayReady() {
 			case <-mSyncAll.ClickedCh:
 				if a.IsConnected() {
 					go func() {
-						if err := a.bridge.SyncAll(); err != nil {
+						if _, err := a.StartSync("", TriggerTray); err != nil {
 							log.Printf("[Tray] Sync failed: %v", err)
 						} else {
 							log.Println("[Tray] Sync triggered for all plugins")
@@ -97,6 +134,23 @@ func (a *App) onTrayReady() {
 					}()
 				}
 
+			case <-mLLMStart.ClickedCh:
+				go func() {
+					if err := a.llm.Start(); err != nil {
+						log.Printf("[Tray] LLM start failed: %v", err)
+					}
+				}()
+
+			case <-mLLMStop.ClickedCh:
+				go a.llm.Stop()
+
+			case <-mLLMRestart.ClickedCh:
+				go func() {
+					if err := a.llm.Restart(); err != nil {
+						log.Printf("[Tray] LLM restart failed: %v", err)
+					}
+				}()
+
 			case <-mQuit.ClickedCh:
 				systray.Quit()
 				return
@@ -108,6 +162,62 @@ func (a *App) onTrayReady() {
 	}()
 }
 
+// syncSummary describes the latest sync job for the tray, with a line per
+// plugin in the tooltip
+func (a *App) syncSummary() (string, string) {
+	jobs := a.syncs.List("", "", 1)
+	if len(jobs) == 0 {
+		return "No syncs yet", "Sync history"
+	}
+	job := jobs[0]
+
+	var lines []string
+	var failed []string
+	for _, r := range job.Results {
+		switch r.Status {
+		case SyncError:
+			failed = append(failed, r.Plugin)
+			lines = append(lines, fmt.Sprintf("✗ %s: %s", r.Plugin, r.Error))
+		case SyncRunning:
+			lines = append(lines, fmt.Sprintf("… %s", r.Plugin))
+		default:
+			lines = append(lines, fmt.Sprintf("✓ %s: %d new, %d updated", r.Plugin, r.Created, r.Updated))
+		}
+	}
+	tooltip := strings.Join(lines, "\n")
+	if tooltip == "" {
+		tooltip = "Sync history"
+	}
+
+	if job.Status == SyncRunning {
+		return "⟳ Syncing...", tooltip
+	}
+	ago := formatAgo(job.StartedAt)
+	switch {
+	case len(failed) > 0:
+		return fmt.Sprintf("✗ Last sync %s: %s failed", ago, strings.Join(failed, ", ")), tooltip
+	case job.Error != "":
+		return fmt.Sprintf("✗ Last sync %s failed", ago), job.Error
+	default:
+		return fmt.Sprintf("✓ Last sync %s", ago), tooltip
+	}
+}
+
+// formatAgo renders a past time as "just now", "5m ago", "3h ago"...
+func formatAgo(t time.Time) string {
+	d := time.Since(t)
+	switch {
+	case d < time.Minute:
+		return "just now"
+	case d < time.Hour:
+		return fmt.Sprintf("%dm ago", int(d.Minutes()))
+	case d < 24*time.Hour:
+		return fmt.Sprintf("%dh ago", int(d.Hours()))
+	default:
+		return fmt.Sprintf("%dd ago", int(d.Hours()/24))
+	}
+}
+
 func (a *App) onTrayExit() {
 	log.Println("[Tray] Exiting...")
 	a.Stop()
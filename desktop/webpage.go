@@ -0,0 +1,173 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"mime"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	pageFetchTimeout = 15 * time.Second
+	maxPageSize      = 5 << 20
+	// maxPageText caps the readable text kept from a page
+	maxPageText = 100 << 10
+)
+
+// WebPage is what a URL capture keeps of a page
+type WebPage struct {
+	URL         string
+	Title       string
+	Description string
+	Author      string
+	Text        string // Readable text as markdown paragraphs
+}
+
+var pageClient = &http.Client{Timeout: pageFetchTimeout}
+
+// FetchPage downloads an HTML page and extracts its title, description,
+// author and readable text
+func FetchPage(pageURL string) (*WebPage, error) {
+	req, err := http.NewRequest("GET", pageURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "Mozilla/5.0 (compatible; ThymerBot/1.0)")
+	req.Header.Set("Accept", "text/html,application/xhtml+xml;q=0.9,*/*;q=0.5")
+
+	resp, err := pageClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxPageSize))
+	if err != nil {
+		return nil, err
+	}
+
+	page := &WebPage{URL: resp.Request.URL.String()}
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if mediaType == "text/plain" || mediaType == "text/markdown" {
+		page.Text = truncateText(strings.TrimSpace(string(data)), maxPageText)
+		return page, nil
+	}
+	if mediaType != "" && mediaType != "text/html" && mediaType != "application/xhtml+xml" {
+		return nil, fmt.Errorf("not a web page: %s", mediaType)
+	}
+
+	doc := string(data)
+	page.Title = firstMeta(doc, "og:title", "twitter:title")
+	if page.Title == "" {
+		if m := titleTag.FindStringSubmatch(doc); m != nil {
+			page.Title = cleanText(m[1])
+		}
+	}
+	page.Description = firstMeta(doc, "og:description", "twitter:description", "description")
+	page.Author = firstMeta(doc, "article:author", "author")
+	page.Text = truncateText(readableText(doc), maxPageText)
+	return page, nil
+}
+
+var (
+	titleTag = regexp.MustCompile(`(?is)<title[^>]*>(.*?)</title>`)
+	metaTag  = regexp.MustCompile(`(?is)<meta\s[^>]*>`)
+	attr     = regexp.MustCompile(`(?is)([a-z:-]+)\s*=\s*("([^"]*)"|'([^']*)')`)
+)
+
+// firstMeta returns the content of the first of the named meta tags the
+// page has, matched by property or name
+func firstMeta(doc string, names ...string) string {
+	found := make(map[string]string)
+	for _, tag := range metaTag.FindAllString(doc, -1) {
+		attrs := make(map[string]string)
+		for _, m := range attr.FindAllStringSubmatch(tag, -1) {
+			attrs[strings.ToLower(m[1])] = m[3] + m[4]
+		}
+		key := strings.ToLower(attrs["property"])
+		if key == "" {
+			key = strings.ToLower(attrs["name"])
+		}
+		if _, seen := found[key]; key != "" && !seen {
+			found[key] = cleanText(attrs["content"])
+		}
+	}
+	for _, name := range names {
+		if v := found[name]; v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+var (
+	// Never part of the readable text
+	noiseBlocks = regexp.MustCompile(`(?is)<(script|style|noscript|svg|nav|header|footer|aside|form|template)\b[^>]*>.*?</\s*(script|style|noscript|svg|nav|header|footer|aside|form|template)\s*>|<!--.*?-->`)
+	articleTag  = regexp.MustCompile(`(?is)<article\b[^>]*>(.*)</article>`)
+	mainTag     = regexp.MustCompile(`(?is)<main\b[^>]*>(.*)</main>`)
+	bodyTag     = regexp.MustCompile(`(?is)<body\b[^>]*>(.*)</body>`)
+	headingTag  = regexp.MustCompile(`(?is)<h([1-6])\b[^>]*>(.*?)</h[1-6]\s*>`)
+	listItemTag = regexp.MustCompile(`(?is)<li\b[^>]*>`)
+	blockTag    = regexp.MustCompile(`(?is)</?(p|div|section|br|tr|blockquote|pre|ul|ol|table|figure|li|dd|dt)\b[^>]*>`)
+	cellTag     = regexp.MustCompile(`(?is)</?(td|th)\b[^>]*>`)
+	anyTag      = regexp.MustCompile(`(?s)<[^>]*>`)
+)
+
+// readableText keeps the article (or main content, or body) of a page as
+// paragraphs, with headings and list items in markdown
+func readableText(doc string) string {
+	doc = noiseBlocks.ReplaceAllString(doc, " ")
+	for _, re := range []*regexp.Regexp{articleTag, mainTag, bodyTag} {
+		if m := re.FindStringSubmatch(doc); m != nil {
+			doc = m[1]
+			break
+		}
+	}
+	doc = headingTag.ReplaceAllStringFunc(doc, func(h string) string {
+		m := headingTag.FindStringSubmatch(h)
+		level := strings.Repeat("#", max(2, int(m[1][0]-'0')))
+		return "\n\n" + level + " " + cleanText(m[2]) + "\n\n"
+	})
+	doc = listItemTag.ReplaceAllString(doc, "\n\n- ")
+	doc = blockTag.ReplaceAllString(doc, "\n\n")
+	doc = cellTag.ReplaceAllString(doc, " ")
+	// Inline tags like <b> sit inside words and before punctuation
+	doc = anyTag.ReplaceAllString(doc, "")
+
+	var paragraphs []string
+	for _, p := range strings.Split(doc, "\n\n") {
+		p = cleanText(p)
+		if p != "" && p != "-" {
+			paragraphs = append(paragraphs, p)
+		}
+	}
+	return strings.Join(paragraphs, "\n\n")
+}
+
+// cleanText decodes entities and collapses whitespace
+func cleanText(s string) string {
+	return strings.Join(strings.Fields(html.UnescapeString(anyTag.ReplaceAllString(s, " "))), " ")
+}
+
+// truncateText cuts text to at most n bytes at a paragraph, line or rune
+// boundary
+func truncateText(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	cut := n
+	for cut > 0 && !isRuneStart(s[cut]) {
+		cut--
+	}
+	s = s[:cut]
+	if i := strings.LastIndex(s, "\n\n"); i > n/2 {
+		s = s[:i]
+	}
+	return s + "\n\n…"
+}
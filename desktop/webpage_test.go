@@ -0,0 +1,94 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const testPage = `<!doctype html>
+<html><head>
+<title>Fallback title</title>
+<meta property="og:title" content="Attention &amp; Transformers">
+<meta name="description" content="A short tour of attention.">
+<meta name="author" content="Ada Lovelace">
+<script>var s = "<p>not text</p>";</script>
+<style>p { color: red }</style>
+</head><body>
+<nav>Home | About</nav>
+<article>
+<h1>Attention</h1>
+<p>Transformers use <b>attention</b>.</p>
+<ul><li>Queries</li><li>Keys</li></ul>
+</article>
+<footer>(c) 2026</footer>
+</body></html>`
+
+func TestFetchPage(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/post":
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.Write([]byte(testPage))
+		case "/plain":
+			w.Header().Set("Content-Type", "text/plain")
+			w.Write([]byte("  just text  \n"))
+		case "/image":
+			w.Header().Set("Content-Type", "image/png")
+			w.Write([]byte("\x89PNG\r\n\x1a\n"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	page, err := FetchPage(srv.URL + "/post")
+	if err != nil {
+		t.Fatalf("FetchPage: %v", err)
+	}
+	if page.Title != "Attention & Transformers" {
+		t.Errorf("Title = %q", page.Title)
+	}
+	if page.Description != "A short tour of attention." {
+		t.Errorf("Description = %q", page.Description)
+	}
+	if page.Author != "Ada Lovelace" {
+		t.Errorf("Author = %q", page.Author)
+	}
+	want := "## Attention\n\nTransformers use attention.\n\n- Queries\n\n- Keys"
+	if page.Text != want {
+		t.Errorf("Text = %q, want %q", page.Text, want)
+	}
+	for _, noise := range []string{"not text", "color", "Home", "2026"} {
+		if strings.Contains(page.Text, noise) {
+			t.Errorf("Text keeps %q: %q", noise, page.Text)
+		}
+	}
+
+	page, err = FetchPage(srv.URL + "/plain")
+	if err != nil || page.Text != "just text" || page.Title != "" {
+		t.Errorf("plain text page = %+v, %v", page, err)
+	}
+	if _, err := FetchPage(srv.URL + "/image"); err == nil {
+		t.Error("FetchPage of an image succeeded")
+	}
+	if _, err := FetchPage(srv.URL + "/missing"); err == nil || !strings.Contains(err.Error(), "404") {
+		t.Errorf("FetchPage of a missing page: %v", err)
+	}
+}
+
+func TestTitleFallsBackToTitleTag(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("<html><head><title> Plain  &lt;Page&gt; </title></head><body><p>Hi</p></body></html>"))
+	}))
+	defer srv.Close()
+
+	page, err := FetchPage(srv.URL)
+	if err != nil {
+		t.Fatalf("FetchPage: %v", err)
+	}
+	if page.Title != "Plain <Page>" || page.Text != "Hi" {
+		t.Errorf("page = %+v", page)
+	}
+}
@@ -382,6 +382,31 @@ class Plugin extends CollectionPlugin {
      */
     registerWithSyncHub() {
         const register = () => {
+            // Expose habits to agents (and MCP argument completion)
+            if (window.syncHub?.registerCollectionTools) {
+                window.syncHub.registerCollectionTools({
+                    collection: 'HabitHub',
+                    version: VERSION,
+                    description: 'Habits and vices tracked by HabitHub',
+                    schema: {
+                        title: 'Habit name',
+                        kind: 'Habit | Vice',
+                        schedule: 'Daily | Weekdays | Weekends | Weekly',
+                        target: 'Daily target',
+                        unit: 'Unit of measure'
+                    },
+                    tools: [
+                        {
+                            name: 'list',
+                            description: 'List tracked habits. Returns GUIDs - use [[GUID]] to link.',
+                            parameters: {
+                                limit: { type: 'number', optional: true }
+                            },
+                            handler: async (args) => this.toolList(args)
+                        }
+                    ]
+                });
+            }
             if (window.syncHub?.registerHub) {
                 window.syncHub.registerHub({
                     id: 'habithub',
@@ -398,6 +423,23 @@ class Plugin extends CollectionPlugin {
         }
     }
 
+    async toolList(args = {}) {
+        const records = await this.myCollection.getAllRecords();
+        const limit = args.limit || 100;
+
+        return records
+            .filter(r => r.prop('enabled')?.choice() !== 'no')
+            .slice(0, limit)
+            .map(r => ({
+                guid: r.guid,
+                title: r.getName(),
+                kind: r.prop('kind')?.choice() || 'habit',
+                schedule: r.prop('schedule')?.choice() || 'daily',
+                target: r.prop('target')?.number() || 0,
+                unit: r.text('unit')
+            }));
+    }
+
     onUnload() {
         if (this.logHabitsCommand) this.logHabitsCommand.remove();
         if (this.syncCommand) this.syncCommand.remove();
@@ -44,6 +44,7 @@ class Plugin extends AppPlugin {
             icon: 'ti-PLUGIN_ICON',
             defaultInterval: '5m',
             version: VERSION,
+            collection: 'TARGET_COLLECTION',
             sync: async (ctx) => this.sync(ctx),
         });
     }
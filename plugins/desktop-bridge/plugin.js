@@ -9,6 +9,7 @@ const VERSION = 'v1.2.0';
  * - WebSocket connection to thymer-bar (ws://127.0.0.1:9848)
  * - MCP status bar with activity indicator
  * - Tool call forwarding to LLM clients
+ * - LLM sampling via the connected MCP client (window.syncHub.sample)
  * - Live activity log
  */
 
@@ -35,6 +36,8 @@ class Plugin extends AppPlugin {
         this.intentionalClose = false;
         this.connectedAt = null;
         this.activityLog = []; // Circular buffer of recent tool calls
+        this.pendingRequests = new Map(); // Requests we sent to thymer-bar, by id
+        this.requestCounter = 0;
 
         // MCP status bar (wand icon)
         this.statusBarItem = this.ui.addStatusBarItem({
@@ -43,11 +46,22 @@ class Plugin extends AppPlugin {
             onClick: (event) => this.onStatusBarClick(event)
         });
 
+        // Keep thymer-bar's plugin list current (settings, status, last sync)
+        this._onPluginsChanged = () => {
+            clearTimeout(this._pushPluginsTimer);
+            this._pushPluginsTimer = setTimeout(() => this._pushPlugins(), 500);
+        };
+        window.addEventListener('synchub-plugins-changed', this._onPluginsChanged);
+
         // Connect to thymer-bar
         this.connect();
     }
 
     onUnload() {
+        if (this._onPluginsChanged) {
+            window.removeEventListener('synchub-plugins-changed', this._onPluginsChanged);
+            clearTimeout(this._pushPluginsTimer);
+        }
         this.disconnect();
         if (this.statusBarItem) {
             this.statusBarItem.remove();
@@ -103,6 +117,9 @@ class Plugin extends AppPlugin {
                 this._pushTools();
                 this._pushPlugins();
 
+                // Offer sampling through thymer-bar's MCP clients
+                window.syncHub.registerSampler?.((request) => this.sample(request));
+
                 // Update status bar
                 this.updateStatusBar();
             };
@@ -117,6 +134,9 @@ class Plugin extends AppPlugin {
                 this.ws = null;
                 this.connectedAt = null;
 
+                window.syncHub.registerSampler?.(null);
+                this._rejectPending('Disconnected from thymer-bar');
+
                 this.updateStatusBar();
 
                 // Don't reconnect if intentional or replaced by new client
@@ -164,13 +184,36 @@ class Plugin extends AppPlugin {
         try {
             const msg = JSON.parse(data);
 
+            // Response to a request we sent
+            if (msg.id && !msg.type && this.pendingRequests.has(msg.id)) {
+                const pending = this.pendingRequests.get(msg.id);
+                this.pendingRequests.delete(msg.id);
+                clearTimeout(pending.timeout);
+                if (msg.error) {
+                    pending.reject(new Error(msg.error));
+                } else {
+                    pending.resolve(msg.result);
+                }
+                return;
+            }
+
             switch (msg.type) {
                 case 'get_tools':
                     this._sendResponse(msg.id, window.syncHub.getRegisteredTools());
                     break;
 
                 case 'get_plugins':
-                    this._sendResponse(msg.id, window.syncHub.getPlugins());
+                    Promise.resolve(window.syncHub.getPlugins())
+                        .then(plugins => this._sendResponse(msg.id, plugins))
+                        .catch(err => this._sendError(msg.id, err.message));
+                    break;
+
+                case 'set_plugin_enabled':
+                    this._handlePluginChange(msg, () => window.syncHub.setPluginEnabled(msg.plugin, msg.enabled));
+                    break;
+
+                case 'set_plugin_config':
+                    this._handlePluginChange(msg, () => window.syncHub.setPluginConfig(msg.plugin, msg.settings || {}));
                     break;
 
                 case 'tool_call':
@@ -178,15 +221,12 @@ class Plugin extends AppPlugin {
                     break;
 
                 case 'sync':
-                    window.syncHub.requestSync(msg.plugin)
-                        .then(() => this._sendResponse(msg.id, { success: true }))
-                        .catch(err => this._sendError(msg.id, err.message));
+                    this._handleSync(msg, (options) => window.syncHub.requestSync(msg.plugin, options)
+                        .then(outcome => [outcome]));
                     break;
 
                 case 'sync_all':
-                    window.syncHub.syncAll()
-                        .then(() => this._sendResponse(msg.id, { success: true }))
-                        .catch(err => this._sendError(msg.id, err.message));
+                    this._handleSync(msg, (options) => window.syncHub.syncAll(options));
                     break;
 
                 default:
@@ -197,6 +237,60 @@ class Plugin extends AppPlugin {
         }
     }
 
+    /**
+     * Run a sync for thymer-bar. With a job id, accept it right away and
+     * report each plugin's progress, then sync_complete with the outcomes.
+     */
+    _handleSync(msg, run) {
+        if (!msg.job) {
+            run({})
+                .then(() => this._sendResponse(msg.id, { success: true }))
+                .catch(err => this._sendError(msg.id, err.message));
+            return;
+        }
+
+        this._sendResponse(msg.id, { success: true, accepted: true, job: msg.job });
+
+        const onProgress = (event, result) => this._send({
+            type: 'sync_progress',
+            job: msg.job,
+            event,
+            result
+        });
+
+        run({ onProgress })
+            .then(results => this._send({
+                type: 'sync_complete',
+                job: msg.job,
+                plugin: msg.plugin || 'all',
+                results: results || []
+            }))
+            .catch(err => this._send({
+                type: 'sync_complete',
+                job: msg.job,
+                plugin: msg.plugin || 'all',
+                error: err.message
+            }));
+    }
+
+    /**
+     * Change a plugin's settings for thymer-bar; replies with the updated plugin.
+     */
+    _handlePluginChange(msg, change) {
+        if (!window.syncHub.setPluginConfig) {
+            this._sendError(msg.id, `SyncHub ${window.syncHub.version} can't change plugin settings; update SyncHub`);
+            return;
+        }
+        change()
+            .then(plugin => this._sendResponse(msg.id, plugin))
+            .catch(err => this._sendError(msg.id, err.message));
+    }
+
+    _send(message) {
+        if (!this.isConnected()) return;
+        this.ws.send(JSON.stringify(message));
+    }
+
     _handleToolCall(msg) {
         this.flashActivity();
 
@@ -231,6 +325,36 @@ class Plugin extends AppPlugin {
             });
     }
 
+    /**
+     * Forward a sampling request to thymer-bar, which asks its MCP client's LLM.
+     */
+    sample(request) {
+        if (!this.isConnected()) {
+            return Promise.reject(new Error('Not connected to thymer-bar'));
+        }
+
+        const id = `sample_${++this.requestCounter}`;
+        return new Promise((resolve, reject) => {
+            // Clients may ask the user to approve, so allow plenty of time
+            const timeout = setTimeout(() => {
+                this.pendingRequests.delete(id);
+                reject(new Error('Sampling request timed out'));
+            }, 150000);
+
+            this.pendingRequests.set(id, { resolve, reject, timeout });
+            this.flashActivity();
+            this.ws.send(JSON.stringify({ ...request, type: 'sample', id }));
+        });
+    }
+
+    _rejectPending(reason) {
+        for (const [id, pending] of this.pendingRequests) {
+            clearTimeout(pending.timeout);
+            pending.reject(new Error(reason));
+        }
+        this.pendingRequests.clear();
+    }
+
     _sendResponse(id, result) {
         if (!this.isConnected()) return;
         this.ws.send(JSON.stringify({ id, result }));
@@ -249,12 +373,14 @@ class Plugin extends AppPlugin {
         }));
     }
 
-    _pushPlugins() {
+    async _pushPlugins() {
         if (!this.isConnected()) return;
-        this.ws.send(JSON.stringify({
-            type: 'plugins',
-            plugins: window.syncHub.getPlugins()
-        }));
+        try {
+            const plugins = await window.syncHub.getPlugins();
+            this._send({ type: 'plugins', plugins });
+        } catch (e) {
+            console.debug('[DesktopBridge] Could not list plugins:', e.message);
+        }
     }
 
     // =========================================================================
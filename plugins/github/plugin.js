@@ -36,6 +36,7 @@ class Plugin extends AppPlugin {
             icon: 'ti-brand-github',
             defaultInterval: '5m',
             version: VERSION,
+            collection: 'Issues',
             sync: async (ctx) => this.sync(ctx),
         });
         console.log('[GitHub] Registered successfully');
@@ -171,6 +171,7 @@ class Plugin extends AppPlugin {
             icon: 'ti-calendar',
             defaultInterval: '15m',
             version: VERSION,
+            collection: 'Calendar',
             sync: async (ctx) => this.sync(ctx),
         });
         // Register connect function for dashboard button
@@ -159,6 +159,7 @@ class Plugin extends AppPlugin {
             icon: 'ti-wallet',
             defaultInterval: '1h', // Contacts don't change often
             version: VERSION,
+            collection: 'People',
             sync: async (ctx) => this.sync(ctx),
         });
         // Register connect function for dashboard button
@@ -34,6 +34,7 @@ class Plugin extends AppPlugin {
             icon: 'ti-books',
             defaultInterval: '1h',
             version: VERSION,
+            collection: 'Captures',
             sync: async (ctx) => this.sync(ctx),
         });
         console.log('[Readwise] Registered successfully');
@@ -41,6 +41,7 @@ class Plugin extends AppPlugin {
             icon: 'ti-plane',
             defaultInterval: '1m',
             version: VERSION,
+            collection: 'Captures',
             sync: async (ctx) => this.sync(ctx),
         });
     }
@@ -20,6 +20,18 @@ const LOCK_SETTLE_JITTER_MS = 400;       // Random jitter to reduce race collisi
 // Markdown config
 const BLANK_LINE_BEFORE_HEADINGS = true;
 
+// Plugin record settings thymer-bar may change, with their allowed choices
+const PLUGIN_SETTINGS = {
+    interval: ['1m', '5m', '15m', '1h', 'manual'],
+    journal: ['none', 'major_only', 'verbose'],
+    toast: ['all_updates', 'new_records', 'errors_only', 'none'],
+    log_level: ['info', 'debug'],
+};
+
+// Config keys holding credentials: hidden from thymer-bar and not editable there
+const SECRET_CONFIG_KEY = /token|secret|password|passwd|api_?key|credential|private/i;
+const REDACTED = '********';
+
 // Dashboard CSS
 const DASHBOARD_CSS = `
     .sync-dashboard {
@@ -398,7 +410,13 @@ class Plugin extends CollectionPlugin {
             executeToolCall: (name, args) => this.executeToolCall(name, args),
             // Desktop bridge API
             getPlugins: () => this._getPluginList(),
-            syncAll: () => this.syncAll(),
+            setPluginEnabled: (pluginId, enabled) => this.setPluginEnabled(pluginId, enabled),
+            setPluginConfig: (pluginId, changes) => this.setPluginConfig(pluginId, changes),
+            syncAll: (options) => this.syncAll(options),
+            // LLM sampling via a connected MCP client (provided by Desktop Bridge)
+            registerSampler: (sampleFn) => { this.sampler = sampleFn; },
+            canSample: () => !!this.sampler,
+            sample: (request) => this.sample(request),
         };
 
         // Track registered sync functions (MUST be before event dispatch!)
@@ -855,9 +873,10 @@ class Plugin extends CollectionPlugin {
      * @param {Function} config.sync - Async function to perform sync
      * @param {string} config.defaultInterval - Default interval (e.g., '5m', '1h')
      * @param {string} config.version - Plugin version (e.g., 'v1.0.0')
+     * @param {string} config.collection - Collection the plugin syncs into (e.g., 'Issues')
      */
     async registerPlugin(config) {
-        const { id, name, icon, sync, defaultInterval = '5m', version } = config;
+        const { id, name, icon, sync, defaultInterval = '5m', version, collection } = config;
 
         if (!id || !sync) {
             this.log(`Registration failed: missing id or sync function`, 'error');
@@ -871,6 +890,7 @@ class Plugin extends CollectionPlugin {
         this.registeredPlugins.set(id, {
             name: name || id,
             version: version || 'unknown',
+            collection,
             registeredAt: new Date()
         });
 
@@ -893,12 +913,21 @@ class Plugin extends CollectionPlugin {
             });
         }
 
+        this.notifyPluginsChanged();
         return record;
     }
 
     async unregisterPlugin(pluginId) {
         this.syncFunctions.delete(pluginId);
         this.registeredPlugins.delete(pluginId);
+        this.notifyPluginsChanged();
+    }
+
+    /**
+     * Let listeners (Desktop Bridge) know plugin settings or status changed
+     */
+    notifyPluginsChanged() {
+        window.dispatchEvent(new CustomEvent('synchub-plugins-changed'));
     }
 
     /**
@@ -1173,11 +1202,18 @@ class Plugin extends CollectionPlugin {
      * @param {boolean} options.full - If true, clears last_run to force full sync
      * @param {boolean} options.manual - If true, always show toast (for UI-triggered syncs)
      */
+    /**
+     * Sync one plugin. Resolves to its outcome:
+     * { plugin, status: 'success'|'error'|'skipped', created, updated, skipped, summary, error, duration_ms }
+     * options.onProgress(event, outcome) is called with 'started' and 'finished'.
+     */
     async requestSync(pluginId, options = {}) {
         const record = await this.findPluginRecord(pluginId);
         if (!record) {
             this.log(`Cannot sync unknown plugin: ${pluginId}`, 'error');
-            return;
+            const outcome = this.syncOutcome(pluginId, 'error', { error: `Unknown plugin: ${pluginId}` });
+            options.onProgress?.('finished', outcome);
+            return outcome;
         }
 
         // Full sync: clear last_run to force fetching everything
@@ -1186,7 +1222,18 @@ class Plugin extends CollectionPlugin {
             console.log(`[SyncHub] Full sync requested for ${pluginId}, cleared last_run`);
         }
 
-        await this.runSync(pluginId, record, { manual: options.manual });
+        return this.runSync(pluginId, record, { manual: options.manual, onProgress: options.onProgress });
+    }
+
+    syncOutcome(pluginId, status, fields = {}) {
+        return {
+            plugin: pluginId,
+            status,
+            created: 0,
+            updated: 0,
+            skipped: 0,
+            ...fields
+        };
     }
 
     /**
@@ -1199,10 +1246,13 @@ class Plugin extends CollectionPlugin {
     }
 
     async runSync(pluginId, record, options = {}) {
+        const onProgress = options.onProgress || (() => {});
         const syncFn = this.syncFunctions.get(pluginId);
         if (!syncFn) {
             this.log(`No sync function for: ${pluginId}`, 'warn');
-            return;
+            const outcome = this.syncOutcome(pluginId, 'error', { error: `No sync function for: ${pluginId}` });
+            onProgress('finished', outcome);
+            return outcome;
         }
 
         // Acquire sync lock (prevents duplicate syncs across Thymer instances)
@@ -1217,7 +1267,9 @@ class Plugin extends CollectionPlugin {
                     autoDestroyTime: 3000,
                 });
             }
-            return;
+            const outcome = this.syncOutcome(pluginId, 'skipped', { summary: 'Sync already in progress' });
+            onProgress('finished', outcome);
+            return outcome;
         }
 
         const logLevel = record.prop('log_level')?.choice() || 'info';
@@ -1229,6 +1281,7 @@ class Plugin extends CollectionPlugin {
         record.prop('status')?.setChoice('syncing');
         this.currentlySyncing = pluginId;
         this.updateStatusBar();
+        onProgress('started', this.syncOutcome(pluginId, 'running'));
 
         const startTime = Date.now();
         let result = null;
@@ -1310,6 +1363,20 @@ class Plugin extends CollectionPlugin {
             this.setLastRun(record);
             this.updateStatusBar();
         }
+
+        const changes = result?.changes || [];
+        const outcome = errorMsg
+            ? this.syncOutcome(pluginId, 'error', { error: errorMsg, duration_ms: Date.now() - startTime })
+            : this.syncOutcome(pluginId, 'success', {
+                created: result?.created || 0,
+                updated: result?.updated || 0,
+                skipped: result?.skipped ?? changes.filter(c => c.verb === 'skipped').length,
+                summary: result?.summary || 'Sync complete',
+                duration_ms: Date.now() - startTime
+            });
+        onProgress('finished', outcome);
+        this.notifyPluginsChanged();
+        return outcome;
     }
 
     shouldToast(level, result) {
@@ -1423,18 +1490,32 @@ class Plugin extends CollectionPlugin {
     // =========================================================================
 
     async getTodayJournalRecord() {
+        return this.getJournalRecord(null);
+    }
+
+    /**
+     * Find the journal record for a date ("2025-12-31" or "20251231").
+     * Null date means today.
+     */
+    async getJournalRecord(date) {
         try {
             const collections = await this.data.getAllCollections();
             const journalCollection = collections.find(c => c.getName() === 'Journal');
             if (!journalCollection) return null;
 
-            // Journal guids end with the date in YYYYMMDD format
-            const today = new Date().toISOString().slice(0, 10).replace(/-/g, ''); // "20251231"
+            // Journal guids end with the date in YYYYMMDD format. Today is
+            // the local day, as in Thymer's own calendar and the CLI's --date.
+            const now = new Date();
+            const day = date
+                ? String(date).replace(/-/g, '')
+                : String(now.getFullYear()) +
+                  String(now.getMonth() + 1).padStart(2, '0') +
+                  String(now.getDate()).padStart(2, '0'); // "20251231"
 
             const records = await journalCollection.getAllRecords();
-            const todayRecord = records.find(r => r.guid.endsWith(today));
+            const record = records.find(r => r.guid.endsWith(day));
 
-            return todayRecord || null;
+            return record || null;
         } catch (e) {
             return null;
         }
@@ -1644,6 +1725,7 @@ class Plugin extends CollectionPlugin {
                         type: 'object',
                         properties: {
                             query: { type: 'string', description: 'Search query - keywords or phrases' },
+                            collection: { type: 'string', description: 'Only search this collection (optional)' },
                             limit: { type: 'number', description: 'Max results (default: 5)' }
                         },
                         required: ['query']
@@ -1660,6 +1742,68 @@ class Plugin extends CollectionPlugin {
                 },
                 _core: true
             },
+            {
+                type: 'function',
+                function: {
+                    name: 'get_collection_records',
+                    description: 'Get records from any collection with all their fields. Prefer collection-specific find tools when they exist.',
+                    parameters: {
+                        type: 'object',
+                        properties: {
+                            collection: { type: 'string', description: 'Collection name (from list_collections)' },
+                            limit: { type: 'number', description: 'Max records (default: all)' },
+                            offset: { type: 'number', description: 'Records to skip, for paging (default: 0)' },
+                            bodies: { type: 'boolean', description: 'Include the text of each record (default: false)' },
+                            snapshot: { type: 'boolean', description: 'Page through the records as they are now: returns a snapshot_id for the next pages (default: false)' },
+                            snapshot_id: { type: 'string', description: 'snapshot_id from the first page' },
+                            guids: { type: 'array', items: { type: 'string' }, description: 'Only these records, in this order, e.g. guids from a find tool' }
+                        },
+                        required: ['collection']
+                    }
+                },
+                _core: true
+            },
+            {
+                type: 'function',
+                function: {
+                    name: 'snapshot_collection',
+                    description: 'A page of a collection\'s record guids, with the records among them changed since a time, so deletions show. Used by Thymer Desktop to keep its offline copy current.',
+                    parameters: {
+                        type: 'object',
+                        properties: {
+                            collection: { type: 'string', description: 'Collection name' },
+                            since: { type: 'string', description: 'ISO time of the collection\'s previous snapshot (default: everything)' },
+                            bodies: { type: 'boolean', description: 'Include the text of each changed record (default: false)' },
+                            limit: { type: 'number', description: 'Records per page (default: all)' },
+                            offset: { type: 'number', description: 'Records to skip (default: 0)' },
+                            snapshot_id: { type: 'string', description: 'snapshot_id from the first page' }
+                        },
+                        required: ['collection']
+                    }
+                },
+                _core: true
+            },
+            {
+                type: 'function',
+                function: {
+                    name: 'import_records',
+                    description: 'Create or update records by external_id. Used by Thymer Desktop to import CSV and JSON files.',
+                    parameters: {
+                        type: 'object',
+                        properties: {
+                            collection: { type: 'string', description: 'Collection name (from list_collections)' },
+                            records: {
+                                type: 'array',
+                                description: 'Records as {external_id, title, fields, body?}; choice fields take labels, dates ISO strings',
+                                items: { type: 'object' }
+                            },
+                            dry_run: { type: 'boolean', description: 'Only report what would be created or updated (default: false)' }
+                        },
+                        required: ['collection', 'records']
+                    }
+                },
+                _core: true
+            },
             {
                 type: 'function',
                 function: {
@@ -1695,11 +1839,13 @@ class Plugin extends CollectionPlugin {
                 type: 'function',
                 function: {
                     name: 'log_to_journal',
-                    description: 'Add an entry to today\'s journal.',
+                    description: 'Add an entry to today\'s journal, or to a past day\'s with date. With section, the entry goes at the end of that heading\'s section (created if missing).',
                     parameters: {
                         type: 'object',
                         properties: {
-                            content: { type: 'string', description: 'Content to add' }
+                            content: { type: 'string', description: 'Content to add' },
+                            section: { type: 'string', description: 'Heading to add under, e.g. "## Morning" or "Morning"' },
+                            date: { type: 'string', description: 'Journal date as YYYY-MM-DD (default: today)' }
                         },
                         required: ['content']
                     }
@@ -1719,18 +1865,50 @@ class Plugin extends CollectionPlugin {
                 },
                 _core: true
             },
+            {
+                type: 'function',
+                function: {
+                    name: 'get_journal',
+                    description: 'Get the journal/daily note for a specific date.',
+                    parameters: {
+                        type: 'object',
+                        properties: {
+                            date: { type: 'string', description: 'Journal date as YYYY-MM-DD' }
+                        },
+                        required: ['date']
+                    }
+                },
+                _core: true
+            },
+            {
+                type: 'function',
+                function: {
+                    name: 'get_journal_tasks',
+                    description: 'List the unchecked tasks in a journal, with the heading each one is under.',
+                    parameters: {
+                        type: 'object',
+                        properties: {
+                            date: { type: 'string', description: 'Journal date as YYYY-MM-DD (default: today)' }
+                        },
+                        required: []
+                    }
+                },
+                _core: true
+            },
             {
                 type: 'function',
                 function: {
                     name: 'save_note',
-                    description: 'Create a new note in a collection. Title is extracted from the first # heading. Returns the new note GUID.',
+                    description: 'Create a new note in a collection, or replace the body of an existing note by GUID. For new notes the title is extracted from the first # heading. Returns the note GUID.',
                     parameters: {
                         type: 'object',
                         properties: {
-                            collection: { type: 'string', description: 'Collection name (e.g., "Captures", "Issues")' },
-                            content: { type: 'string', description: 'Markdown content. First # heading becomes the title.' }
+                            collection: { type: 'string', description: 'Collection name (e.g., "Captures", "Issues") for a new note' },
+                            content: { type: 'string', description: 'Markdown content. First # heading becomes the title of a new note.' },
+                            guid: { type: 'string', description: 'GUID of an existing note to replace the body of with the markdown, as get_note returns it (optional). Unchanged lines keep their items.' },
+                            base_hash: { type: 'string', description: 'content_hash from get_note; the save fails if the note changed since (optional)' }
                         },
-                        required: ['collection', 'content']
+                        required: ['content']
                     }
                 },
                 _core: true
@@ -1747,6 +1925,12 @@ class Plugin extends CollectionPlugin {
                 return this.toolSearchWorkspace(args);
             case 'list_collections':
                 return this.toolListCollections();
+            case 'get_collection_records':
+                return this.toolGetCollectionRecords(args);
+            case 'snapshot_collection':
+                return this.toolSnapshotCollection(args);
+            case 'import_records':
+                return this.toolImportRecords(args);
             case 'get_note':
                 return this.toolGetNote(args);
             case 'append_to_note':
@@ -1755,6 +1939,10 @@ class Plugin extends CollectionPlugin {
                 return this.toolLogToJournal(args);
             case 'get_todays_journal':
                 return this.toolGetTodaysJournal();
+            case 'get_journal':
+                return this.toolGetJournal(args);
+            case 'get_journal_tasks':
+                return this.toolGetJournalTasks(args);
             case 'save_note':
                 return this.toolSaveNote(args);
             default:
@@ -1762,12 +1950,34 @@ class Plugin extends CollectionPlugin {
         }
     }
 
-    async toolSearchWorkspace({ query, limit = 5 }) {
+    async toolSearchWorkspace({ query, collection, limit = 5 }) {
         try {
-            const result = await this.data.searchByQuery(query, limit);
+            if (!collection) {
+                const result = await this.data.searchByQuery(query, limit);
+                return {
+                    query,
+                    results: (result.records || []).map(r => ({
+                        guid: r.guid,
+                        title: r.getName?.() || 'Untitled',
+                        snippet: r.snippet || ''
+                    }))
+                };
+            }
+
+            // Search doesn't filter by collection, so over-fetch and keep
+            // only records that belong to it
+            const wanted = collection.toLowerCase();
+            const allCollections = await this.data.getAllCollections();
+            const col = allCollections.find(c => c.getName().toLowerCase() === wanted);
+            if (!col) {
+                return { error: `Collection not found: ${collection}` };
+            }
+            const guids = new Set((await col.getAllRecords()).map(r => r.guid));
+            const result = await this.data.searchByQuery(query, limit * 10);
             return {
                 query,
-                results: (result.records || []).map(r => ({
+                collection: col.getName(),
+                results: (result.records || []).filter(r => guids.has(r.guid)).slice(0, limit).map(r => ({
                     guid: r.guid,
                     title: r.getName?.() || 'Untitled',
                     snippet: r.snippet || ''
@@ -1817,6 +2027,7 @@ class Plugin extends CollectionPlugin {
                 guid: col.guid,
                 description: registered?.description || `${name} collection`,
                 schema,
+                fields: await this.collectionFields(col),
                 tools: registered ? registered.tools.map(t => t.name) : [],
                 has_tools: !!registered
             };
@@ -1825,6 +2036,298 @@ class Plugin extends CollectionPlugin {
         return { collections };
     }
 
+    /**
+     * Field types from a collection's configuration: id, label, type, choice
+     * labels, and whether the field holds several values. Empty when Thymer
+     * doesn't expose the configuration.
+     */
+    async collectionFields(col) {
+        try {
+            const config = await col.getConfiguration?.();
+            return (config?.fields || []).filter(f => f.id).map(f => {
+                const field = { id: f.id, label: f.label || f.id, type: f.type || 'text' };
+                if (f.choices) field.choices = f.choices.map(c => ({ id: c.id, label: c.label || c.id }));
+                if (f.many) field.many = true;
+                return field;
+            });
+        } catch (e) {
+            return [];
+        }
+    }
+
+    /**
+     * Records of a collection, a page at a time. With snapshot, the pages
+     * follow the list of records as it was on the first page, so records
+     * added or deleted while paging don't shift the offsets; records deleted
+     * since are left out.
+     */
+    async toolGetCollectionRecords({ collection, limit, offset = 0, bodies = false, snapshot = false, snapshot_id, guids }) {
+        try {
+            if (!collection) {
+                return { error: 'Collection required' };
+            }
+
+            const wanted = collection.toLowerCase();
+            const allCollections = await this.data.getAllCollections();
+            const col = allCollections.find(c => c.getName().toLowerCase() === wanted);
+            if (!col) {
+                return { error: `Collection not found: ${collection}` };
+            }
+
+            const all = await col.getAllRecords();
+            if (snapshot || snapshot_id) {
+                return this.snapshotPage(col, all, { limit, offset, bodies, snapshot_id });
+            }
+            let records;
+            if (Array.isArray(guids)) {
+                const byGuid = new Map(all.map(r => [r.guid, r]));
+                records = guids.map(g => byGuid.get(g)).filter(Boolean);
+            } else {
+                records = all.slice(offset, limit ? offset + limit : undefined);
+            }
+
+            const result = [];
+            for (const r of records) {
+                const record = {
+                    guid: r.guid,
+                    title: r.getName?.() || 'Untitled',
+                    fields: this.recordFields(r)
+                };
+                if (bodies) record.body = await this.renderBody(r);
+                result.push(record);
+            }
+            return { collection: col.getName(), total: all.length, records: result };
+        } catch (e) {
+            return { error: e.message };
+        }
+    }
+
+    /**
+     * A page of records that later pages follow the record list of. With
+     * mirror, the page also lists its guids and carries only the records
+     * changed since `since`: by updated_at when they have it, else by a
+     * fingerprint of their title, fields and text against the last
+     * snapshot the mirror paged through to the end.
+     */
+    async snapshotPage(col, all, { limit, offset, bodies, snapshot_id, since, mirror = false }) {
+        // Snapshots nobody finished paging through expire
+        this.recordSnapshots ??= new Map();
+        const now = Date.now();
+        for (const [id, snap] of this.recordSnapshots) {
+            if (snap.expires < now) this.recordSnapshots.delete(id);
+        }
+
+        let id = snapshot_id;
+        let snap = id && this.recordSnapshots.get(id);
+        if (id && !snap) {
+            return { error: 'Snapshot expired; start again' };
+        }
+        if (!snap) {
+            id = `${col.getName()}-${now}-${Math.random().toString(36).slice(2, 8)}`;
+            snap = {
+                guids: all.map(r => r.guid),
+                snapshotAt: new Date(now).toISOString(),
+                since: since ? Date.parse(since) : NaN,
+                fingerprints: new Map()
+            };
+            this.recordSnapshots.set(id, snap);
+        }
+        snap.expires = now + 10 * 60 * 1000;
+
+        const byGuid = new Map(all.map(r => [r.guid, r]));
+        const end = limit ? offset + limit : snap.guids.length;
+        const result = [];
+        for (const guid of snap.guids.slice(offset, end)) {
+            const r = byGuid.get(guid);
+            if (!r) continue; // Deleted since the snapshot
+            const fields = this.recordFields(r);
+            const updated = Date.parse(fields.updated_at);
+            if (mirror && updated <= snap.since) continue;
+            const record = {
+                guid: r.guid,
+                title: r.getName?.() || 'Untitled',
+                fields
+            };
+            if (bodies) record.body = await this.renderBody(r);
+            if (mirror && isNaN(updated)) {
+                const fingerprint = this.hashContent(JSON.stringify(record));
+                snap.fingerprints.set(guid, fingerprint);
+                if (!isNaN(snap.since) && this.mirrorFingerprints?.get(guid) === fingerprint) continue;
+            }
+            result.push(record);
+        }
+
+        const page = { collection: col.getName(), total: snap.guids.length, snapshot_id: id, records: result };
+        if (mirror) {
+            page.snapshot_at = snap.snapshotAt;
+            page.guids = snap.guids.slice(offset, end);
+        }
+        if (end >= snap.guids.length) {
+            this.recordSnapshots.delete(id);
+            if (mirror) {
+                this.mirrorFingerprints ??= new Map();
+                for (const [guid, fingerprint] of snap.fingerprints) {
+                    this.mirrorFingerprints.set(guid, fingerprint);
+                }
+            }
+        }
+        return page;
+    }
+
+    /**
+     * A page of a collection's snapshot for the offline mirror: every guid
+     * in the page, so the caller can drop deleted records, and the records
+     * among them changed since `since`. With `bodies`, each changed record
+     * carries its text for search.
+     */
+    async toolSnapshotCollection({ collection, since, bodies = false, limit, offset = 0, snapshot_id }) {
+        try {
+            if (!collection) {
+                return { error: 'Collection required' };
+            }
+
+            const wanted = collection.toLowerCase();
+            const allCollections = await this.data.getAllCollections();
+            const col = allCollections.find(c => c.getName().toLowerCase() === wanted);
+            if (!col) {
+                return { error: `Collection not found: ${collection}` };
+            }
+
+            const all = await col.getAllRecords();
+            return this.snapshotPage(col, all, { limit, offset, bodies, snapshot_id, since, mirror: true });
+        } catch (e) {
+            return { error: e.message };
+        }
+    }
+
+    /**
+     * Create or update records by their external_id. Fields are set by type:
+     * choices by label, datetimes as dates. With dry_run nothing is written,
+     * but each record still reports whether it would be created or updated.
+     */
+    async toolImportRecords({ collection, records = [], dry_run = false }) {
+        try {
+            if (!collection) {
+                return { error: 'Collection required' };
+            }
+
+            const wanted = collection.toLowerCase();
+            const allCollections = await this.data.getAllCollections();
+            const col = allCollections.find(c => c.getName().toLowerCase() === wanted);
+            if (!col) {
+                return { error: `Collection not found: ${collection}` };
+            }
+
+            const fields = new Map();
+            for (const f of await this.collectionFields(col)) {
+                fields.set(f.id, f);
+            }
+            // Records are matched by external_id; without the field every
+            // row would leave a record behind that no retry can find
+            if (!fields.has('external_id')) {
+                return { error: `${col.getName()} has no external_id field to match records by` };
+            }
+            const existing = new Map();
+            for (const r of await col.getAllRecords()) {
+                const id = r.text?.('external_id');
+                if (id) existing.set(id, r);
+            }
+
+            let created = 0, updated = 0, failed = 0;
+            const results = [];
+            for (const item of records) {
+                const result = { external_id: item.external_id };
+                try {
+                    if (!item.external_id) throw new Error('external_id required');
+                    // Check what we can before writing, so failures leave no half-made records
+                    for (const [id, value] of Object.entries(item.fields || {})) {
+                        const field = fields.get(id);
+                        if (!field) throw new Error(`Unknown field: ${id}`);
+                        if (field?.choices && value && !field.choices.some(c => c.label === value)) {
+                            throw new Error(`Unknown choice for ${id}: ${value}`);
+                        }
+                    }
+                    let record = existing.get(item.external_id);
+                    const isNew = !record;
+                    if (!dry_run) {
+                        if (isNew) {
+                            const guid = col.createRecord(item.title || 'Untitled');
+                            if (!guid) throw new Error('Failed to create record');
+                            // Wait for record to be available (SDK quirk)
+                            await new Promise(r => setTimeout(r, 50));
+                            record = (await col.getAllRecords()).find(r => r.guid === guid);
+                            if (!record) throw new Error('Record created but not found');
+                            existing.set(item.external_id, record);
+                        } else if (item.title && record.getName?.() !== item.title) {
+                            record.setName?.(item.title);
+                        }
+                        this.setImportField(record, 'external_id', item.external_id, 'text');
+                        for (const [id, value] of Object.entries(item.fields || {})) {
+                            this.setImportField(record, id, value, fields.get(id)?.type);
+                        }
+                        if (item.body) {
+                            if (isNew) await this.insertMarkdown(item.body, record, null);
+                            else await this.replaceContents(item.body, record);
+                        }
+                    }
+                    result.action = isNew ? 'created' : 'updated';
+                    if (record) result.guid = record.guid;
+                    isNew ? created++ : updated++;
+                } catch (e) {
+                    result.action = 'failed';
+                    result.error = e.message;
+                    failed++;
+                }
+                results.push(result);
+            }
+            return { collection: col.getName(), dry_run, created, updated, failed, results };
+        } catch (e) {
+            return { error: e.message };
+        }
+    }
+
+    setImportField(record, id, value, type) {
+        const prop = record.prop(id);
+        if (!prop) throw new Error(`Unknown field: ${id}`);
+        if (value === null || value === '') {
+            return;
+        }
+        if (type === 'datetime') {
+            prop.set(new Date(value));
+        } else if (typeof value === 'string' && typeof prop.setChoice === 'function' && prop.setChoice(value)) {
+            // Choice matched by label
+        } else if (type === 'choice') {
+            throw new Error(`Unknown choice for ${id}: ${value}`);
+        } else {
+            prop.set(value);
+        }
+    }
+
+    /**
+     * All field values of a record keyed by field id. Choice fields give the
+     * choice id, dates an ISO string.
+     */
+    recordFields(record) {
+        const fields = {};
+        for (const prop of record.getAllProperties?.() || []) {
+            const key = prop.id || prop.name;
+            if (!key) continue;
+
+            let value = prop.choice?.() || null;
+            if (value === null) {
+                const date = prop.date?.();
+                if (date instanceof Date && !isNaN(date)) value = date.toISOString();
+            }
+            if (value === null) {
+                value = prop.text?.() ?? prop.number?.() ?? null;
+            }
+            if (value !== null && value !== undefined && value !== '') {
+                fields[key] = value;
+            }
+        }
+        return fields;
+    }
+
     async toolGetNote({ guid }) {
         try {
             if (!guid) {
@@ -1844,28 +2347,133 @@ class Plugin extends CollectionPlugin {
                 if (value) fields[prop.name || prop.id] = value;
             }
 
-            const lineItems = await record.getLineItems?.() || [];
-            const body = lineItems
-                .filter(item => item.parent_guid === record.guid)
-                .map(item => item.segments?.map(s => {
-                    if (s.type === 'ref' && typeof s.text === 'object') {
-                        return `[[${s.text.guid}]]`;
-                    }
-                    return s.text || '';
-                }).join('') || '')
-                .join('\n');
+            const body = await this.renderBody(record);
 
             return {
                 guid: record.guid,
                 title: record.getName?.() || 'Untitled',
                 fields,
-                body: body || '(empty)'
+                body: body || '(empty)',
+                content_hash: await this.bodyHash(body)
             };
         } catch (e) {
             return { error: e.message };
         }
     }
 
+    /**
+     * Render a record's line items as markdown: headings, tasks, lists,
+     * quotes and code blocks by type, with children indented under their
+     * parent. Runs of empty items become one blank line and ones at the
+     * ends are dropped.
+     */
+    async renderBody(record) {
+        const lineItems = await record.getLineItems?.() || [];
+        return this.bodyLines(record, lineItems)
+            .map(l => l.line ? '  '.repeat(l.depth) + l.line : '')
+            .join('\n')
+            .replace(/\n{3,}/g, '\n\n')
+            .replace(/^\n+|\n+$/g, '');
+    }
+
+    /**
+     * A record's line items in document order, each with its depth and the
+     * markdown line it renders as. A code block's items are its lines, at
+     * the block's depth, and its closing fence is a line without an item.
+     * Code blocks holding data: URLs are left out.
+     */
+    bodyLines(record, lineItems) {
+        const children = new Map();
+        for (const item of lineItems) {
+            if (!children.has(item.parent_guid)) children.set(item.parent_guid, []);
+            children.get(item.parent_guid).push(item);
+        }
+
+        const lines = [];
+        const walk = (parentGuid, depth) => {
+            for (const item of children.get(parentGuid) || []) {
+                if (this.isDataURLBlock(item, lineItems)) continue;
+                lines.push({ item, depth, line: this.markdownLine(item) });
+                if (item.type !== 'block') {
+                    walk(item.guid, depth + 1);
+                    continue;
+                }
+                for (const code of children.get(item.guid) || []) {
+                    lines.push({ item: code, depth, code: true, line: this.lineItemText(code) });
+                }
+                lines.push({ item: null, depth, line: '```' });
+            }
+        };
+        walk(record.guid, 0);
+        return lines;
+    }
+
+    /**
+     * A line item as a line of markdown, the way parseLine reads it back
+     */
+    markdownLine(item) {
+        const text = this.markdownText(item.segments);
+        if (!text && item.type !== 'br' && item.type !== 'block') {
+            return '';
+        }
+        switch (item.type) {
+            case 'heading':
+                return '#'.repeat(Math.min(Math.max(item.heading_size || 1, 1), 6)) + ' ' + text;
+            case 'task':
+                // props.done: 8 is done; other states read as unchecked
+                return (item.props?.done === 8 ? '- [x] ' : '- [ ] ') + text;
+            case 'ulist':
+                return '- ' + text;
+            case 'olist':
+                return '1. ' + text;
+            case 'quote':
+                return '> ' + text;
+            case 'br':
+                return '---';
+            case 'block':
+                return '```';
+            default:
+                return text;
+        }
+    }
+
+    /**
+     * Segments as inline markdown, the way parseInlineFormatting reads them
+     */
+    markdownText(segments) {
+        return segments?.map(s => {
+            switch (s.type) {
+                case 'ref':
+                    return typeof s.text === 'object' ? `[[${s.text.guid}]]` : s.text || '';
+                case 'bold':
+                    return s.text ? `**${s.text}**` : '';
+                case 'italic':
+                    return s.text ? `*${s.text}*` : '';
+                case 'code':
+                    return s.text ? `\`${s.text}\`` : '';
+                case 'datetime': {
+                    // { d: 'YYYYMMDD', t: { t: 'HHMMSS' } }
+                    const d = s.text?.d || '', t = s.text?.t?.t || '';
+                    return [
+                        d && `${d.slice(0, 4)}-${d.slice(4, 6)}-${d.slice(6, 8)}`,
+                        t && `${t.slice(0, 2)}:${t.slice(2, 4)}`
+                    ].filter(Boolean).join(' ');
+                }
+                default:
+                    return typeof s.text === 'string' ? s.text : '';
+            }
+        }).join('') || '';
+    }
+
+    /**
+     * Whether an item is a code block holding a data: URL, as files were
+     * once attached to captures. Its base64 is no use to read or search.
+     */
+    isDataURLBlock(item, lineItems) {
+        return item.type === 'block' && lineItems.some(child =>
+            child.parent_guid === item.guid && /^\s*data:[^,\s]*;base64,/.test(this.lineItemText(child)));
+    }
+
     async toolAppendToNote({ guid, content }) {
         try {
             if (!guid) {
@@ -1893,28 +2501,264 @@ class Plugin extends CollectionPlugin {
         }
     }
 
-    async toolLogToJournal({ content }) {
+    /**
+     * SHA-256 of a rendered body, for detecting edits made in between
+     */
+    async bodyHash(text) {
+        const digest = await crypto.subtle.digest('SHA-256', new TextEncoder().encode(text));
+        return Array.from(new Uint8Array(digest), b => b.toString(16).padStart(2, '0')).join('');
+    }
+
+    /**
+     * Replace a note's body with markdown as renderBody writes it. With
+     * baseHash, refuses to overwrite changes made since the caller read the
+     * note and returns the current body instead.
+     *
+     * Lines that didn't change keep their items. A changed line updates an
+     * item of its type in the same place, else gets a new item. Items can't
+     * be deleted or change type, so ones no longer in the text are emptied,
+     * like replaceContents does. Items markdown can't express, such as
+     * transclusions, are left as they are.
+     */
+    async replaceNoteBody(guid, content, baseHash) {
+        const record = await this.findRecordByGUID(guid);
+        if (!record) {
+            return { error: `Note not found: ${guid}` };
+        }
+
+        const lineItems = await record.getLineItems?.() || [];
+        const current = await this.renderBody(record);
+        const currentHash = await this.bodyHash(current);
+        if (baseHash && baseHash !== currentHash) {
+            return {
+                error: 'Note changed since it was read',
+                conflict: true,
+                guid,
+                body: current,
+                content_hash: currentHash
+            };
+        }
+
+        const old = this.bodyLines(record, lineItems).filter(l => l.item);
+        const lines = this.parseBodyLines(content);
+        const key = l => `${l.code ? 'code' : 'line'} ${l.depth} ${l.line}`;
+        const matches = this.matchLines(old.map(key), lines.map(key));
+
+        // For each line, where the next unchanged one is in the old lines
+        const nextMatch = new Array(lines.length + 1).fill(old.length);
+        for (let i = lines.length - 1; i >= 0; i--) {
+            nextMatch[i] = matches.has(i) ? matches.get(i) : nextMatch[i + 1];
+        }
+        const matched = new Set(matches.values());
+
+        const placed = [];             // line index -> its item
+        const lastChild = new Map();   // parent GUID -> last item placed in it
+        const used = new Set();
+        let next = 0;                  // first old line not passed yet
+        for (let i = 0; i < lines.length; i++) {
+            const line = lines[i];
+            const parent = line.parent === null ? null : placed[line.parent];
+            if (parent === undefined) continue; // Its parent couldn't be created
+            const parentGuid = parent ? parent.guid : record.guid;
+
+            let item = null;
+            const m = matches.get(i);
+            if (m !== undefined && old[m].item.parent_guid === parentGuid) {
+                item = old[m].item;
+                next = m + 1;
+            } else {
+                for (let k = next; k < nextMatch[i + 1]; k++) {
+                    const candidate = old[k].item;
+                    if (!matched.has(k) && !used.has(candidate.guid) && this.isEditable(candidate) &&
+                        (candidate.type || 'text') === line.type && candidate.parent_guid === parentGuid) {
+                        item = candidate;
+                        next = k + 1;
+                        break;
+                    }
+                }
+                if (!item) {
+                    item = await record.createLineItem(parent, lastChild.get(parentGuid) || null, line.type);
+                    if (!item) continue;
+                }
+                this.applyLine(item, line);
+            }
+            used.add(item.guid);
+            placed[i] = item;
+            lastChild.set(parentGuid, item);
+        }
+
+        for (const { item } of old) {
+            if (!used.has(item.guid) && this.isEditable(item) && item.segments?.length) {
+                item.setSegments([]);
+            }
+        }
+
+        return {
+            success: true,
+            guid,
+            content_hash: await this.bodyHash(await this.renderBody(record))
+        };
+    }
+
+    /**
+     * Whether save_note may rewrite or empty an item
+     */
+    isEditable(item) {
+        return ['text', 'heading', 'task', 'ulist', 'olist', 'quote', 'br', 'block'].includes(item.type || 'text');
+    }
+
+    /**
+     * Set an item's text and state from a parsed line
+     */
+    applyLine(item, line) {
+        item.setSegments(line.segments);
+        if (line.type === 'heading' && (line.level > 1 || item.heading_size > 1)) {
+            try { item.setHeadingSize?.(line.level); } catch(e) {}
+        }
+        if (line.type === 'block') {
+            try { item.setHighlightLanguage?.(this.normalizeLanguage(line.lang)); } catch(e) {}
+        }
+        if (line.type === 'task' && line.done !== (item.props?.done === 8)) {
+            item.setMetaProperties?.({ done: line.done ? 8 : null });
+        }
+    }
+
+    /**
+     * Parse markdown into lines with a depth (two spaces of indent each),
+     * the index of their parent line, and the item type and segments they
+     * become. A code block's lines are its children, kept as they are.
+     */
+    parseBodyLines(markdown) {
+        const lines = [];
+        const stack = [];  // Index of the last line at each depth
+        let fence = null;  // The open code block
+        for (const raw of markdown.split('\n')) {
+            const indent = raw.match(/^\s*/)[0].replace(/\t/g, '  ').length;
+            const text = raw.trim();
+            if (fence) {
+                if (text.startsWith('```')) {
+                    fence = null;
+                    continue;
+                }
+                const line = raw.slice(Math.min(fence.indent, raw.match(/^ */)[0].length));
+                lines.push({
+                    depth: fence.depth, parent: fence.index, code: true, line,
+                    type: 'text', segments: line ? [{ type: 'text', text: line }] : []
+                });
+                continue;
+            }
+            if (!text) continue;
+
+            const depth = Math.min(Math.floor(indent / 2), stack.length);
+            stack.length = depth;
+            const parent = depth > 0 ? stack[depth - 1] : null;
+            const fenceMatch = text.match(/^```(.*)$/);
+            const parsed = fenceMatch
+                ? { type: 'block', segments: [], lang: fenceMatch[1].trim() }
+                : this.parseLine(text);
+            const index = lines.length;
+            lines.push({ depth, parent, line: text, done: /^[-*]\s+\[[xX]\]\s/.test(text), ...parsed });
+            stack.push(index);
+            if (fenceMatch) fence = { index, depth, indent };
+        }
+        return lines;
+    }
+
+    /**
+     * Longest common subsequence of two lists of keys, as a map from the
+     * index of each matched key in b to its index in a
+     */
+    matchLines(a, b) {
+        const len = Array.from({ length: a.length + 1 }, () => new Uint32Array(b.length + 1));
+        for (let i = a.length - 1; i >= 0; i--) {
+            for (let j = b.length - 1; j >= 0; j--) {
+                len[i][j] = a[i] === b[j] ? len[i + 1][j + 1] + 1 : Math.max(len[i + 1][j], len[i][j + 1]);
+            }
+        }
+        const matches = new Map();
+        for (let i = 0, j = 0; i < a.length && j < b.length;) {
+            if (a[i] === b[j]) {
+                matches.set(j, i++);
+                j++;
+            } else if (len[i + 1][j] >= len[i][j + 1]) {
+                i++;
+            } else {
+                j++;
+            }
+        }
+        return matches;
+    }
+
+    async toolLogToJournal({ content, section, date }) {
         try {
-            const journal = await this.getTodayJournalRecord();
+            const journal = await this.getJournalRecord(date);
             if (!journal) {
-                return { error: 'Journal not available' };
+                return { error: date ? `No journal found for ${date}` : 'Journal not available' };
             }
-            // Find last top-level item to append after
             const lineItems = await journal.getLineItems?.() || [];
             const topLevel = lineItems.filter(item => item.parent_guid === journal.guid);
+
+            if (section) {
+                const target = this.findJournalSection(topLevel, section);
+                if (target) {
+                    await this.insertMarkdown(content, journal, target);
+                    return { success: true, guid: journal.guid, section };
+                }
+                // Section doesn't exist yet - start it at the end
+                const heading = section.match(/^#{1,6}\s/) ? section.trim() : `## ${section.trim()}`;
+                content = `${heading}\n${content}`;
+            }
+
+            // Find last top-level item to append after
             const lastItem = topLevel.length > 0 ? topLevel[topLevel.length - 1] : null;
             await this.insertMarkdown(content, journal, lastItem);
-            return { success: true };
+            return { success: true, guid: journal.guid };
         } catch (e) {
             return { error: e.message };
         }
     }
 
+    /**
+     * Find the item to insert after to add to the end of a heading's section.
+     * The section runs until the next heading; trailing blank lines are kept
+     * before that heading. Returns null if there's no such heading.
+     */
+    findJournalSection(topLevel, section) {
+        const name = section.replace(/^#{1,6}\s*/, '').trim().toLowerCase();
+        const start = topLevel.findIndex(item =>
+            item.type === 'heading' && this.lineItemText(item).trim().toLowerCase() === name);
+        if (start < 0) return null;
+
+        let end = start + 1;
+        while (end < topLevel.length && topLevel[end].type !== 'heading') end++;
+
+        let last = end - 1;
+        while (last > start && !this.lineItemText(topLevel[last]).trim()) last--;
+        return topLevel[last];
+    }
+
+    lineItemText(item) {
+        return item.segments?.map(s => {
+            if (s.type === 'ref' && typeof s.text === 'object') {
+                return `[[${s.text.guid}]]`;
+            }
+            return s.text || '';
+        }).join('') || '';
+    }
+
     async toolGetTodaysJournal() {
+        return this.toolGetJournal({});
+    }
+
+    async toolGetJournal({ date }) {
         try {
-            const journal = await this.getTodayJournalRecord();
+            const journal = await this.getJournalRecord(date);
             if (!journal) {
-                return { error: 'No journal found for today. Open your daily note first to create it.' };
+                return {
+                    error: date
+                        ? `No journal found for ${date}`
+                        : 'No journal found for today. Open your daily note first to create it.'
+                };
             }
 
             const props = journal.getAllProperties?.() || [];
@@ -1924,16 +2768,7 @@ class Plugin extends CollectionPlugin {
                 if (value) fields[prop.name || prop.id] = value;
             }
 
-            const lineItems = await journal.getLineItems?.() || [];
-            const body = lineItems
-                .filter(item => item.parent_guid === journal.guid)
-                .map(item => item.segments?.map(s => {
-                    if (s.type === 'ref' && typeof s.text === 'object') {
-                        return `[[${s.text.guid}]]`;
-                    }
-                    return s.text || '';
-                }).join('') || '')
-                .join('\n');
+            const body = await this.renderBody(journal);
 
             return {
                 guid: journal.guid,
@@ -1947,8 +2782,50 @@ class Plugin extends CollectionPlugin {
         }
     }
 
-    async toolSaveNote({ collection, content }) {
+    async toolGetJournalTasks({ date }) {
+        try {
+            const journal = await this.getJournalRecord(date);
+            if (!journal) {
+                return { error: date ? `No journal found for ${date}` : 'No journal found for today' };
+            }
+
+            // Walk the document in order so each task knows its heading
+            const lineItems = await journal.getLineItems?.() || [];
+            const tasks = [];
+            let section = null;
+            for (const item of lineItems) {
+                if (item.type === 'heading' && item.parent_guid === journal.guid) {
+                    section = this.lineItemText(item).trim();
+                    continue;
+                }
+                if (item.type !== 'task') continue;
+
+                // props.done: undefined/0 unchecked, 1 in progress, 8 done
+                const done = item.props?.done;
+                if (done === 8) continue;
+                tasks.push({
+                    guid: item.guid,
+                    text: this.lineItemText(item),
+                    status: done === 1 ? 'in_progress' : 'todo',
+                    section
+                });
+            }
+
+            return {
+                guid: journal.guid,
+                date: journal.guid.slice(-8),
+                tasks
+            };
+        } catch (e) {
+            return { error: e.message };
+        }
+    }
+
+    async toolSaveNote({ collection, content, guid, base_hash }) {
         try {
+            if (guid) {
+                return this.replaceNoteBody(guid, content || '', base_hash);
+            }
             if (!collection) {
                 return { error: 'Collection name required' };
             }
@@ -2504,19 +3381,153 @@ class Plugin extends CollectionPlugin {
     }
 
     /**
-     * Get list of registered sync plugins (for Desktop Bridge API)
+     * List sync plugins with their settings and status (for Desktop Bridge API).
+     * Includes plugins whose record exists but which aren't loaded right now.
      */
-    _getPluginList() {
+    async _getPluginList() {
+        if (!this.myCollection) return [];
+        const records = await this.myCollection.getAllRecords();
         const plugins = [];
-        for (const [pluginId, _] of this.syncFunctions) {
-            plugins.push({
-                name: pluginId,
-                enabled: true // We only track registered (enabled) plugins
-            });
+        for (const record of records) {
+            const pluginId = record.text('plugin_id');
+            if (pluginId) {
+                plugins.push(this._describePlugin(pluginId, record));
+            }
         }
         return plugins;
     }
 
+    /**
+     * A plugin as thymer-bar sees it. The token and secret-looking config
+     * values never leave the browser.
+     */
+    _describePlugin(pluginId, record) {
+        const info = this.registeredPlugins.get(pluginId) || {};
+        const settings = {};
+        for (const key of Object.keys(PLUGIN_SETTINGS)) {
+            const value = record.prop(key)?.choice();
+            if (value && key !== 'interval') settings[key] = value;
+        }
+        const config = this._parsePluginConfig(record);
+
+        return {
+            id: pluginId,
+            name: record.getName() || info.name || pluginId,
+            enabled: record.prop('enabled')?.choice() === 'yes',
+            loaded: this.syncFunctions.has(pluginId),
+            version: info.version,
+            collection: info.collection,
+            interval: record.prop('interval')?.choice() || 'manual',
+            status: record.prop('status')?.choice() || 'idle',
+            last_sync: record.prop('last_run')?.date()?.toISOString(),
+            last_error: record.prop('last_error')?.text() || undefined,
+            has_token: !!record.text('token'),
+            settings,
+            config: config ? this._redactConfig(config) : undefined,
+            config_error: config ? undefined : 'Config is not valid JSON',
+        };
+    }
+
+    _parsePluginConfig(record) {
+        const configJson = record.text('config');
+        if (!configJson) return {};
+        try {
+            const config = JSON.parse(configJson);
+            return config && typeof config === 'object' && !Array.isArray(config) ? config : null;
+        } catch (e) {
+            return null;
+        }
+    }
+
+    _redactConfig(value) {
+        if (Array.isArray(value)) return value.map(v => this._redactConfig(v));
+        if (!value || typeof value !== 'object') return value;
+        const redacted = {};
+        for (const [key, v] of Object.entries(value)) {
+            redacted[key] = SECRET_CONFIG_KEY.test(key) ? REDACTED : this._redactConfig(v);
+        }
+        return redacted;
+    }
+
+    /**
+     * Enable or pause a plugin (from thymer-bar)
+     */
+    async setPluginEnabled(pluginId, enabled) {
+        const record = await this.findPluginRecord(pluginId);
+        if (!record) throw new Error(`Unknown plugin: ${pluginId}`);
+
+        record.prop('enabled')?.setChoice(enabled ? 'yes' : 'no');
+        await this.appendLog(record.guid, enabled ? 'Enabled from Thymer Desktop' : 'Disabled from Thymer Desktop');
+        this.updateStatusBar();
+        this.notifyPluginsChanged();
+        return this._describePlugin(pluginId, record);
+    }
+
+    /**
+     * Change a plugin's settings (from thymer-bar). Keys are record settings
+     * (interval, journal, toast, log_level) or top-level keys of its JSON
+     * config; null removes a config key. Secrets must be set in Thymer.
+     */
+    async setPluginConfig(pluginId, changes = {}) {
+        const record = await this.findPluginRecord(pluginId);
+        if (!record) throw new Error(`Unknown plugin: ${pluginId}`);
+
+        // Check everything before changing anything
+        const entries = Object.entries(changes);
+        if (entries.length === 0) throw new Error('No settings to change');
+        let config = null;
+        for (const [key, value] of entries) {
+            if (key === 'token' || SECRET_CONFIG_KEY.test(key)) {
+                throw new Error(`${key} is a secret; set it in Thymer`);
+            }
+            if (JSON.stringify(value)?.includes(REDACTED)) {
+                throw new Error(`${key} contains a hidden secret; set it in Thymer`);
+            }
+            if (PLUGIN_SETTINGS[key]) {
+                if (!PLUGIN_SETTINGS[key].includes(value)) {
+                    throw new Error(`${key} must be one of: ${PLUGIN_SETTINGS[key].join(', ')}`);
+                }
+            } else if (!config) {
+                config = this._parsePluginConfig(record);
+                if (!config) throw new Error('Config is not valid JSON; fix it in Thymer first');
+            }
+        }
+
+        for (const [key, value] of entries) {
+            if (PLUGIN_SETTINGS[key]) {
+                record.prop(key)?.setChoice(value);
+            } else if (value === null) {
+                delete config[key];
+            } else {
+                config[key] = value;
+            }
+        }
+        if (config) {
+            record.prop('config')?.set(JSON.stringify(config));
+        }
+
+        await this.appendLog(record.guid, `Settings changed from Thymer Desktop: ${entries.map(([k]) => k).join(', ')}`);
+        this.notifyPluginsChanged();
+        return this._describePlugin(pluginId, record);
+    }
+
+    /**
+     * Ask the LLM of a connected MCP client for a completion.
+     * Lets plugins use an LLM without API keys in the browser.
+     *
+     * @param {Object} request
+     * @param {Array} request.messages - [{ role: 'user' | 'assistant', content: 'text' }]
+     * @param {string} request.systemPrompt - Optional system prompt
+     * @param {number} request.maxTokens - Optional token limit
+     * @returns {Promise<{role, content, model, stopReason}>}
+     */
+    async sample(request) {
+        if (!this.sampler) {
+            throw new Error('Sampling not available - connect thymer-bar and an MCP client that supports sampling');
+        }
+        return this.sampler(request);
+    }
+
     /**
      * Get human-readable name for a plugin ID
      */
@@ -2539,9 +3550,11 @@ class Plugin extends CollectionPlugin {
     }
 
     /**
-     * Sync all enabled plugins
+     * Sync all enabled plugins. Resolves to the outcome of each; options as
+     * for requestSync.
      */
-    async syncAll() {
+    async syncAll(options = {}) {
+        const outcomes = [];
         try {
             const records = await this.myCollection?.getAllRecords() || [];
             const enabledRecords = records.filter(r => r.prop('enabled')?.choice() === 'yes');
@@ -2553,7 +3566,7 @@ class Plugin extends CollectionPlugin {
                     dismissible: true,
                     autoDestroyTime: 2000,
                 });
-                return;
+                return outcomes;
             }
 
             this.ui.addToaster({
@@ -2566,7 +3579,7 @@ class Plugin extends CollectionPlugin {
             for (const record of enabledRecords) {
                 const pluginId = record.text('plugin_id');
                 if (pluginId && this.syncFunctions.has(pluginId)) {
-                    await this.runSync(pluginId, record);
+                    outcomes.push(await this.runSync(pluginId, record, { onProgress: options.onProgress }));
                 }
             }
 
@@ -2577,7 +3590,9 @@ class Plugin extends CollectionPlugin {
                 dismissible: true,
                 autoDestroyTime: 3000,
             });
+            outcomes.push(this.syncOutcome('all', 'error', { error: e.message }));
         }
+        return outcomes;
     }
 
     /**